@@ -0,0 +1,103 @@
+package goql
+
+import (
+	"context"
+	"database/sql"
+)
+
+// SumInt returns the sum of column over the rows matched by the FROM,
+// JOIN and WHERE clauses of the query, 0 when there are none, for
+// example
+// total, err := queryBuilder.From("orders").Where("user_id = $?", id).SumInt(db, "quantity")
+// The selected columns and the GROUP BY, HAVING, DISTINCT, ORDER BY and
+// LIMIT clauses are ignored.
+func (qb *QueryBuilder) SumInt(Db Queryer, column string) (int64, error) {
+	return qb.SumIntContext(context.Background(), Db, column)
+}
+
+// SumIntContext is the same as SumInt but the query is canceled when ctx
+// is done.
+func (qb *QueryBuilder) SumIntContext(ctx context.Context, Db Queryer, column string) (int64, error) {
+	var sum sql.NullInt64
+	err := qb.aggregate(ctx, Db, "SUM", column, &sum)
+	return sum.Int64, err
+}
+
+// SumFloat is the same as SumInt for columns that are not integers.
+func (qb *QueryBuilder) SumFloat(Db Queryer, column string) (float64, error) {
+	return qb.SumFloatContext(context.Background(), Db, column)
+}
+
+// SumFloatContext is the same as SumFloat but the query is canceled
+// when ctx is done.
+func (qb *QueryBuilder) SumFloatContext(ctx context.Context, Db Queryer, column string) (float64, error) {
+	var sum sql.NullFloat64
+	err := qb.aggregate(ctx, Db, "SUM", column, &sum)
+	return sum.Float64, err
+}
+
+// Avg returns the average of column, see SumInt. It's 0 when there are
+// no rows.
+func (qb *QueryBuilder) Avg(Db Queryer, column string) (float64, error) {
+	return qb.AvgContext(context.Background(), Db, column)
+}
+
+// AvgContext is the same as Avg but the query is canceled when ctx is
+// done.
+func (qb *QueryBuilder) AvgContext(ctx context.Context, Db Queryer, column string) (float64, error) {
+	var avg sql.NullFloat64
+	err := qb.aggregate(ctx, Db, "AVG", column, &avg)
+	return avg.Float64, err
+}
+
+// Min returns the minimum value of column as returned by the driver,
+// nil when there are no rows, see SumInt. Text is returned as a string.
+func (qb *QueryBuilder) Min(Db Queryer, column string) (interface{}, error) {
+	return qb.MinContext(context.Background(), Db, column)
+}
+
+// MinContext is the same as Min but the query is canceled when ctx is
+// done.
+func (qb *QueryBuilder) MinContext(ctx context.Context, Db Queryer, column string) (interface{}, error) {
+	return qb.aggregateValue(ctx, Db, "MIN", column)
+}
+
+// Max returns the maximum value of column, see Min.
+func (qb *QueryBuilder) Max(Db Queryer, column string) (interface{}, error) {
+	return qb.MaxContext(context.Background(), Db, column)
+}
+
+// MaxContext is the same as Max but the query is canceled when ctx is
+// done.
+func (qb *QueryBuilder) MaxContext(ctx context.Context, Db Queryer, column string) (interface{}, error) {
+	return qb.aggregateValue(ctx, Db, "MAX", column)
+}
+
+// aggregateValue runs the aggregate fn of column and returns its value.
+func (qb *QueryBuilder) aggregateValue(ctx context.Context, Db Queryer, fn string, column string) (interface{}, error) {
+	var val interface{}
+	if err := qb.aggregate(ctx, Db, fn, column, &val); err != nil {
+		return nil, err
+	}
+	if b, ok := val.([]byte); ok {
+		return string(b), nil
+	}
+	return val, nil
+}
+
+// aggregate runs the aggregate fn of column over the rows matched by the
+// query and scans the result into dest.
+func (qb *QueryBuilder) aggregate(ctx context.Context, Db Queryer, fn string, column string, dest interface{}) error {
+	agg := qb.unordered()
+	agg.columns = []string{fn + "(" + column + ")"}
+	agg.params, agg.resultColumns, agg.distinct, agg.windows = nil, nil, "", nil
+	agg.groupBy, agg.having, agg.compound = nil, nil, nil
+	for _, clause := range []string{"select", "having", "compound"} {
+		agg.setValues(clause, nil)
+	}
+	qry, vals, err := agg.BuildWithArgs()
+	if err != nil {
+		return err
+	}
+	return canceled(ctx, queryRow(qb.tagged(ctx), Db, qry, vals...).Scan(dest))
+}
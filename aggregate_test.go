@@ -0,0 +1,42 @@
+package goql
+
+import (
+	"testing"
+)
+
+func TestAggregates(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`INSERT INTO user(username, password) VALUES('a', 'x'), ('b', 'x'), ('c', 'y')`)
+
+	qb := QueryBuilder{}
+	qb.Select(User{}).Where("password = ?", "x").GroupBy("id").OrderBy("id DESC").Limit(1)
+	sum, err := qb.SumInt(db, "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum != 3 {
+		t.Errorf("Expected a sum of 3, got %d", sum)
+	}
+	if avg, err := qb.Avg(db, "id"); err != nil || avg != 1.5 {
+		t.Errorf("Expected an average of 1.5, got %v %v", avg, err)
+	}
+	if sum, err := qb.SumFloat(db, "id * 0.5"); err != nil || sum != 1.5 {
+		t.Errorf("Expected a sum of 1.5, got %v %v", sum, err)
+	}
+	if min, err := qb.Min(db, "username"); err != nil || min != "a" {
+		t.Errorf("Expected a min of a, got %v %v", min, err)
+	}
+	if max, err := qb.Max(db, "id"); err != nil || max != int64(2) {
+		t.Errorf("Expected a max of 2, got %v %v", max, err)
+	}
+
+	qb = QueryBuilder{}
+	qb.From("user").Where("password = ?", "none")
+	if sum, err := qb.SumInt(db, "id"); err != nil || sum != 0 {
+		t.Errorf("Expected a sum of 0, got %d %v", sum, err)
+	}
+	if max, err := qb.Max(db, "id"); err != nil || max != nil {
+		t.Errorf("Expected no max, got %v %v", max, err)
+	}
+}
@@ -0,0 +1,223 @@
+package goql
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// WhereAny adds a "column = ANY(values)" condition, values being a slice
+// bound as a single Postgres array, so the SQL is the same whatever the
+// number of values, for example
+// queryBuilder.WhereAny("id", []int64{1, 2, 3}) generates WHERE id = ANY($1)
+// The databases without arrays get the same condition as WhereIn.
+func (qb *QueryBuilder) WhereAny(column string, values interface{}) (ret *QueryBuilder) {
+	ret = qb
+	if !featuresOf(qb.getDialect()).arrays {
+		return qb.WhereIn(column, values)
+	}
+	if v := reflect.ValueOf(values); v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		qb.addError(fmt.Errorf("WhereAny %s: values must be a slice, got %T", column, values))
+		return
+	}
+	return qb.Where(column+" = ANY($?)", Array(values))
+}
+
+// WhereArrayContains adds a condition matching the rows whose array
+// column contains all of values, for example
+// queryBuilder.WhereArrayContains("tags", []string{"go", "sql"}) generates
+// WHERE tags @> $1. It's only supported by Postgres.
+func (qb *QueryBuilder) WhereArrayContains(column string, values interface{}) (ret *QueryBuilder) {
+	ret = qb
+	d := qb.getDialect()
+	if !featuresOf(d).arrays {
+		qb.addError(fmt.Errorf("goql: the %s dialect doesn't support arrays", d.Name()))
+		return
+	}
+	if v := reflect.ValueOf(values); v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		qb.addError(fmt.Errorf("WhereArrayContains %s: values must be a slice, got %T", column, values))
+		return
+	}
+	return qb.Where(column+" @> $?", Array(values))
+}
+
+// PGArray binds and scans a Go slice as a one dimensional Postgres array,
+// see Array.
+type PGArray struct {
+	slice interface{}
+}
+
+// Array wraps slice, or a pointer to one when it's scanned, so it's
+// encoded as a Postgres array literal such as {1,2,3} or {"a","b"}, for
+// example to insert into or read from text[] and int[] columns:
+// DB.QueryRow("SELECT tags FROM posts WHERE id = $1", id).Scan(goql.Array(&tags))
+// Strings, integers, floats and booleans are supported, nil elements
+// are encoded as NULL.
+func Array(slice interface{}) PGArray {
+	return PGArray{slice: slice}
+}
+
+// Value implements driver.Valuer.
+func (a PGArray) Value() (driver.Value, error) {
+	v := reflect.Indirect(reflect.ValueOf(a.slice))
+	if v.Kind() == reflect.Slice && v.IsNil() {
+		return nil, nil
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("goql: %T is not a slice", a.slice)
+	}
+	elems := make([]string, v.Len())
+	for i := range elems {
+		elem, err := arrayElement(v.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		elems[i] = elem
+	}
+	return "{" + strings.Join(elems, ",") + "}", nil
+}
+
+// arrayElement encodes an element of an array literal.
+func arrayElement(v reflect.Value) (string, error) {
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return "NULL", nil
+	}
+	v = reflect.Indirect(v)
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v.String()) + `"`, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64), nil
+	case reflect.Bool:
+		if v.Bool() {
+			return "t", nil
+		}
+		return "f", nil
+	}
+	return "", fmt.Errorf("goql: unsupported array element %s", v.Type())
+}
+
+// Scan implements sql.Scanner, the array is scanned into the slice the
+// PGArray points to.
+func (a PGArray) Scan(src interface{}) error {
+	dest := reflect.ValueOf(a.slice)
+	if dest.Kind() != reflect.Ptr || dest.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("goql: can't scan an array into %T", a.slice)
+	}
+	var text string
+	switch src := src.(type) {
+	case nil:
+		dest.Elem().Set(reflect.Zero(dest.Elem().Type()))
+		return nil
+	case []byte:
+		text = string(src)
+	case string:
+		text = src
+	default:
+		return fmt.Errorf("goql: can't scan %T as an array", src)
+	}
+	elems, err := parseArray(text)
+	if err != nil {
+		return err
+	}
+	slice := reflect.MakeSlice(dest.Elem().Type(), len(elems), len(elems))
+	for i, elem := range elems {
+		if elem == nil {
+			continue
+		}
+		if err := setArrayElement(slice.Index(i), *elem); err != nil {
+			return err
+		}
+	}
+	dest.Elem().Set(slice)
+	return nil
+}
+
+// parseArray parses a one dimensional array literal, the NULL elements
+// are returned as nil.
+func parseArray(text string) ([]*string, error) {
+	if len(text) < 2 || text[0] != '{' || text[len(text)-1] != '}' {
+		return nil, fmt.Errorf("goql: invalid array %q", text)
+	}
+	body := text[1 : len(text)-1]
+	elems := []*string{}
+	if len(body) <= 0 {
+		return elems, nil
+	}
+	for i := 0; i <= len(body); {
+		var elem []byte
+		quoted := i < len(body) && body[i] == '"'
+		if quoted {
+			i++
+			for ; i < len(body) && body[i] != '"'; i++ {
+				if body[i] == '\\' {
+					i++
+				}
+				if i < len(body) {
+					elem = append(elem, body[i])
+				}
+			}
+			if i >= len(body) {
+				return nil, fmt.Errorf("goql: invalid array %q", text)
+			}
+			i++
+		} else {
+			for ; i < len(body) && body[i] != ','; i++ {
+				if body[i] == '{' {
+					return nil, errors.New("goql: multidimensional arrays are not supported")
+				}
+				elem = append(elem, body[i])
+			}
+		}
+		if s := string(elem); quoted || s != "NULL" {
+			elems = append(elems, &s)
+		} else {
+			elems = append(elems, nil)
+		}
+		if i < len(body) && body[i] != ',' {
+			return nil, fmt.Errorf("goql: invalid array %q", text)
+		}
+		i++
+	}
+	return elems, nil
+}
+
+// setArrayElement sets v, an element of the scanned slice, to text.
+func setArrayElement(v reflect.Value, text string) error {
+	if v.Kind() == reflect.Ptr {
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+	var err error
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(text)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		n, err = strconv.ParseInt(text, 10, 64)
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var n uint64
+		n, err = strconv.ParseUint(text, 10, 64)
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		f, err = strconv.ParseFloat(text, 64)
+		v.SetFloat(f)
+	case reflect.Bool:
+		v.SetBool(text == "t" || text == "true")
+	default:
+		return fmt.Errorf("goql: unsupported array element %s", v.Type())
+	}
+	return err
+}
@@ -0,0 +1,95 @@
+package goql
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestWhereAny(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.Select("id").From("posts").WhereAny("id", []int64{1, 2, 3}).WhereArrayContains("tags", []string{"go", `say "hi"`})
+	expected := `SELECT id FROM posts WHERE id = ANY($1) AND tags @> $2`
+	sql, vals, err := qb.BuildWithArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	encoded := []interface{}{}
+	for _, val := range vals {
+		v, err := val.(PGArray).Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded = append(encoded, v)
+	}
+	if fmt.Sprint(encoded) != `[{1,2,3} {"go","say \"hi\""}]` {
+		t.Errorf("Unexpected values %v", encoded)
+	}
+
+	// Databases without arrays get an IN list
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`INSERT INTO user(username) VALUES('a'), ('b'), ('c')`)
+	qb = QueryBuilder{}
+	qb.Select("id").From("user").WhereAny("id", []int64{1, 3})
+	if count, err := qb.Count(db); err != nil || count != 2 {
+		t.Errorf("Expected 2 rows, got %d %v", count, err)
+	}
+	qb = QueryBuilder{}
+	qb.Select("id").From("user").WhereArrayContains("tags", []string{"a"})
+	if _, _, err := qb.BuildWithArgs(); err == nil {
+		t.Error("Expected an error for arrays in SQLite")
+	}
+}
+
+func TestArrayEncoding(t *testing.T) {
+	name := "x"
+	cases := map[string]interface{}{
+		`{}`:                  []string{},
+		`{1.5,-2}`:            []float64{1.5, -2},
+		`{t,f}`:               []bool{true, false},
+		`{"a,b",NULL,"c\\d"}`: []*string{strPtr("a,b"), nil, strPtr(`c\d`)},
+		`{"x"}`:               []interface{}{name},
+	}
+	for expected, slice := range cases {
+		v, err := Array(slice).Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != expected {
+			t.Errorf("Expected %s, got %v", expected, v)
+		}
+	}
+	if v, err := Array([]string(nil)).Value(); err != nil || v != nil {
+		t.Errorf("Expected NULL for a nil slice, got %v %v", v, err)
+	}
+
+	var tags []string
+	if err := Array(&tags).Scan([]byte(`{go,"a \"b\"",NULL,"NULL"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tags, []string{"go", `a "b"`, "", "NULL"}) {
+		t.Errorf("Unexpected tags %q", tags)
+	}
+	var ids []*int64
+	if err := Array(&ids).Scan(`{1,NULL,3}`); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 3 || *ids[0] != 1 || ids[1] != nil || *ids[2] != 3 {
+		t.Errorf("Unexpected ids %v", ids)
+	}
+	if err := Array(&ids).Scan(`{{1,2},{3,4}}`); err == nil {
+		t.Error("Expected an error for a multidimensional array")
+	}
+	if err := Array(&ids).Scan(nil); err != nil || ids != nil {
+		t.Errorf("Expected a nil slice, got %v %v", ids, err)
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
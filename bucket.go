@@ -0,0 +1,67 @@
+package goql
+
+import (
+	"fmt"
+	"time"
+)
+
+// bucketStyle is the way a database truncates times to buckets.
+type bucketStyle int
+
+const (
+	// bucketPostgres uses date_trunc and to_timestamp
+	bucketPostgres bucketStyle = iota
+	// bucketMySQL uses UNIX_TIMESTAMP and FROM_UNIXTIME
+	bucketMySQL
+	// bucketSQLite uses strftime('%s') and datetime
+	bucketSQLite
+	// bucketUnsupported is the style of the databases GroupByTimeBucket
+	// doesn't support
+	bucketUnsupported
+)
+
+// dateTruncUnits holds the buckets truncated by date_trunc in Postgres.
+var dateTruncUnits = map[time.Duration]string{
+	time.Second: "second", time.Minute: "minute", time.Hour: "hour", 24 * time.Hour: "day",
+}
+
+// GroupByTimeBucket groups the rows by the bucket of duration bucket the
+// time column falls in and selects the start of the bucket as "bucket",
+// which is how metrics are aggregated over time, for example
+// queryBuilder.Select("COUNT(*) AS n").From("events").GroupByTimeBucket("created_at", time.Hour)
+// generates SELECT COUNT(*) AS n,date_trunc('hour', created_at) "bucket" FROM events
+// GROUP BY date_trunc('hour', created_at) in Postgres and
+// FROM_UNIXTIME(FLOOR(UNIX_TIMESTAMP(created_at) / 3600) * 3600) in MySQL.
+// The buckets are aligned on the Unix epoch, in UTC, so the ones of a
+// week start on Thursday. bucket must be a whole number of seconds.
+func (qb *QueryBuilder) GroupByTimeBucket(column string, bucket time.Duration) (ret *QueryBuilder) {
+	ret = qb
+	if bucket < time.Second || bucket%time.Second != 0 {
+		panic(fmt.Sprintf("Invalid time bucket %s", bucket))
+	}
+	d := qb.getDialect()
+	if featuresOf(d).timeBucket == bucketUnsupported {
+		qb.addError(fmt.Errorf("goql: the %s dialect doesn't support GroupByTimeBucket", d.Name()))
+		return
+	}
+	expr := timeBucket(d, column, bucket)
+	qb.invalidate()
+	qb.selectExpr(Raw(expr).As("bucket"))
+	return qb.GroupBy(expr)
+}
+
+// timeBucket returns the expression of the start of the bucket column
+// falls in, in the dialect d.
+func timeBucket(d Dialect, column string, bucket time.Duration) string {
+	seconds := int64(bucket / time.Second)
+	switch featuresOf(d).timeBucket {
+	case bucketMySQL:
+		return fmt.Sprintf("FROM_UNIXTIME(FLOOR(UNIX_TIMESTAMP(%s) / %d) * %d)", column, seconds, seconds)
+	case bucketSQLite:
+		return fmt.Sprintf("datetime(CAST(strftime('%%s', %s) AS INTEGER) / %d * %d, 'unixepoch')", column, seconds, seconds)
+	}
+	if unit, ok := dateTruncUnits[bucket]; ok {
+		return fmt.Sprintf("date_trunc('%s', %s)", unit, column)
+	}
+	return fmt.Sprintf("to_timestamp(floor(extract(epoch from %s) / %d) * %d)", column, seconds, seconds)
+}
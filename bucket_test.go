@@ -0,0 +1,59 @@
+package goql
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestGroupByTimeBucket(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.Select("COUNT(*) AS n").From("events").GroupByTimeBucket("created_at", time.Hour).OrderBy("bucket")
+	expected := `SELECT COUNT(*) AS n,date_trunc('hour', created_at) "bucket" FROM events GROUP BY date_trunc('hour', created_at) ORDER BY bucket`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	qb = QueryBuilder{}
+	qb.Select("COUNT(*) AS n").From("events").GroupByTimeBucket("created_at", 5*time.Minute)
+	expected = `SELECT COUNT(*) AS n,to_timestamp(floor(extract(epoch from created_at) / 300) * 300) "bucket" FROM events GROUP BY to_timestamp(floor(extract(epoch from created_at) / 300) * 300)`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	qb = QueryBuilder{}
+	qb.UseDialect(MySQL).Select("COUNT(*) AS n").From("events").GroupByTimeBucket("created_at", time.Hour)
+	expected = "SELECT COUNT(*) AS n,FROM_UNIXTIME(FLOOR(UNIX_TIMESTAMP(created_at) / 3600) * 3600) `bucket` FROM events GROUP BY FROM_UNIXTIME(FLOOR(UNIX_TIMESTAMP(created_at) / 3600) * 3600)"
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic for a bucket shorter than a second")
+		}
+	}()
+	(&QueryBuilder{}).From("events").GroupByTimeBucket("created_at", time.Millisecond)
+}
+
+func TestGroupByTimeBucketQuery(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`CREATE TABLE events(id INTEGER PRIMARY KEY, created_at TEXT)`)
+	db.Exec(`INSERT INTO events (created_at) VALUES ('2020-01-01 10:05:00'), ('2020-01-01 10:55:00'), ('2020-01-01 11:00:00')`)
+	qb := QueryBuilder{}
+	rows, err := qb.Select("COUNT(*) AS n").From("events").GroupByTimeBucket("created_at", time.Hour).OrderBy("bucket").Query(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	buckets := []string{}
+	for rows.Next() {
+		var n int
+		var bucket string
+		rows.Scan(&n, &bucket)
+		buckets = append(buckets, fmt.Sprintf("%s=%d", bucket, n))
+	}
+	if fmt.Sprint(buckets) != "[2020-01-01 10:00:00=2 2020-01-01 11:00:00=1]" {
+		t.Errorf("Unexpected buckets %v", buckets)
+	}
+}
@@ -0,0 +1,108 @@
+package goql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// routineName matches valid, optionally schema qualified, routine names.
+var routineName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*(\.[A-Za-z_][A-Za-z0-9_$]*)?$`)
+
+// callStyle is the way a database calls a stored procedure.
+type callStyle int
+
+const (
+	// callStatement uses CALL proc(...), as Postgres, MySQL and Oracle do
+	callStatement callStyle = iota
+	// callExec uses EXEC proc ..., marking the sql.Out arguments with
+	// OUTPUT, as MSSQL does
+	callExec
+	// callSelect selects proc(...), as SQLite has no procedures and
+	// only application defined functions can be called
+	callSelect
+)
+
+// Call executes the stored procedure proc with the given arguments in
+// the dialect set with SetDialect: CALL proc(...) in Postgres, MySQL and
+// Oracle, EXEC proc ... in MSSQL and SELECT proc(...) in SQLite, which
+// has no procedures but functions defined by the application.
+// The returned rows hold the result set of the procedure, if any, or
+// the values of its INOUT and OUT parameters on Postgres, which returns
+// them as a row.
+// OUT parameters passed as sql.Out are only supported by the drivers
+// that accept them, such as the ones of MSSQL and Oracle, for example:
+// goql.Call(db, "add_credit", userID, 10, sql.Out{Dest: &balance})
+// With MSSQL they are marked as OUTPUT in the EXEC statement. MySQL
+// drivers don't support them, the OUT parameters of MySQL have to be
+// read from session variables instead.
+func Call(db Queryer, proc string, args ...interface{}) (*sql.Rows, error) {
+	return CallContext(context.Background(), db, proc, args...)
+}
+
+// CallContext is the same as Call but the procedure is canceled when
+// ctx is done.
+func CallContext(ctx context.Context, db Queryer, proc string, args ...interface{}) (*sql.Rows, error) {
+	qry, err := buildProcedureCall(activeDialect(), proc, args)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, qry, args...)
+	return rows, canceled(ctx, err)
+}
+
+// CallFunc calls the set returning or scalar function fn using
+// SELECT * FROM fn(...) and returns its result rows.
+func CallFunc(db Queryer, fn string, args ...interface{}) (*sql.Rows, error) {
+	return CallFuncContext(context.Background(), db, fn, args...)
+}
+
+// CallFuncContext is the same as CallFunc but the function is canceled
+// when ctx is done.
+func CallFuncContext(ctx context.Context, db Queryer, fn string, args ...interface{}) (*sql.Rows, error) {
+	qry, err := buildCall(activeDialect(), "SELECT * FROM", fn, len(args))
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, qry, args...)
+	return rows, canceled(ctx, err)
+}
+
+// buildProcedureCall builds the statement calling proc with args in the
+// dialect d.
+func buildProcedureCall(d Dialect, proc string, args []interface{}) (string, error) {
+	switch featuresOf(d).call {
+	case callExec:
+		if !routineName.MatchString(proc) {
+			return "", fmt.Errorf("goql: invalid routine name %q", proc)
+		}
+		positions := []string{}
+		for i, arg := range args {
+			position := d.Placeholder(i + 1)
+			if _, ok := arg.(sql.Out); ok {
+				position += " OUTPUT"
+			}
+			positions = append(positions, position)
+		}
+		if len(positions) <= 0 {
+			return "EXEC " + proc, nil
+		}
+		return "EXEC " + proc + " " + strings.Join(positions, ", "), nil
+	case callSelect:
+		return buildCall(d, "SELECT", proc, len(args))
+	}
+	return buildCall(d, "CALL", proc, len(args))
+}
+
+func buildCall(d Dialect, stmt string, name string, numArgs int) (string, error) {
+	if !routineName.MatchString(name) {
+		return "", fmt.Errorf("goql: invalid routine name %q", name)
+	}
+	positions := []string{}
+	for i := 1; i <= numArgs; i++ {
+		positions = append(positions, d.Placeholder(i))
+	}
+	return fmt.Sprintf("%s %s(%s)", stmt, name, strings.Join(positions, ",")), nil
+}
@@ -0,0 +1,76 @@
+package goql
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestBuildCall(t *testing.T) {
+	Testing = false
+	expected := `CALL billing.add_credit($1,$2)`
+	qry, err := buildCall(Postgres, "CALL", "billing.add_credit", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if qry != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, qry)
+	}
+}
+
+func TestBuildProcedureCall(t *testing.T) {
+	Testing = false
+	var balance int
+	args := []interface{}{1, 10, sql.Out{Dest: &balance}}
+	cases := []struct {
+		dialect  Dialect
+		expected string
+	}{
+		{Postgres, `CALL add_credit($1,$2,$3)`},
+		{MySQL, `CALL add_credit(?,?,?)`},
+		{MSSQL, `EXEC add_credit @p1, @p2, @p3 OUTPUT`},
+	}
+	for _, c := range cases {
+		qry, err := buildProcedureCall(c.dialect, "add_credit", args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if qry != c.expected {
+			t.Errorf("%s: expected:\n%s\nGot:\n%s", c.dialect.Name(), c.expected, qry)
+		}
+	}
+	if qry, _ := buildProcedureCall(MSSQL, "cleanup", nil); qry != "EXEC cleanup" {
+		t.Errorf("Unexpected statement %s", qry)
+	}
+	if _, err := buildProcedureCall(MSSQL, "x; DROP TABLE user", nil); err == nil {
+		t.Error("Expected an error for an invalid procedure name")
+	}
+}
+
+func TestCallContext(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	rows, err := CallContext(context.Background(), db, "abs", -3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var n int
+	if !rows.Next() || rows.Scan(&n) != nil || n != 3 {
+		t.Errorf("Expected 3, got %d", n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := CallContext(ctx, db, "abs", -3); err != ErrCanceled {
+		t.Errorf("Expected ErrCanceled, got %v", err)
+	}
+}
+
+func TestCallRejectsInvalidNames(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	if _, err := Call(db, "proc(); DROP TABLE user; --"); err == nil {
+		t.Error("Expected an error for an invalid procedure name")
+	}
+}
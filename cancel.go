@@ -0,0 +1,171 @@
+package goql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+)
+
+// ErrCanceled is returned instead of the error of the driver when a
+// query fails because its context was canceled or timed out, for
+// example when the client of an HTTP handler disconnects, so it can be
+// told apart from the errors of the database. The rows of the query are
+// closed and its connection released before it's returned.
+var ErrCanceled = errors.New("goql: the query was canceled")
+
+// canceled returns ErrCanceled when err was caused by the end of ctx,
+// that is when err is the error of ctx or the error the driver returns
+// for a statement canceled on the server. Any other error, such as a
+// constraint violation, is returned as it is even when ctx is done.
+func canceled(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil {
+		return err
+	}
+	if err == context.Canceled || err == context.DeadlineExceeded || isCancelError(err) {
+		return ErrCanceled
+	}
+	return err
+}
+
+// sqlStateQueryCanceled is the SQLSTATE of the statements canceled in
+// Postgres, with pg_cancel_backend or statement_timeout.
+const sqlStateQueryCanceled = "57014"
+
+// isCancelError tells if err is the error of a driver for a canceled
+// statement: the query_canceled SQLSTATE of Postgres, error 1317 of
+// MySQL (ER_QUERY_INTERRUPTED) or SQLITE_INTERRUPT. The drivers are
+// matched by the SQLState method or the Code and Number fields of their
+// errors so none of them has to be imported.
+func isCancelError(err error) bool {
+	if state, ok := err.(interface {
+		SQLState() string
+	}); ok {
+		return state.SQLState() == sqlStateQueryCanceled
+	}
+	val := reflect.Indirect(reflect.ValueOf(err))
+	if val.Kind() != reflect.Struct {
+		return false
+	}
+	if code := val.FieldByName("Code"); code.IsValid() {
+		switch code.Kind() {
+		case reflect.String:
+			return code.String() == sqlStateQueryCanceled
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			// sqlite3.ErrInterrupt
+			return code.Int() == 9
+		}
+	}
+	if number := val.FieldByName("Number"); number.IsValid() {
+		switch number.Kind() {
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return number.Uint() == 1317
+		}
+	}
+	return false
+}
+
+// CancelBackend returns a Queryer that runs each statement on a
+// connection of db checked out for it and, when the context of the
+// statement is done before it finishes, asks Postgres to stop it with
+// pg_cancel_backend from another connection of db, for the drivers that
+// only stop reading the results, leaving long running statements
+// running on the server, for example
+// queryBuilder.QueryAndScanContext(r.Context(), goql.CancelBackend(db), &report)
+// It's only supported by Postgres and it isn't free, so it's meant for
+// the few statements that may run for long: every statement costs an
+// extra round trip to read pg_backend_pid() of the connection, and a
+// goroutine watches its context until it finishes. Statements run on
+// db directly are not watched.
+func CancelBackend(db *sql.DB) Queryer {
+	return backendCanceler{db: db}
+}
+
+// cancelBackendSQL cancels the statement of a backend, see checkout.
+const cancelBackendSQL = `SELECT pg_cancel_backend(pid) FROM pg_stat_activity WHERE pid = $1 AND state = 'active' AND query = $2`
+
+type backendCanceler struct {
+	db *sql.DB
+}
+
+// ExecContext implements Queryer.
+func (c backendCanceler) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	conn, done, err := c.checkout(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	result, err := conn.ExecContext(ctx, query, args...)
+	return result, canceled(ctx, err)
+}
+
+// QueryContext implements Queryer, the connection is released once the
+// rows are closed.
+func (c backendCanceler) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	conn, done, err := c.checkout(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		done()
+		return nil, canceled(ctx, err)
+	}
+	// Closing the connection waits for the rows to be closed
+	go done()
+	return rows, nil
+}
+
+// QueryRowContext implements Queryer, the connection is released once
+// the row is scanned. As a sql.Row can't be built with an error, the
+// statement is run on db as it is when no connection can be checked out.
+func (c backendCanceler) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	conn, done, err := c.checkout(ctx, query)
+	if err != nil {
+		return c.db.QueryRowContext(ctx, query, args...)
+	}
+	row := conn.QueryRowContext(ctx, query, args...)
+	go done()
+	return row
+}
+
+// queryRow implements rowQueryer, returning the error of the checkout.
+func (c backendCanceler) queryRow(ctx context.Context, query string, args ...interface{}) rowScanner {
+	conn, done, err := c.checkout(ctx, query)
+	if err != nil {
+		return errRow{err: err}
+	}
+	row := conn.QueryRowContext(ctx, query, args...)
+	go done()
+	return row
+}
+
+// checkout checks out a connection of the pool and watches ctx until
+// done is called, canceling the statement running on the connection
+// when ctx ends first. done returns the connection to the pool. The
+// backend is only canceled while it's running query, so a late
+// cancellation can't stop the next statement run on the connection.
+func (c backendCanceler) checkout(ctx context.Context, query string) (*sql.Conn, func(), error) {
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return nil, nil, canceled(ctx, err)
+	}
+	var pid int
+	if err := conn.QueryRowContext(ctx, "SELECT pg_backend_pid()").Scan(&pid); err != nil {
+		conn.Close()
+		return nil, nil, canceled(ctx, err)
+	}
+	finished := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.db.ExecContext(context.Background(), cancelBackendSQL, pid, query)
+		case <-finished:
+		}
+	}()
+	done := func() {
+		conn.Close()
+		close(finished)
+	}
+	return conn, done, nil
+}
@@ -0,0 +1,97 @@
+package goql
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestErrCanceled(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`INSERT INTO user (username, password) VALUES ('john', 'x')`)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	qb := QueryBuilder{}
+	qb.Select("id").From("user")
+	if _, err := qb.CountContext(ctx, db); err != ErrCanceled {
+		t.Errorf("Count: expected ErrCanceled, got %v", err)
+	}
+	if _, err := qb.ExistsContext(ctx, db); err != ErrCanceled {
+		t.Errorf("Exists: expected ErrCanceled, got %v", err)
+	}
+	if _, err := qb.QueryContext(ctx, db); err != ErrCanceled {
+		t.Errorf("Query: expected ErrCanceled, got %v", err)
+	}
+	user := User{}
+	if err := (&QueryBuilder{}).SelectExcept(User{}, "total").QueryAndScanContext(ctx, db, &user); err != ErrCanceled {
+		t.Errorf("QueryAndScan: expected ErrCanceled, got %v", err)
+	}
+	if _, err := InsertContext(ctx, db, "user", User{Username: "jane"}); err != ErrCanceled {
+		t.Errorf("Insert: expected ErrCanceled, got %v", err)
+	}
+	users := []User{}
+	if err := RawQuery(db, "SELECT id, username FROM user").WithContext(ctx).ScanAll(&users); err != ErrCanceled {
+		t.Errorf("RawQuery: expected ErrCanceled, got %v", err)
+	}
+	if _, err := CancelBackend(db).QueryContext(ctx, "SELECT 1"); err != ErrCanceled {
+		t.Errorf("CancelBackend: expected ErrCanceled, got %v", err)
+	}
+
+	// The errors of the database are left as they are
+	if _, err := (&QueryBuilder{}).Select("id").From("user").Where("nope = 1").CountContext(context.Background(), db); err == nil || err == ErrCanceled {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+func TestErrCanceledReleasesConnection(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	db.Exec(`INSERT INTO user (username, password) VALUES ('john', 'x'), ('jane', 'y')`)
+	ctx, cancel := context.WithCancel(context.Background())
+	qb := QueryBuilder{}
+	rows, err := qb.Select("id").From("user").QueryContext(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Next()
+	cancel()
+	for rows.Next() {
+	}
+	if err := rows.Err(); err == nil {
+		t.Error("Expected the rows to fail once canceled")
+	}
+	// The only connection of the pool must be available again
+	if n, err := (&QueryBuilder{}).Select("id").From("user").Count(db); err != nil || n != 2 {
+		t.Errorf("Expected 2 rows, got %d %v", n, err)
+	}
+}
+
+type pgError struct{ Code string }
+
+func (e *pgError) Error() string { return "pq: " + e.Code }
+
+type mysqlError struct{ Number uint16 }
+
+func (e *mysqlError) Error() string { return "mysql error" }
+
+func TestCanceledOnlyMapsCancelErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, err := range []error{context.Canceled, context.DeadlineExceeded, &pgError{"57014"}, &mysqlError{1317}} {
+		if canceled(ctx, err) != ErrCanceled {
+			t.Errorf("Expected ErrCanceled for %v", err)
+		}
+	}
+	// A failing statement is reported as it is even when ctx is done
+	for _, err := range []error{&pgError{"23505"}, &mysqlError{1062}, sql.ErrNoRows} {
+		if canceled(ctx, err) != err {
+			t.Errorf("Expected %v to be left as it is", err)
+		}
+	}
+	if err := canceled(context.Background(), context.Canceled); err != context.Canceled {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package goql
+
+// WhereIf is the same as Where but the condition is only added when cond
+// is true, so optional filters keep the chain fluent, for example
+//
+//	queryBuilder.Select("id").From("users").
+//		WhereIf(len(name) > 0, "name = $?", name).
+//		WhereIf(minAge > 0, "age >= $?", minAge)
+func (qb *QueryBuilder) WhereIf(cond bool, where string, vals ...interface{}) *QueryBuilder {
+	if !cond {
+		return qb
+	}
+	return qb.Where(where, vals...)
+}
+
+// OrWhereIf is the same as OrWhere but the condition is only added when
+// cond is true.
+func (qb *QueryBuilder) OrWhereIf(cond bool, where string, vals ...interface{}) *QueryBuilder {
+	if !cond {
+		return qb
+	}
+	return qb.OrWhere(where, vals...)
+}
+
+// HavingIf is the same as Having but the condition is only added when
+// cond is true.
+func (qb *QueryBuilder) HavingIf(cond bool, having string, vals ...interface{}) *QueryBuilder {
+	if !cond {
+		return qb
+	}
+	return qb.Having(having, vals...)
+}
+
+// OrderByIf is the same as OrderBy but the order is only added when cond
+// is true.
+func (qb *QueryBuilder) OrderByIf(cond bool, order interface{}) *QueryBuilder {
+	if !cond {
+		return qb
+	}
+	return qb.OrderBy(order)
+}
+
+// LimitIf is the same as Limit but the limit is only set when cond is
+// true.
+func (qb *QueryBuilder) LimitIf(cond bool, limit interface{}) *QueryBuilder {
+	if !cond {
+		return qb
+	}
+	return qb.Limit(limit)
+}
+
+// OffsetIf is the same as Offset but the offset is only set when cond is
+// true.
+func (qb *QueryBuilder) OffsetIf(cond bool, offset int) *QueryBuilder {
+	if !cond {
+		return qb
+	}
+	return qb.Offset(offset)
+}
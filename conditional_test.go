@@ -0,0 +1,40 @@
+package goql
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestConditionalClauses(t *testing.T) {
+	Testing = false
+	build := func(name string, minAge int, sort string, page int) (string, []interface{}) {
+		qb := QueryBuilder{}
+		qb.Select("id").From("users").
+			WhereIf(len(name) > 0, "name = $?", name).
+			WhereIf(minAge > 0, "age >= $?", minAge).
+			OrWhereIf(len(name) > 0, "nickname = $?", name).
+			GroupBy("id").
+			HavingIf(minAge > 0, "COUNT(*) > $?", 1).
+			OrderByIf(len(sort) > 0, sort).
+			LimitIf(page > 0, 10).
+			OffsetIf(page > 1, (page-1)*10)
+		sql, vals, err := qb.BuildWithArgs()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sql, vals
+	}
+
+	sql, vals := build("", 0, "", 0)
+	if sql != `SELECT id FROM users GROUP BY id` || len(vals) != 0 {
+		t.Errorf("Unexpected query %s %v", sql, vals)
+	}
+	sql, vals = build("john", 18, "age DESC", 3)
+	expected := `SELECT id FROM users WHERE name = $1 AND age >= $2 OR nickname = $3 GROUP BY id HAVING COUNT(*) > $4 ORDER BY age DESC LIMIT 10 OFFSET 20`
+	if sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if fmt.Sprint(vals) != "[john 18 john 1]" {
+		t.Errorf("Unexpected values %v", vals)
+	}
+}
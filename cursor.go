@@ -0,0 +1,118 @@
+package goql
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// CursorPaginate runs the query for the page of limit rows that follows
+// cursor, an empty cursor being the first page, and scans them into
+// dest, which must be a pointer to a slice of structs. Rows are sorted
+// by sortCol and then by idCol, which must be unique, so the page is
+// found with
+// WHERE (sortCol, idCol) > ($?, $?) ORDER BY sortCol, idCol LIMIT limit
+// which unlike OFFSET stays fast and stable over large tables. Both
+// columns must be selected into dest. The returned cursor points to the
+// next page and is empty when there are no more rows.
+func (qb *QueryBuilder) CursorPaginate(db Queryer, sortCol string, idCol string, cursor string, limit int, dest interface{}) (string, error) {
+	return qb.CursorPaginateContext(context.Background(), db, sortCol, idCol, cursor, limit, dest)
+}
+
+// CursorPaginateContext is the same as CursorPaginate but the query is
+// canceled when ctx is done.
+func (qb *QueryBuilder) CursorPaginateContext(ctx context.Context, db Queryer, sortCol string, idCol string, cursor string, limit int, dest interface{}) (string, error) {
+	if limit < 1 {
+		return "", fmt.Errorf("goql: invalid limit %d", limit)
+	}
+	page := qb.unordered()
+	if len(cursor) > 0 {
+		after, err := decodeCursor(cursor)
+		if err != nil {
+			return "", err
+		}
+		page.Where(fmt.Sprintf("(%s, %s) > ($?, $?)", sortCol, idCol), after...)
+	}
+	page.OrderBy(sortCol + ", " + idCol).Limit(limit)
+	sql, vals, err := page.BuildWithArgs()
+	if err != nil {
+		return "", err
+	}
+	rows, err := db.QueryContext(qb.tagged(ctx), sql, vals...)
+	if err != nil {
+		return "", canceled(ctx, err)
+	}
+	if err := scanAll(rows, dest); err != nil {
+		return "", canceled(ctx, err)
+	}
+
+	items := reflect.ValueOf(dest).Elem()
+	if items.Len() < limit {
+		return "", nil
+	}
+	last := reflect.Indirect(items.Index(items.Len() - 1))
+	sortVal, err := cursorField(last, sortCol)
+	if err != nil {
+		return "", err
+	}
+	idVal, err := cursorField(last, idCol)
+	if err != nil {
+		return "", err
+	}
+	return encodeCursor(sortVal, idVal)
+}
+
+// cursorField returns the value of the field of row mapped to col, which
+// can be prefixed with a table alias.
+func cursorField(row reflect.Value, col string) (interface{}, error) {
+	if pos := strings.LastIndex(col, "."); pos >= 0 {
+		col = col[pos+1:]
+	}
+	col = strings.Trim(col, `"`)
+	for i := 0; i <= row.NumField()-1; i++ {
+		if columnName(row.Type(), row.Type().Field(i)) == col {
+			return row.Field(i).Interface(), nil
+		}
+	}
+	return nil, fmt.Errorf("goql: no field of %s is mapped to column %q", row.Type().Name(), col)
+}
+
+// encodeCursor encodes the values of the last row of a page as an
+// opaque token.
+func encodeCursor(vals ...interface{}) (string, error) {
+	data, err := json.Marshal(vals)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor decodes a token returned by encodeCursor, numbers are
+// decoded as int64 when possible so they compare exactly with the ids.
+func decodeCursor(cursor string) ([]interface{}, error) {
+	invalid := errors.New("goql: invalid cursor")
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, invalid
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber()
+	vals := []interface{}{}
+	if err := decoder.Decode(&vals); err != nil || len(vals) != 2 {
+		return nil, invalid
+	}
+	for i, val := range vals {
+		if num, ok := val.(json.Number); ok {
+			if n, err := num.Int64(); err == nil {
+				vals[i] = n
+			} else if f, err := num.Float64(); err == nil {
+				vals[i] = f
+			}
+		}
+	}
+	return vals, nil
+}
@@ -0,0 +1,61 @@
+package goql
+
+import (
+	"testing"
+)
+
+func TestCursorPaginate(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`INSERT INTO user(username, password) VALUES('b', ''), ('a', ''), ('b', ''), ('c', ''), ('a', '')`)
+
+	qb := QueryBuilder{}
+	qb.Select(User{}, Except("total")).OrderBy("ignored")
+	seen := []int64{}
+	cursor := ""
+	for pages := 0; pages < 10; pages++ {
+		users := []User{}
+		next, err := qb.CursorPaginate(db, "username", "id", cursor, 2, &users)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, u := range users {
+			seen = append(seen, u.ID)
+		}
+		if len(next) <= 0 {
+			break
+		}
+		cursor = next
+	}
+	if len(seen) != 5 || seen[0] != 2 || seen[1] != 5 || seen[2] != 1 || seen[3] != 3 || seen[4] != 4 {
+		t.Errorf("Unexpected order %v", seen)
+	}
+
+	users := []User{}
+	if _, err := qb.CursorPaginate(db, "username", "id", "not a cursor", 2, &users); err == nil {
+		t.Error("Expected an error for an invalid cursor")
+	}
+}
+
+func TestCursorPagesDontShareBase(t *testing.T) {
+	Testing = false
+	base := QueryBuilder{}
+	base.Select("id").From("users").
+		Where("a = $?", 1).Where("b = $?", 2).Where("c = $?", 3).
+		OrderBy("id")
+	expected := base.Build()
+	first := base.unordered()
+	first.Where("id > $?", 10).Limit(5)
+	second := base.unordered()
+	second.Where("id > $?", 20).Limit(5)
+	firstSQL, firstVals, _ := first.BuildWithArgs()
+	if firstSQL != "SELECT id FROM users WHERE a = $1 AND b = $2 AND c = $3 AND id > $4 LIMIT 5" || firstVals[3] != 10 {
+		t.Errorf("Unexpected first page %s %v", firstSQL, firstVals)
+	}
+	if _, vals, _ := second.BuildWithArgs(); vals[3] != 20 {
+		t.Errorf("Unexpected second page values %v", vals)
+	}
+	if sql := base.Build(); sql != expected || len(base.GetValues()) != 3 {
+		t.Errorf("The base query changed: %s %v", sql, base.GetValues())
+	}
+}
@@ -0,0 +1,366 @@
+package goql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ddlTypes maps the column types inferred from go types to the type
+// names of each backend supported by CreateTableFor.
+var ddlTypes = map[string]map[string]string{
+	"pg": {
+		"bigint": "BIGINT", "bigserial": "BIGSERIAL", "int": "INTEGER", "serial": "SERIAL",
+		"smallint": "SMALLINT", "real": "REAL", "double": "DOUBLE PRECISION", "bool": "BOOLEAN",
+		"text": "TEXT", "bytes": "BYTEA", "timestamp": "TIMESTAMP WITH TIME ZONE", "time": "TIME",
+		"json": "JSONB",
+	},
+	"mysql": {
+		"bigint": "BIGINT", "bigserial": "BIGINT AUTO_INCREMENT", "int": "INT", "serial": "INT AUTO_INCREMENT",
+		"smallint": "SMALLINT", "real": "FLOAT", "double": "DOUBLE", "bool": "BOOLEAN",
+		"text": "VARCHAR(255)", "bytes": "BLOB", "timestamp": "DATETIME(6)", "time": "TIME",
+		"json": "JSON",
+	},
+	"sqlite": {
+		"bigint": "INTEGER", "bigserial": "INTEGER", "int": "INTEGER", "serial": "INTEGER",
+		"smallint": "INTEGER", "real": "REAL", "double": "REAL", "bool": "BOOLEAN",
+		"text": "TEXT", "bytes": "BLOB", "timestamp": "DATETIME", "time": "TIME",
+		"json": "TEXT",
+	},
+}
+
+// ciTypes maps each backend to the type of the case insensitive text
+// columns, the ones tagged with ci.
+var ciTypes = map[string]string{
+	"pg":     "CITEXT",
+	"mysql":  "VARCHAR(255) COLLATE utf8mb4_general_ci",
+	"sqlite": "TEXT COLLATE NOCASE",
+}
+
+// CreateTable generates the Postgres DDL statements needed to create the
+// table that maps to obj, see CreateTableFor for the details.
+func CreateTable(table string, obj interface{}) ([]string, error) {
+	return CreateTableFor("pg", table, obj)
+}
+
+// CreateTableFor generates the DDL statements needed to create the table
+// that maps to obj in the given backend, which can be "pg", "mysql" or
+// "sqlite". The fields in the structure must have the "db" tag set in the
+// same way they do for Insert and Update.
+// Fields tagged with pk are declared as primary key, fields with the
+// "sql" tag are ignored as they only exist at select time and fields
+// with the "generated" tag are declared as generated columns, for example:
+// Total float64 `db:"total" generated:"stored,expr=price*quantity"`
+//
+// The column type is inferred from the go type of the field unless the
+// "coltype" tag is set, a backend specific type can be set with the
+// "coltype_<backend>" tag which takes precedence, for example:
+// Code string `db:"code" coltype:"CHAR(8)" coltype_pg:"CITEXT"`
+//
+// Indexes are declared with the "index" tag which takes the index name
+// followed by optional "unique", "expr=" and "where=" options. Fields that
+// share an index name produce a single multi column index, for example:
+// Email string `db:"email" index:"idx_user_email,unique,expr=lower(email),where=deleted_at IS NULL"`
+//
+// String fields tagged with ci:"true" are compared case insensitively,
+// they are declared as CITEXT in Postgres, which needs the citext
+// extension, and with a case insensitive collation in MySQL and SQLite.
+//
+// Column comments are taken from the "comment" tag and the table comment
+// from the TableComment method when obj implements TableCommenter.
+// SQLite has no comments, they are written as /* ... */ within the
+// CREATE TABLE statement, whose text SQLite keeps in sqlite_master.
+func CreateTableFor(backend string, table string, obj interface{}) ([]string, error) {
+	types, ok := ddlTypes[backend]
+	if !ok {
+		return nil, fmt.Errorf("unsupported backend %q", backend)
+	}
+	t := reflect.TypeOf(obj)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, errors.New("obj must be a struct")
+	}
+
+	cols := []string{}
+	comments := []string{}
+	citext := false
+	indexes := []*indexDef{}
+	for i := 0; i <= t.NumField()-1; i++ {
+		field := t.Field(i)
+		name := columnName(t, field)
+		if len(name) <= 0 || len(field.Tag.Get("sql")) > 0 {
+			continue
+		}
+		colType, nullable, err := columnType(field)
+		if err != nil {
+			return nil, err
+		}
+		typeName := types[colType]
+		if override := field.Tag.Get("coltype_" + backend); len(override) > 0 {
+			typeName = override
+		} else if override := field.Tag.Get("coltype"); len(override) > 0 {
+			typeName = override
+		} else if len(field.Tag.Get("ci")) > 0 && colType == "text" {
+			typeName = ciTypes[backend]
+			citext = citext || backend == "pg"
+		}
+		def := fmt.Sprintf(`%s %s`, ddlIdent(backend, name), typeName)
+		if gen := field.Tag.Get("generated"); len(gen) > 0 {
+			kind, expr, err := parseGeneratedTag(gen)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %s", field.Name, err)
+			}
+			def += fmt.Sprintf(" GENERATED ALWAYS AS (%s) %s", expr, strings.ToUpper(kind))
+		} else if len(field.Tag.Get("pk")) > 0 {
+			def += " PRIMARY KEY"
+			if backend == "sqlite" && typeName == "INTEGER" && strings.HasSuffix(colType, "serial") {
+				def += " AUTOINCREMENT"
+			}
+		} else if !nullable {
+			def += " NOT NULL"
+		}
+		if comment := field.Tag.Get("comment"); len(comment) > 0 {
+			switch backend {
+			case "pg":
+				comments = append(comments, fmt.Sprintf(`COMMENT ON COLUMN %s.%s IS %s`, table, ddlIdent(backend, name), quoteString(comment)))
+			case "mysql":
+				def += " COMMENT " + ddlString(backend, comment)
+			case "sqlite":
+				def += " " + sqlComment(comment)
+			}
+		}
+		cols = append(cols, def)
+		if tag := field.Tag.Get("index"); len(tag) > 0 {
+			if indexes, err = addIndexTag(indexes, ddlIdent(backend, name), tag); err != nil {
+				return nil, fmt.Errorf("field %s: %s", field.Name, err)
+			}
+		}
+	}
+	if len(cols) <= 0 {
+		return nil, errors.New("obj has no db fields")
+	}
+
+	commenter, hasComment := obj.(TableCommenter)
+	if hasComment && backend == "sqlite" {
+		cols[0] = sqlComment(commenter.TableComment()) + " " + cols[0]
+	}
+	stmt := fmt.Sprintf(`CREATE TABLE %s (%s)`, table, strings.Join(cols, ", "))
+	if hasComment && backend == "mysql" {
+		stmt += " COMMENT=" + ddlString(backend, commenter.TableComment())
+	}
+	stmts := []string{stmt}
+	if citext {
+		stmts = append([]string{"CREATE EXTENSION IF NOT EXISTS citext"}, stmts...)
+	}
+	if hasComment && backend == "pg" {
+		stmts = append(stmts, fmt.Sprintf(`COMMENT ON TABLE %s IS %s`, table, quoteString(commenter.TableComment())))
+	}
+	stmts = append(stmts, comments...)
+	for _, idx := range indexes {
+		if len(idx.where) > 0 && backend == "mysql" {
+			return nil, fmt.Errorf("index %s: mysql doesn't support partial indexes", idx.name)
+		}
+		stmts = append(stmts, idx.build(table))
+	}
+	return stmts, nil
+}
+
+// ddlIdent quotes an identifier for the given backend.
+func ddlIdent(backend string, name string) string {
+	if backend == "mysql" {
+		return MySQL.QuoteIdent(name)
+	}
+	return quoteIdent(name)
+}
+
+// ddlString quotes a string literal for the given backend.
+func ddlString(backend string, s string) string {
+	if backend == "mysql" {
+		s = strings.Replace(s, `\`, `\\`, -1)
+	}
+	return quoteString(s)
+}
+
+// sqlComment returns text as a /* ... */ SQL comment.
+func sqlComment(text string) string {
+	return "/* " + commentText(text) + " */"
+}
+
+// TableCommenter can be implemented by models to document the table
+// in the DDL generated by CreateTable.
+type TableCommenter interface {
+	TableComment() string
+}
+
+// quoteString quotes s as a SQL string literal.
+func quoteString(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// indexDef is an index collected from the index tags of a structure.
+type indexDef struct {
+	name   string
+	unique bool
+	parts  []string
+	where  string
+}
+
+func (idx *indexDef) build(table string) string {
+	qry := "CREATE INDEX"
+	if idx.unique {
+		qry = "CREATE UNIQUE INDEX"
+	}
+	qry = fmt.Sprintf(`%s %s ON %s (%s)`, qry, idx.name, table, strings.Join(idx.parts, ", "))
+	if len(idx.where) > 0 {
+		qry += " WHERE " + idx.where
+	}
+	return qry
+}
+
+// addIndexTag parses an index tag of the quoted column col and merges it into
+// indexes, appending the column to an existing index of the same name.
+func addIndexTag(indexes []*indexDef, col string, tag string) ([]*indexDef, error) {
+	opts := splitTagOptions(tag)
+	name := strings.TrimSpace(opts[0])
+	if len(name) <= 0 {
+		return nil, errors.New("index tag has no name")
+	}
+	var idx *indexDef
+	for _, v := range indexes {
+		if v.name == name {
+			idx = v
+		}
+	}
+	if idx == nil {
+		idx = &indexDef{name: name}
+		indexes = append(indexes, idx)
+	}
+	part := col
+	for _, opt := range opts[1:] {
+		opt = strings.TrimSpace(opt)
+		switch {
+		case opt == "unique":
+			idx.unique = true
+		case strings.HasPrefix(opt, "expr="):
+			part = "(" + strings.TrimPrefix(opt, "expr=") + ")"
+		case strings.HasPrefix(opt, "where="):
+			if len(idx.where) > 0 && idx.where != strings.TrimPrefix(opt, "where=") {
+				return nil, fmt.Errorf("index %s has more than one where predicate", name)
+			}
+			idx.where = strings.TrimPrefix(opt, "where=")
+		default:
+			return nil, fmt.Errorf("invalid index option %q", opt)
+		}
+	}
+	idx.parts = append(idx.parts, part)
+	return indexes, nil
+}
+
+// splitTagOptions splits a tag value on commas that are not inside
+// parentheses or quotes so expressions can be used as option values.
+func splitTagOptions(tag string) []string {
+	parts := []string{}
+	depth := 0
+	quoted := false
+	start := 0
+	for i, c := range tag {
+		switch {
+		case c == '\'':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, tag[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, tag[start:])
+}
+
+// parseGeneratedTag parses the value of a generated tag which has the
+// form "stored,expr=<expression>". The expression is everything after
+// "expr=" so it can contain commas of its own.
+func parseGeneratedTag(tag string) (kind string, expr string, err error) {
+	pos := strings.Index(tag, "expr=")
+	if pos < 0 {
+		return "", "", errors.New("generated tag has no expr")
+	}
+	expr = strings.TrimSpace(tag[pos+len("expr="):])
+	kind = strings.ToLower(strings.Trim(tag[:pos], ", "))
+	if len(kind) <= 0 {
+		kind = "stored"
+	}
+	if kind != "stored" && kind != "virtual" {
+		return "", "", fmt.Errorf("invalid generated column kind %q", kind)
+	}
+	if len(expr) <= 0 {
+		return "", "", errors.New("generated tag has an empty expr")
+	}
+	return kind, expr, nil
+}
+
+// columnType maps the go type of a field to one of the column types of
+// ddlTypes and tells whether the column can hold NULL values.
+func columnType(field reflect.StructField) (string, bool, error) {
+	t := field.Type
+	nullable := false
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+	isPk := len(field.Tag.Get("pk")) > 0
+
+	switch field.Tag.Get("type") {
+	case "time":
+		return "time", nullable, nil
+	case "json":
+		return "json", true, nil
+	}
+
+	switch t {
+	case reflect.TypeOf(time.Time{}):
+		return "timestamp", nullable, nil
+	case reflect.TypeOf([]byte{}):
+		return "bytes", nullable, nil
+	case reflect.TypeOf(sql.NullString{}):
+		return "text", true, nil
+	case reflect.TypeOf(sql.NullInt64{}):
+		return "bigint", true, nil
+	case reflect.TypeOf(sql.NullFloat64{}):
+		return "double", true, nil
+	case reflect.TypeOf(sql.NullBool{}):
+		return "bool", true, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Int64, reflect.Uint32, reflect.Uint64:
+		if isPk {
+			return "bigserial", false, nil
+		}
+		return "bigint", nullable, nil
+	case reflect.Int, reflect.Int32, reflect.Uint, reflect.Uint16:
+		if isPk {
+			return "serial", false, nil
+		}
+		return "int", nullable, nil
+	case reflect.Int8, reflect.Int16, reflect.Uint8:
+		return "smallint", nullable, nil
+	case reflect.Float32:
+		return "real", nullable, nil
+	case reflect.Float64:
+		return "double", nullable, nil
+	case reflect.Bool:
+		return "bool", nullable, nil
+	case reflect.String:
+		return "text", nullable, nil
+	}
+	return "", false, fmt.Errorf("field %s: unsupported type %s", field.Name, t)
+}
@@ -0,0 +1,225 @@
+package goql
+
+import (
+	"strings"
+	"testing"
+)
+
+type Product struct {
+	ID       int64   `db:"id" pk:"true"`
+	Name     string  `db:"name"`
+	Price    float64 `db:"price"`
+	Quantity int     `db:"quantity"`
+	Total    float64 `db:"total" generated:"stored,expr=price*quantity"`
+	Notes    *string `db:"notes"`
+	Ignored  string
+}
+
+func TestCreateTable(t *testing.T) {
+	expected := `CREATE TABLE product ("id" BIGSERIAL PRIMARY KEY, "name" TEXT NOT NULL, "price" DOUBLE PRECISION NOT NULL, "quantity" INTEGER NOT NULL, "total" DOUBLE PRECISION GENERATED ALWAYS AS (price*quantity) STORED, "notes" TEXT)`
+	stmts, err := CreateTable("product", Product{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 1 || stmts[0] != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%v", expected, stmts)
+	}
+}
+
+func TestCreateTableWithInvalidGeneratedTag(t *testing.T) {
+	type Invalid struct {
+		Total float64 `db:"total" generated:"stored"`
+	}
+	if _, err := CreateTable("invalid", Invalid{}); err == nil {
+		t.Error("Expected an error for a generated tag without expr")
+	}
+}
+
+func TestInsertSkipsGeneratedColumns(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	_, err := db.Exec(`
+		CREATE TABLE product(
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name CHAR(255),
+			price REAL,
+			quantity INTEGER,
+			total REAL GENERATED ALWAYS AS (price*quantity) STORED,
+			notes CHAR(255)
+		)`)
+	if err != nil {
+		t.Skip("sqlite build without generated column support: ", err)
+	}
+	_, err = Insert(db, "product", Product{Name: "pen", Price: 1.5, Quantity: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var total float64
+	if err = db.QueryRow("SELECT total FROM product").Scan(&total); err != nil {
+		t.Fatal(err)
+	}
+	if total != 6 {
+		t.Errorf("Expected total 6, got %v", total)
+	}
+}
+
+func TestCreateTableWithIndexes(t *testing.T) {
+	type Account struct {
+		ID        int64   `db:"id" pk:"true"`
+		Email     string  `db:"email" index:"idx_account_email,unique,expr=lower(email),where=deleted_at IS NULL"`
+		Country   string  `db:"country" index:"idx_account_location"`
+		City      string  `db:"city" index:"idx_account_location,where=status IN ('a','b')"`
+		DeletedAt *string `db:"deleted_at"`
+	}
+	stmts, err := CreateTable("account", Account{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`CREATE UNIQUE INDEX idx_account_email ON account ((lower(email))) WHERE deleted_at IS NULL`,
+		`CREATE INDEX idx_account_location ON account ("country", "city") WHERE status IN ('a','b')`,
+	}
+	if len(stmts) != 3 {
+		t.Fatalf("Expected 3 statements, got %v", stmts)
+	}
+	for i, v := range expected {
+		if stmts[i+1] != v {
+			t.Errorf("Expected:\n%s\nGot:\n%s", v, stmts[i+1])
+		}
+	}
+}
+
+type Invoice struct {
+	ID    int64  `db:"id" pk:"true"`
+	Payer string `db:"payer" comment:"Customer's legal name"`
+}
+
+func (Invoice) TableComment() string {
+	return "Issued invoices"
+}
+
+func TestCreateTableWithComments(t *testing.T) {
+	stmts, err := CreateTable("invoice", Invoice{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`CREATE TABLE invoice ("id" BIGSERIAL PRIMARY KEY, "payer" TEXT NOT NULL)`,
+		`COMMENT ON TABLE invoice IS 'Issued invoices'`,
+		`COMMENT ON COLUMN invoice."payer" IS 'Customer''s legal name'`,
+	}
+	if strings.Join(stmts, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected:\n%s\nGot:\n%s", strings.Join(expected, "\n"), strings.Join(stmts, "\n"))
+	}
+
+	// SQLite keeps the comments in the text of the statement
+	stmts, err = CreateTableFor("sqlite", "invoice", Invoice{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedSQL := `CREATE TABLE invoice (/* Issued invoices */ "id" INTEGER PRIMARY KEY AUTOINCREMENT, "payer" TEXT NOT NULL /* Customer's legal name */)`
+	if strings.Join(stmts, "\n") != expectedSQL {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expectedSQL, strings.Join(stmts, "\n"))
+	}
+	db := dbSetup()
+	defer db.Close()
+	if _, err := db.Exec(stmts[0]); err != nil {
+		t.Fatal(err)
+	}
+	var schema string
+	if err := db.QueryRow(`SELECT sql FROM sqlite_master WHERE name = 'invoice'`).Scan(&schema); err != nil || schema != expectedSQL {
+		t.Errorf("Expected the comments in the schema, got %s %v", schema, err)
+	}
+}
+
+type Coupon struct {
+	ID   int64   `db:"id" pk:"true"`
+	Code string  `db:"code" coltype:"CHAR(8)" coltype_pg:"CITEXT" comment:"Public code"`
+	Rate float64 `db:"rate" index:"idx_coupon_rate"`
+}
+
+func TestCreateTableForMySQL(t *testing.T) {
+	stmts, err := CreateTableFor("mysql", "coupon", Coupon{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"CREATE TABLE coupon (`id` BIGINT AUTO_INCREMENT PRIMARY KEY, `code` CHAR(8) NOT NULL COMMENT 'Public code', `rate` DOUBLE NOT NULL)",
+		"CREATE INDEX idx_coupon_rate ON coupon (`rate`)",
+	}
+	if strings.Join(stmts, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected:\n%s\nGot:\n%s", strings.Join(expected, "\n"), strings.Join(stmts, "\n"))
+	}
+}
+
+func TestCreateTableColumnTypeOverrides(t *testing.T) {
+	stmts, err := CreateTable("coupon", Coupon{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `CREATE TABLE coupon ("id" BIGSERIAL PRIMARY KEY, "code" CITEXT NOT NULL, "rate" DOUBLE PRECISION NOT NULL)`
+	if stmts[0] != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, stmts[0])
+	}
+}
+
+func TestCreateTableForSQLiteRuns(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	stmts, err := CreateTableFor("sqlite", "coupon", Coupon{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %s", stmt, err)
+		}
+	}
+	if _, err := Insert(db, "coupon", Coupon{Code: "SAVE10", Rate: 0.1}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCreateTableForUnknownBackend(t *testing.T) {
+	if _, err := CreateTableFor("oracle", "coupon", Coupon{}); err == nil {
+		t.Error("Expected an error for an unknown backend")
+	}
+}
+
+type account struct {
+	ID    int64  `db:"id" pk:"true"`
+	Email string `db:"email" ci:"true"`
+}
+
+func TestCreateTableCaseInsensitiveColumns(t *testing.T) {
+	stmts, err := CreateTable("account", account{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `CREATE EXTENSION IF NOT EXISTS citext;CREATE TABLE account ("id" BIGSERIAL PRIMARY KEY, "email" CITEXT NOT NULL)`
+	if got := strings.Join(stmts, ";"); got != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+	}
+
+	db := dbSetup()
+	defer db.Close()
+	stmts, err = CreateTableFor("sqlite", "account", account{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(stmts[0]); err != nil {
+		t.Fatal(err)
+	}
+	db.Exec(`INSERT INTO account(email) VALUES('Ana@Example.com')`)
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM account WHERE email = ?`, "ana@example.COM").Scan(&count); err != nil || count != 1 {
+		t.Errorf("Expected a case insensitive match, got %d %v", count, err)
+	}
+
+	Testing = false
+	qb := QueryBuilder{}
+	qb.Select("id").From("users").WhereEqualFold("email", "A@b.com")
+	if sql := qb.Build(); sql != `SELECT id FROM users WHERE lower(email) = lower($1)` {
+		t.Errorf("Unexpected query %s", sql)
+	}
+}
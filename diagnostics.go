@@ -0,0 +1,131 @@
+package goql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"log"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// SkipReason tells why a struct field is not mapped to a column.
+type SkipReason string
+
+// Reasons of the fields skipped by the struct mapping.
+const (
+	// SkipUntagged is an exported field without a db tag, which is only
+	// mapped by a naming strategy, see AutoMapFields.
+	SkipUntagged SkipReason = "untagged"
+	// SkipUnsupportedType is a field whose type can't be bound nor
+	// scanned by database/sql, such as a map without type:"json".
+	SkipUnsupportedType SkipReason = "unsupported type"
+	// SkipComputed is a field tagged with sql or generated, which is
+	// selected but never written by Insert and Update.
+	SkipComputed SkipReason = "computed"
+)
+
+// FieldWarning is a field of a model skipped by the struct mapping, see
+// Diagnostics.
+type FieldWarning struct {
+	Model  string
+	Field  string
+	Reason SkipReason
+}
+
+func (w FieldWarning) String() string {
+	return fmt.Sprintf("goql: %s.%s is skipped: %s", w.Model, w.Field, w.Reason)
+}
+
+// LogFieldWarnings logs the warnings of each model the first time it's
+// mapped, which helps to notice the drift between the structs and the
+// schema.
+var LogFieldWarnings = false
+
+var (
+	diagnosticsMu sync.Mutex
+	checkedModels = map[reflect.Type]bool{}
+	fieldWarnings []FieldWarning
+)
+
+// Diagnostics returns the fields skipped by the struct mapping of the
+// models used so far, in the order they were found. Fields that are
+// silently dropped, such as a new column whose field was not tagged, are
+// easy to miss otherwise.
+func Diagnostics() []FieldWarning {
+	diagnosticsMu.Lock()
+	defer diagnosticsMu.Unlock()
+	return append([]FieldWarning{}, fieldWarnings...)
+}
+
+// checkFields records the warnings of the model t the first time it's
+// mapped.
+func checkFields(t reflect.Type) {
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	diagnosticsMu.Lock()
+	defer diagnosticsMu.Unlock()
+	if checkedModels[t] {
+		return
+	}
+	checkedModels[t] = true
+	for _, w := range fieldWarningsOf(t) {
+		fieldWarnings = append(fieldWarnings, w)
+		if LogFieldWarnings {
+			log.Println(w)
+		}
+	}
+}
+
+// fieldWarningsOf returns the fields of the struct t skipped by the
+// struct mapping.
+func fieldWarningsOf(t reflect.Type) []FieldWarning {
+	warnings := []FieldWarning{}
+	for i := 0; i <= t.NumField()-1; i++ {
+		field := t.Field(i)
+		if len(field.PkgPath) > 0 || field.Anonymous || len(field.Tag.Get("join")) > 0 || field.Tag.Get("db") == "-" {
+			continue
+		}
+		var reason SkipReason
+		switch {
+		case len(columnName(t, field)) <= 0:
+			reason = SkipUntagged
+		case len(field.Tag.Get("sql")) > 0 || len(field.Tag.Get("generated")) > 0:
+			reason = SkipComputed
+		case len(field.Tag.Get("type")) <= 0 && !bindable(field.Type):
+			reason = SkipUnsupportedType
+		default:
+			continue
+		}
+		warnings = append(warnings, FieldWarning{Model: t.Name(), Field: field.Name, Reason: reason})
+	}
+	return warnings
+}
+
+var (
+	valuerType  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+// bindable tells whether values of type t can be bound and scanned by
+// database/sql without a conversion.
+func bindable(t reflect.Type) bool {
+	if t.Implements(valuerType) || reflect.PtrTo(t).Implements(scannerType) {
+		return true
+	}
+	if t.Kind() == reflect.Ptr {
+		return bindable(t.Elem())
+	}
+	switch t.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Map, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer, reflect.Array:
+		return false
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.Uint8
+	case reflect.Struct:
+		return t == timeType
+	}
+	return true
+}
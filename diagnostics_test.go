@@ -0,0 +1,44 @@
+package goql
+
+import (
+	"testing"
+)
+
+type driftedModel struct {
+	ID       int64             `db:"id" pk:"true"`
+	Nickname string            // forgotten db tag
+	Meta     map[string]string `db:"meta"`
+	Props    map[string]string `db:"props" type:"json"`
+	Total    int64             `db:"total" sql:"COUNT(*)"`
+	Ignored  string            `db:"-"`
+	internal string
+}
+
+func TestDiagnostics(t *testing.T) {
+	qb := QueryBuilder{}
+	qb.Select(driftedModel{})
+	qb.Select(driftedModel{})
+
+	warnings := map[string]SkipReason{}
+	for _, w := range Diagnostics() {
+		if w.Model == "driftedModel" {
+			if _, ok := warnings[w.Field]; ok {
+				t.Errorf("%s was reported twice", w.Field)
+			}
+			warnings[w.Field] = w.Reason
+		}
+	}
+	expected := map[string]SkipReason{"Nickname": SkipUntagged, "Meta": SkipUnsupportedType, "Total": SkipComputed}
+	if len(warnings) != len(expected) {
+		t.Errorf("Expected %v, got %v", expected, warnings)
+	}
+	for field, reason := range expected {
+		if warnings[field] != reason {
+			t.Errorf("Expected %s to be skipped as %q, got %q", field, reason, warnings[field])
+		}
+	}
+	w := FieldWarning{Model: "driftedModel", Field: "Nickname", Reason: SkipUntagged}
+	if w.String() != "goql: driftedModel.Nickname is skipped: untagged" {
+		t.Errorf("Unexpected message %s", w)
+	}
+}
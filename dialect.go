@@ -0,0 +1,459 @@
+package goql
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Dialect is the SQL flavor spoken by a database: how the placeholders
+// and the identifiers are written, how the rows of a query are limited
+// and which statements are supported.
+// The dialect is set for all the queries with SetDialect or for a single
+// query with QueryBuilder.UseDialect, Postgres is used by default.
+//
+// The differences that can't be expressed with this interface, such as
+// the pagination style, the JSON operators, upserts or row locking, are
+// only known for the dialects of the package. Any other implementation
+// is treated as Postgres for them, so a custom dialect should describe a
+// database close to Postgres or embed the dialect it extends, whose
+// behavior is kept, for example
+//
+//	type cockroach struct{ goql.Dialect }
+//
+//	func (cockroach) Name() string { return "cockroach" }
+//
+//	goql.SetDialect(cockroach{goql.Postgres})
+type Dialect interface {
+	// Name is the name of the dialect, the same name CreateTableFor
+	// takes as backend, for example "pg".
+	Name() string
+	// Placeholder returns the placeholder of the n-th value of a
+	// statement, starting at 1.
+	Placeholder(n int) string
+	// QuoteIdent quotes an identifier such as a table or column name.
+	QuoteIdent(name string) string
+	// Limit returns the clause that limits the rows returned by a query,
+	// limit or offset are empty when they are not set.
+	Limit(limit string, offset string) string
+	// SupportsReturning tells whether INSERT, UPDATE and DELETE can
+	// return the affected rows with RETURNING. UpsertInserted doesn't
+	// read the returned row when it's false.
+	SupportsReturning() bool
+}
+
+// Postgres is the dialect of PostgreSQL, it's the default dialect.
+var Postgres Dialect = postgres{}
+
+type postgres struct{}
+
+func (postgres) Name() string {
+	return "pg"
+}
+
+func (postgres) Placeholder(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+func (postgres) QuoteIdent(name string) string {
+	return quoteIdent(name)
+}
+
+func (postgres) Limit(limit string, offset string) string {
+	parts := []string{}
+	if len(limit) > 0 {
+		parts = append(parts, "LIMIT "+limit)
+	}
+	if len(offset) > 0 {
+		parts = append(parts, "OFFSET "+offset)
+	}
+	return strings.Join(parts, " ")
+}
+
+func (postgres) SupportsReturning() bool {
+	return true
+}
+
+func (postgres) features() dialectFeatures {
+	return dialectFeatures{pagination: paginateLimit, distinctOn: true, locking: true, systemColumns: true, exists: true, ilike: true, arrays: true, nullSafeEq: "IS NOT DISTINCT FROM"}
+}
+
+// MySQL is the dialect of MySQL, it uses ? placeholders, identifiers
+// quoted with backticks and LIMIT offset, count pagination. Full outer
+// joins and NULLS FIRST or LAST are emulated as MySQL lacks them.
+var MySQL Dialect = mysql{}
+
+type mysql struct{}
+
+func (mysql) Name() string {
+	return "mysql"
+}
+
+func (mysql) Placeholder(n int) string {
+	return "?"
+}
+
+func (mysql) QuoteIdent(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+func (mysql) Limit(limit string, offset string) string {
+	if len(offset) <= 0 {
+		if len(limit) <= 0 {
+			return ""
+		}
+		return "LIMIT " + limit
+	}
+	if len(limit) <= 0 {
+		limit = maxLimit
+	}
+	return "LIMIT " + offset + ", " + limit
+}
+
+func (mysql) SupportsReturning() bool {
+	return false
+}
+
+func (mysql) features() dialectFeatures {
+	return dialectFeatures{
+		pagination:       paginateLimitRequired,
+		emulateNulls:     true,
+		emulateFullJoins: true,
+		locking:          true,
+		upsert:           upsertOnDuplicateKey,
+		exists:           true,
+		json:             jsonMySQL,
+		nullSafeEq:       "<=>",
+		indexHints:       hintMySQL,
+		timeBucket:       bucketMySQL,
+	}
+}
+
+// SQLite is the dialect of SQLite, it uses ? placeholders and renders
+// no locking clauses as SQLite locks the whole database instead of rows.
+// Upsert needs SQLite 3.24 or later.
+var SQLite Dialect = sqlite{}
+
+type sqlite struct{}
+
+func (sqlite) Name() string {
+	return "sqlite"
+}
+
+func (sqlite) Placeholder(n int) string {
+	return "?"
+}
+
+func (sqlite) QuoteIdent(name string) string {
+	return quoteIdent(name)
+}
+
+func (sqlite) Limit(limit string, offset string) string {
+	if len(limit) <= 0 && len(offset) > 0 {
+		// A negative limit means no limit
+		limit = "-1"
+	}
+	return postgres{}.Limit(limit, offset)
+}
+
+func (sqlite) SupportsReturning() bool {
+	return true
+}
+
+func (sqlite) features() dialectFeatures {
+	return dialectFeatures{pagination: paginateLimit, serialized: true, exists: true, json: jsonSQLite, nullSafeEq: "IS", indexHints: hintSQLite, timeBucket: bucketSQLite, call: callSelect}
+}
+
+// MSSQL is the dialect of Microsoft SQL Server 2012 or later, it uses
+// @pN placeholders, identifiers quoted with brackets and paginates with
+// SELECT TOP n or, when there is an offset, OFFSET m ROWS FETCH NEXT n
+// ROWS ONLY. NULLS FIRST and LAST are emulated, WhereEqNullSafe needs
+// SQL Server 2022 and the row locks, the JSON, array, time bucket and
+// upsert helpers are not supported, they record an error.
+var MSSQL Dialect = mssql{}
+
+type mssql struct{}
+
+func (mssql) Name() string {
+	return "mssql"
+}
+
+func (mssql) Placeholder(n int) string {
+	return fmt.Sprintf("@p%d", n)
+}
+
+func (mssql) QuoteIdent(name string) string {
+	return "[" + strings.Replace(name, "]", "]]", -1) + "]"
+}
+
+func (mssql) Limit(limit string, offset string) string {
+	return fetchLimit(limit, offset)
+}
+
+func (mssql) SupportsReturning() bool {
+	return false
+}
+
+func (mssql) features() dialectFeatures {
+	return dialectFeatures{
+		pagination:   paginateTop,
+		emulateNulls: true,
+		upsert:       upsertUnsupported,
+		json:         jsonUnsupported,
+		nullSafeEq:   "IS NOT DISTINCT FROM",
+		timeBucket:   bucketUnsupported,
+		call:         callExec,
+	}
+}
+
+// Oracle is the dialect of Oracle 12c or later, it uses :N placeholders
+// and paginates with OFFSET m ROWS FETCH NEXT n ROWS ONLY. Oracle11 is
+// the one of the older versions. WhereEqNullSafe needs Oracle 23ai and
+// the JSON, array, time bucket and upsert helpers are not supported,
+// they record an error.
+var Oracle Dialect = oracle{}
+
+// Oracle11 is the dialect of Oracle before 12c, it's the same as Oracle
+// but it paginates filtering on the ROWNUM of a subquery.
+var Oracle11 Dialect = oracle11{}
+
+type oracle struct{}
+
+func (oracle) Name() string {
+	return "oracle"
+}
+
+func (oracle) Placeholder(n int) string {
+	return fmt.Sprintf(":%d", n)
+}
+
+func (oracle) QuoteIdent(name string) string {
+	return quoteIdent(name)
+}
+
+func (oracle) Limit(limit string, offset string) string {
+	return fetchLimit(limit, offset)
+}
+
+func (oracle) SupportsReturning() bool {
+	return false
+}
+
+func (oracle) features() dialectFeatures {
+	return dialectFeatures{
+		pagination: paginateFetch,
+		locking:    true,
+		upsert:     upsertUnsupported,
+		json:       jsonUnsupported,
+		nullSafeEq: "IS NOT DISTINCT FROM",
+		timeBucket: bucketUnsupported,
+	}
+}
+
+type oracle11 struct {
+	oracle
+}
+
+func (oracle11) Name() string {
+	return "oracle11"
+}
+
+func (oracle11) features() dialectFeatures {
+	f := oracle{}.features()
+	f.pagination = paginateRownum
+	return f
+}
+
+// fetchLimit returns the standard OFFSET m ROWS FETCH NEXT n ROWS ONLY
+// clause.
+func fetchLimit(limit string, offset string) string {
+	if len(limit) <= 0 && len(offset) <= 0 {
+		return ""
+	}
+	if len(offset) <= 0 {
+		offset = "0"
+	}
+	clause := "OFFSET " + offset + " ROWS"
+	if len(limit) > 0 {
+		clause += " FETCH NEXT " + limit + " ROWS ONLY"
+	}
+	return clause
+}
+
+// LegacySQLite is the dialect of SQLite before 3.39, which lacks RIGHT
+// and FULL OUTER JOIN. A query whose first join is a right join is
+// rewritten as a left join of both tables swapped, and full outer joins
+// are emulated as in MySQL. As the tables are swapped the columns should
+// be selected by name, SELECT * returns them in another order.
+var LegacySQLite Dialect = legacySQLite{}
+
+type legacySQLite struct {
+	sqlite
+}
+
+func (legacySQLite) features() dialectFeatures {
+	f := sqlite{}.features()
+	f.emulateFullJoins = true
+	f.swapRightJoins = true
+	return f
+}
+
+// upsertStyle is the way a database expresses an insert that updates
+// the row when its key already exists.
+type upsertStyle int
+
+const (
+	// upsertOnConflict uses ON CONFLICT (...) DO UPDATE, as Postgres does
+	upsertOnConflict upsertStyle = iota
+	// upsertOnDuplicateKey uses ON DUPLICATE KEY UPDATE, as MySQL does
+	upsertOnDuplicateKey
+	// upsertUnsupported is the style of the databases whose upserts,
+	// such as MERGE, are not supported
+	upsertUnsupported
+)
+
+// dialectFeatures are the differences between the databases that can't
+// be expressed with the Dialect interface, they are known only for the
+// dialects of the package and the other dialects get the ones of
+// Postgres.
+type dialectFeatures struct {
+	// pagination is the style of LIMIT and OFFSET
+	pagination paginationStyle
+	// emulateNulls emulates NULLS FIRST and NULLS LAST in ORDER BY
+	emulateNulls bool
+	// emulateFullJoins emulates full outer joins with a union
+	emulateFullJoins bool
+	// distinctOn tells whether SELECT DISTINCT ON is supported
+	distinctOn bool
+	// locking tells whether FOR UPDATE and FOR SHARE are supported
+	locking bool
+	// serialized tells whether the writes of a transaction lock the
+	// whole database, so the rows it reads can't be locked by others
+	// and the locking clauses are not needed
+	serialized bool
+	// upsert is the style of Upsert
+	upsert upsertStyle
+	// systemColumns tells whether the Postgres system columns, such as
+	// xmax, can be selected
+	systemColumns bool
+	// exists tells whether SELECT EXISTS(...) is supported
+	exists bool
+	// ilike tells whether ILIKE is supported
+	ilike bool
+	// json is the style of the JSON helpers
+	json jsonStyle
+	// arrays tells whether array columns and parameters are supported
+	arrays bool
+	// nullSafeEq is the equality operator that holds for two NULLs
+	nullSafeEq string
+	// indexHints is the style of UseIndex, ForceIndex and IgnoreIndex
+	indexHints indexHintStyle
+	// timeBucket is the style of GroupByTimeBucket
+	timeBucket bucketStyle
+	// call is the style of Call
+	call callStyle
+	// swapRightJoins rewrites a leading right join as a left join
+	swapRightJoins bool
+}
+
+// featuresOf returns the features of the dialect d, the ones of the
+// dialect it embeds or the ones of Postgres.
+func featuresOf(d Dialect) dialectFeatures {
+	if f, ok := d.(interface {
+		features() dialectFeatures
+	}); ok {
+		return f.features()
+	}
+	v := reflect.Indirect(reflect.ValueOf(d))
+	if v.Kind() == reflect.Struct {
+		for i := 0; i <= v.NumField()-1; i++ {
+			field := v.Type().Field(i)
+			if !field.Anonymous || len(field.PkgPath) > 0 {
+				continue
+			}
+			if embedded, ok := v.Field(i).Interface().(Dialect); ok && embedded != nil {
+				return featuresOf(embedded)
+			}
+		}
+	}
+	return postgres{}.features()
+}
+
+var defaultDialect = Postgres
+
+// SetDialect sets the dialect of the queries that don't set their own
+// with UseDialect and of the statements run by Insert, Update, Delete
+// and the rest of the package level helpers. It's meant to be called
+// once on start up.
+func SetDialect(d Dialect) {
+	if d == nil {
+		panic("goql: nil dialect")
+	}
+	defaultDialect = d
+}
+
+// activeDialect returns the dialect of the package level helpers.
+func activeDialect() Dialect {
+	if Testing {
+		return SQLite
+	}
+	return defaultDialect
+}
+
+// UseDialect sets the dialect of the query, overriding the one set with
+// SetDialect. As identifiers are quoted when the clauses are added, it
+// should be called before any of them, for example
+// queryBuilder.UseDialect(goql.Postgres).Select(User{})
+func (qb *QueryBuilder) UseDialect(d Dialect) (ret *QueryBuilder) {
+	ret = qb
+	qb.invalidate()
+	qb.dialect = d
+	return
+}
+
+// PlaceholderFormat renders the placeholder of the n-th value of a
+// statement, starting at 1.
+type PlaceholderFormat func(n int) string
+
+// Placeholders sets how the placeholders of the query are rendered,
+// overriding the ones of its dialect, for drivers that expect a format
+// no dialect uses, for example
+// queryBuilder.Placeholders(func(n int) string { return fmt.Sprintf(":p%d", n) })
+func (qb *QueryBuilder) Placeholders(format PlaceholderFormat) (ret *QueryBuilder) {
+	ret = qb
+	qb.invalidate()
+	qb.placeholders = format
+	return
+}
+
+// formattedDialect is a dialect whose placeholders are rendered with
+// a PlaceholderFormat.
+type formattedDialect struct {
+	Dialect
+	format PlaceholderFormat
+}
+
+func (d formattedDialect) Placeholder(n int) string {
+	return d.format(n)
+}
+
+func (d formattedDialect) features() dialectFeatures {
+	return featuresOf(d.Dialect)
+}
+
+// getDialect returns the dialect the query is built with.
+func (qb *QueryBuilder) getDialect() Dialect {
+	d := qb.dialect
+	if d == nil {
+		d = activeDialect()
+	}
+	if qb.placeholders != nil {
+		return formattedDialect{Dialect: d, format: qb.placeholders}
+	}
+	return d
+}
+
+// quote quotes an identifier with the dialect of the query.
+func (qb *QueryBuilder) quote(name string) string {
+	return qb.getDialect().QuoteIdent(name)
+}
@@ -0,0 +1,271 @@
+package goql
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type atDialect struct {
+	postgres
+}
+
+func (atDialect) Name() string {
+	return "at"
+}
+
+func (atDialect) Placeholder(n int) string {
+	return fmt.Sprintf("@p%d", n)
+}
+
+func (atDialect) QuoteIdent(name string) string {
+	return "[" + name + "]"
+}
+
+func (atDialect) Limit(limit string, offset string) string {
+	return "OFFSET " + offset + " ROWS FETCH NEXT " + limit + " ROWS ONLY"
+}
+
+func TestUseDialect(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.UseDialect(atDialect{}).Select(User{}, IgnoreComputed()).Where("id > $?", 10).Limit(5).Offset(10)
+	expected := `SELECT [id],[username],[password],[total] FROM user WHERE id > @p1 OFFSET 10 ROWS FETCH NEXT 5 ROWS ONLY`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+}
+
+func TestSetDialect(t *testing.T) {
+	Testing = false
+	SetDialect(atDialect{})
+	defer SetDialect(Postgres)
+	info, err := creatQueryStructInfo(User{ID: 1, Username: "john", Password: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `[username] = @p1,[password] = @p2 WHERE [id] = @p3`
+	if sql := strings.Join(info.FieldsForUpdate, ",") + " WHERE " + strings.Join(info.PrimaryKeyQuery, " AND "); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	qb := QueryBuilder{}
+	qb.Select("id").From("users").Where("id = $?", 1)
+	if sql := qb.Build(); sql != `SELECT id FROM users WHERE id = @p1` {
+		t.Errorf("Unexpected SQL: %s", sql)
+	}
+}
+
+func TestMySQLDialect(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.UseDialect(MySQL).Select(member{}).Where("id > $?", 1).OrderByAsc("username", NullsLast()).Limit(10).Offset(20)
+	expected := "SELECT `id`,`username` FROM member WHERE id > ? ORDER BY username IS NULL, username ASC LIMIT 20, 10"
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+
+	qb = QueryBuilder{}
+	qb.UseDialect(MySQL).Select("a.id").From("a").FullOuterJoin("b ON b.id = a.b_id AND b.ok = $?", true).Where("a.n > $?", 3)
+	expected = "SELECT a.id FROM a LEFT JOIN b ON b.id = a.b_id AND b.ok = ? WHERE a.n > ? UNION ALL SELECT a.id FROM a RIGHT JOIN b ON b.id = a.b_id AND b.ok = ? WHERE a.n > ? AND a.b_id IS NULL"
+	sql, args, err := qb.BuildWithArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if fmt.Sprint(args) != "[true 3 true 3]" {
+		t.Errorf("Unexpected args %v", args)
+	}
+
+	qb = QueryBuilder{}
+	qb.UseDialect(MySQL).Select("id").From("a").DistinctOn("id")
+	if _, _, err := qb.BuildWithArgs(); err == nil {
+		t.Error("Expected DISTINCT ON to fail with MySQL")
+	}
+}
+
+func TestMySQLUpsert(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	defer resetStatementHooks()
+	Testing = false
+	SetDialect(MySQL)
+	defer SetDialect(Postgres)
+
+	var qry string
+	BeforeStatement(KindInsert, "user", func(e *StatementEvent) error {
+		qry = e.Query
+		return errors.New("aborted")
+	})
+	Upsert(db, "user", member{ID: 1, Username: "john"})
+	expected := "INSERT INTO user (`id`,`username`) VALUES(?,?) ON DUPLICATE KEY UPDATE `username` = VALUES(`username`)"
+	if qry != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, qry)
+	}
+}
+
+func TestSQLiteDialect(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	Testing = false
+	SetDialect(SQLite)
+	defer SetDialect(Postgres)
+
+	for _, name := range []string{"a", "b", "c"} {
+		if _, err := Insert(db, "user", User{Username: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := Upsert(db, "user", User{ID: 2, Username: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	qb := QueryBuilder{}
+	qb.Select(User{}, IgnoreComputed()).Where("id > $?", 1).OrderBy("id").Offset(1).ForUpdate()
+	expected := `SELECT "id","username","password","total" FROM user WHERE id > ? ORDER BY id LIMIT -1 OFFSET 1`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	users := []member{}
+	qb = QueryBuilder{}
+	qb.Select(member{}).From("user").Where("id > $?", 1).OrderBy("id")
+	rows, err := qb.Query(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := scanAll(rows, &users); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[0].Username != "x" || users[1].Username != "c" {
+		t.Errorf("Unexpected users %+v", users)
+	}
+}
+
+func TestPostgresUpsertInserted(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	defer resetStatementHooks()
+	Testing = false
+
+	var qry string
+	BeforeStatement(KindInsert, "user", func(e *StatementEvent) error {
+		qry = e.Query
+		return errors.New("aborted")
+	})
+	UpsertInserted(db, "user", member{ID: 1, Username: "john"})
+	expected := `INSERT INTO user ("id","username") VALUES($1,$2) ON CONFLICT ("id") DO UPDATE SET "username" = EXCLUDED."username" RETURNING (xmax = 0)`
+	if qry != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, qry)
+	}
+}
+
+func TestPlaceholders(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.UseDialect(MySQL).Placeholders(func(n int) string {
+		return fmt.Sprintf(":p%d", n)
+	})
+	qb.Select("id").From("users").Where("id = $? OR parent = $?", 1, 2).OrderByAsc("id", NullsFirst())
+	expected := "SELECT id FROM users WHERE id = :p1 OR parent = :p2 ORDER BY id IS NULL DESC, id ASC"
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+}
+
+type noReturning struct {
+	Dialect
+}
+
+func (noReturning) SupportsReturning() bool {
+	return false
+}
+
+func TestUpsertInsertedWithoutReturning(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	defer resetStatementHooks()
+	Testing = false
+	SetDialect(noReturning{Postgres})
+	defer SetDialect(Postgres)
+
+	var qry string
+	BeforeStatement(KindInsert, "user", func(e *StatementEvent) error {
+		qry = e.Query
+		return errors.New("aborted")
+	})
+	UpsertInserted(db, "user", member{ID: 1, Username: "john"})
+	expected := `INSERT INTO user ("id","username") VALUES($1,$2) ON CONFLICT ("id") DO NOTHING`
+	if qry != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, qry)
+	}
+}
+
+func TestEmbeddedDialectFeatures(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.UseDialect(noReturning{MySQL}).Select("id").From("users").WhereEqNullSafe("parent", nil).Offset(5)
+	expected := "SELECT id FROM users WHERE parent <=> ? LIMIT 5, 18446744073709551615"
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+}
+
+func TestWhereGroupDialect(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.UseDialect(MySQL).Select("id").From("users").WhereGroup(func(g *QueryBuilder) {
+		g.WhereEqNullSafe("a", 1).OrWhereGroup(func(g *QueryBuilder) {
+			g.WhereEqNullSafe("b", nil)
+		})
+	})
+	expected := "SELECT id FROM users WHERE (a <=> ? OR (b <=> ?))"
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+}
+
+func TestUnsupportedHelpers(t *testing.T) {
+	Testing = false
+	for _, d := range []Dialect{MSSQL, Oracle, Oracle11} {
+		helpers := map[string]func(qb *QueryBuilder){
+			"WhereJSONContains": func(qb *QueryBuilder) { qb.WhereJSONContains("meta", map[string]string{"plan": "pro"}) },
+			"WhereJSONPath":     func(qb *QueryBuilder) { qb.WhereJSONPath("meta", "plan", "=", "pro") },
+			"SelectJSONField":   func(qb *QueryBuilder) { qb.SelectJSONField("meta", "plan", "plan") },
+			"GroupByTimeBucket": func(qb *QueryBuilder) { qb.GroupByTimeBucket("created_at", time.Hour) },
+		}
+		for name, helper := range helpers {
+			qb := &QueryBuilder{}
+			qb.UseDialect(d).Select("id").From("events")
+			helper(qb)
+			if sql, _, err := qb.BuildWithArgs(); err == nil {
+				t.Errorf("%s %s: expected an error, got %s", d.Name(), name, sql)
+			}
+		}
+
+		SetDialect(d)
+		if _, _, err := buildUpsert(d, "user", User{ID: 1, Username: "a"}, false); err == nil {
+			t.Errorf("%s: expected the upsert to fail", d.Name())
+		}
+		ib := InsertInto("counters").Set("id", 1).OnConflict("id").DoNothing()
+		if sql, _, err := ib.Build(); err == nil {
+			t.Errorf("%s: expected the insert to fail, got %s", d.Name(), sql)
+		}
+		if _, _, err := InsertInto("counters").Set("id", 1).Build(); err != nil {
+			t.Errorf("%s: unexpected error %v", d.Name(), err)
+		}
+		SetDialect(Postgres)
+	}
+
+	// MSSQL has no FOR UPDATE, rows can't be claimed without it
+	qb := &QueryBuilder{}
+	qb.UseDialect(MSSQL).Select("id").From("jobs").ForUpdate(SkipLocked())
+	if sql, _, err := qb.BuildWithArgs(); err == nil {
+		t.Errorf("Expected the lock to fail, got %s", sql)
+	}
+	users := []User{}
+	if err := ClaimRows(nil, qb, 10, &users, nil); err == nil {
+		t.Error("Expected ClaimRows to fail")
+	}
+}
@@ -0,0 +1,345 @@
+package goql
+
+import (
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// queryJSON is the JSON form of a QueryBuilder, see MarshalJSON.
+type queryJSON struct {
+	Version       int                    `json:"version"`
+	Dialect       string                 `json:"dialect,omitempty"`
+	SelectAlias   string                 `json:"selectAlias,omitempty"`
+	IgnoreDynamic bool                   `json:"ignoreDynamic,omitempty"`
+	Columns       []string               `json:"columns,omitempty"`
+	Distinct      string                 `json:"distinct,omitempty"`
+	Where         []conditionJSON        `json:"where,omitempty"`
+	Scopes        []string               `json:"scopes,omitempty"`
+	Unscoped      bool                   `json:"unscoped,omitempty"`
+	Having        []string               `json:"having,omitempty"`
+	OrderBy       []orderJSON            `json:"orderBy,omitempty"`
+	DefaultOrder  string                 `json:"defaultOrder,omitempty"`
+	Limit         string                 `json:"limit,omitempty"`
+	Offset        string                 `json:"offset,omitempty"`
+	Params        []paramJSON            `json:"params,omitempty"`
+	Named         map[string]valueJSON   `json:"named,omitempty"`
+	GroupBy       []string               `json:"groupBy,omitempty"`
+	InnerJoin     []string               `json:"innerJoin,omitempty"`
+	LeftJoin      []string               `json:"leftJoin,omitempty"`
+	Joins         []conditionJSON        `json:"joins,omitempty"`
+	From          string                 `json:"from,omitempty"`
+	FromAlias     string                 `json:"fromAlias,omitempty"`
+	AsOf          string                 `json:"asOf,omitempty"`
+	SystemTime    string                 `json:"systemTime,omitempty"`
+	Lock          *lockJSON              `json:"lock,omitempty"`
+	Compound      []string               `json:"compound,omitempty"`
+	CTEs          []string               `json:"ctes,omitempty"`
+	Recursive     bool                   `json:"recursive,omitempty"`
+	ReadOnly      bool                   `json:"readOnly,omitempty"`
+	SubTables     []string               `json:"subTables,omitempty"`
+	Windows       []string               `json:"windows,omitempty"`
+	Values        map[string][]valueJSON `json:"values,omitempty"`
+	ResultColumns []resultColumnJSON     `json:"resultColumns,omitempty"`
+	Tag           string                 `json:"tag,omitempty"`
+	Comments      []string               `json:"comments,omitempty"`
+	IndexHints    []indexHintJSON        `json:"indexHints,omitempty"`
+}
+
+type conditionJSON struct {
+	Kind string `json:"kind"`
+	Expr string `json:"expr"`
+}
+
+type orderJSON struct {
+	Expr  string `json:"expr,omitempty"`
+	Col   string `json:"col,omitempty"`
+	Desc  bool   `json:"desc,omitempty"`
+	Nulls string `json:"nulls,omitempty"`
+}
+
+type paramJSON struct {
+	Name  string    `json:"name,omitempty"`
+	Value valueJSON `json:"value"`
+}
+
+type lockJSON struct {
+	Mode string   `json:"mode"`
+	Of   []string `json:"of,omitempty"`
+	Wait string   `json:"wait,omitempty"`
+}
+
+type resultColumnJSON struct {
+	Name     string `json:"name"`
+	Field    string `json:"field,omitempty"`
+	Computed bool   `json:"computed,omitempty"`
+}
+
+type indexHintJSON struct {
+	Kind    string   `json:"kind"`
+	Indexes []string `json:"indexes"`
+}
+
+// valueJSON is a value bound to the query along with its type, so it's
+// decoded as it was instead of as the types of encoding/json.
+type valueJSON struct {
+	Type  string `json:"type"`
+	Value string `json:"value,omitempty"`
+}
+
+// queryJSONVersion is the version of the JSON form of the queries.
+const queryJSONVersion = 1
+
+// encodedDialects holds the dialects a query can be decoded with.
+var encodedDialects = map[string]Dialect{
+	Postgres.Name(): Postgres, MySQL.Name(): MySQL, SQLite.Name(): SQLite,
+	MSSQL.Name(): MSSQL, Oracle.Name(): Oracle, Oracle11.Name(): Oracle11,
+}
+
+// MarshalJSON encodes the query so it can be stored, for example as a
+// saved search, or sent to another service, and decoded later with
+// UnmarshalJSON to be built and run again:
+//
+//	data, err := json.Marshal(queryBuilder)
+//	...
+//	saved := &goql.QueryBuilder{}
+//	err = json.Unmarshal(data, saved)
+//	rows, err := saved.QueryContext(ctx, db)
+//
+// The bound values keep their type when they are strings, booleans,
+// numbers, times or []byte, the ones implementing driver.Valuer are
+// stored as the value they return. Queries with the errors of a
+// misused builder, custom dialects or placeholders can't be encoded.
+func (qb *QueryBuilder) MarshalJSON() ([]byte, error) {
+	if qb.err != nil {
+		return nil, qb.err
+	}
+	if qb.placeholders != nil {
+		return nil, errors.New("goql: queries with custom placeholders can't be encoded")
+	}
+	q := queryJSON{
+		Version: queryJSONVersion, SelectAlias: qb.SelectAlias, IgnoreDynamic: qb.IgnoreDynamic,
+		Columns: qb.columns, Distinct: qb.distinct, Scopes: qb.scopes, Unscoped: qb.unscoped,
+		Having: qb.having, DefaultOrder: qb.defOrder, Limit: qb.limit, Offset: qb.offset,
+		GroupBy: qb.groupBy, InnerJoin: qb.innerJoin, LeftJoin: qb.leftJoin, From: qb.from,
+		FromAlias: qb.fromAlias, AsOf: qb.asOf, SystemTime: qb.systemTime, Compound: qb.compound,
+		CTEs: qb.ctes, Recursive: qb.recursive, ReadOnly: qb.readOnly, SubTables: qb.subTables,
+		Windows: qb.windows, Tag: qb.tag, Comments: qb.comments,
+	}
+	if qb.dialect != nil {
+		if d, ok := encodedDialects[qb.dialect.Name()]; !ok || d != qb.dialect {
+			return nil, fmt.Errorf("goql: queries of the %s dialect can't be encoded", qb.dialect.Name())
+		}
+		q.Dialect = qb.dialect.Name()
+	}
+	for _, cond := range qb.where {
+		q.Where = append(q.Where, conditionJSON{Kind: cond.conj, Expr: cond.expr})
+	}
+	for _, j := range qb.joins {
+		q.Joins = append(q.Joins, conditionJSON{Kind: j.kind, Expr: j.expr})
+	}
+	for _, term := range qb.orderBy {
+		q.OrderBy = append(q.OrderBy, orderJSON{Expr: term.expr, Col: term.col, Desc: term.desc, Nulls: term.nulls})
+	}
+	if len(qb.lock.mode) > 0 {
+		q.Lock = &lockJSON{Mode: qb.lock.mode, Of: qb.lock.of, Wait: qb.lock.wait}
+	}
+	for _, col := range qb.resultColumns {
+		q.ResultColumns = append(q.ResultColumns, resultColumnJSON{Name: col.Name, Field: col.Field, Computed: col.Computed})
+	}
+	for _, hint := range qb.indexHints {
+		q.IndexHints = append(q.IndexHints, indexHintJSON{Kind: hint.kind, Indexes: hint.indexes})
+	}
+	for _, param := range qb.params {
+		val, err := encodeValue(param.val)
+		if err != nil {
+			return nil, err
+		}
+		q.Params = append(q.Params, paramJSON{Name: param.name, Value: val})
+	}
+	if len(qb.named) > 0 {
+		q.Named = map[string]valueJSON{}
+		for name, v := range qb.named {
+			val, err := encodeValue(v)
+			if err != nil {
+				return nil, err
+			}
+			q.Named[name] = val
+		}
+	}
+	if len(qb.values) > 0 {
+		q.Values = map[string][]valueJSON{}
+		for clause, vals := range qb.values {
+			for _, v := range vals {
+				val, err := encodeValue(v)
+				if err != nil {
+					return nil, err
+				}
+				q.Values[clause] = append(q.Values[clause], val)
+			}
+		}
+	}
+	return json.Marshal(q)
+}
+
+// UnmarshalJSON decodes a query encoded with MarshalJSON, replacing the
+// state of qb.
+func (qb *QueryBuilder) UnmarshalJSON(data []byte) error {
+	q := queryJSON{}
+	if err := json.Unmarshal(data, &q); err != nil {
+		return err
+	}
+	if q.Version != queryJSONVersion {
+		return fmt.Errorf("goql: unsupported query version %d", q.Version)
+	}
+	decoded := QueryBuilder{
+		SelectAlias: q.SelectAlias, IgnoreDynamic: q.IgnoreDynamic,
+		columns: q.Columns, distinct: q.Distinct, scopes: q.Scopes, unscoped: q.Unscoped,
+		having: q.Having, defOrder: q.DefaultOrder, limit: q.Limit, offset: q.Offset,
+		groupBy: q.GroupBy, innerJoin: q.InnerJoin, leftJoin: q.LeftJoin, from: q.From,
+		fromAlias: q.FromAlias, asOf: q.AsOf, systemTime: q.SystemTime, compound: q.Compound,
+		ctes: q.CTEs, recursive: q.Recursive, readOnly: q.ReadOnly, subTables: q.SubTables,
+		windows: q.Windows, tag: q.Tag, comments: q.Comments,
+	}
+	if len(q.Dialect) > 0 {
+		d, ok := encodedDialects[q.Dialect]
+		if !ok {
+			return fmt.Errorf("goql: unknown dialect %q", q.Dialect)
+		}
+		decoded.dialect = d
+	}
+	for _, cond := range q.Where {
+		decoded.where = append(decoded.where, condition{conj: cond.Kind, expr: cond.Expr})
+	}
+	for _, j := range q.Joins {
+		decoded.joins = append(decoded.joins, join{kind: j.Kind, expr: j.Expr})
+	}
+	for _, term := range q.OrderBy {
+		decoded.orderBy = append(decoded.orderBy, orderTerm{expr: term.Expr, col: term.Col, desc: term.Desc, nulls: term.Nulls})
+	}
+	if q.Lock != nil {
+		decoded.lock = rowLock{mode: q.Lock.Mode, of: q.Lock.Of, wait: q.Lock.Wait}
+	}
+	for _, col := range q.ResultColumns {
+		decoded.resultColumns = append(decoded.resultColumns, ResultColumn{Name: col.Name, Field: col.Field, Computed: col.Computed})
+	}
+	for _, hint := range q.IndexHints {
+		decoded.indexHints = append(decoded.indexHints, indexHint{kind: hint.Kind, indexes: hint.Indexes})
+	}
+	for _, param := range q.Params {
+		val, err := decodeValue(param.Value)
+		if err != nil {
+			return err
+		}
+		decoded.params = append(decoded.params, selectParam{name: param.Name, val: val})
+	}
+	if len(q.Named) > 0 {
+		decoded.named = map[string]interface{}{}
+		for name, v := range q.Named {
+			val, err := decodeValue(v)
+			if err != nil {
+				return err
+			}
+			decoded.named[name] = val
+		}
+	}
+	for clause, vals := range q.Values {
+		for _, v := range vals {
+			val, err := decodeValue(v)
+			if err != nil {
+				return err
+			}
+			decoded.addValues(clause, []interface{}{val})
+		}
+	}
+	*qb = decoded
+	return nil
+}
+
+// encodeValue encodes a value bound to the query.
+func encodeValue(val interface{}) (valueJSON, error) {
+	if valuer, ok := val.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return valueJSON{}, err
+		}
+		val = v
+	}
+	v := reflect.ValueOf(val)
+	for v.IsValid() && v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return valueJSON{Type: "null"}, nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return valueJSON{Type: "null"}, nil
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return valueJSON{Type: "time", Value: t.Format(time.RFC3339Nano)}, nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return valueJSON{Type: "string", Value: v.String()}, nil
+	case reflect.Bool:
+		return valueJSON{Type: "bool", Value: strconv.FormatBool(v.Bool())}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return valueJSON{Type: v.Kind().String(), Value: strconv.FormatInt(v.Int(), 10)}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return valueJSON{Type: v.Kind().String(), Value: strconv.FormatUint(v.Uint(), 10)}, nil
+	case reflect.Float32, reflect.Float64:
+		return valueJSON{Type: v.Kind().String(), Value: strconv.FormatFloat(v.Float(), 'g', -1, 64)}, nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return valueJSON{Type: "bytes", Value: base64.StdEncoding.EncodeToString(v.Bytes())}, nil
+		}
+	}
+	return valueJSON{}, fmt.Errorf("goql: values of type %T can't be encoded", val)
+}
+
+// numberTypes holds the types of the numbers encoded by encodeValue.
+var numberTypes = map[string]reflect.Type{
+	"int": reflect.TypeOf(int(0)), "int8": reflect.TypeOf(int8(0)), "int16": reflect.TypeOf(int16(0)),
+	"int32": reflect.TypeOf(int32(0)), "int64": reflect.TypeOf(int64(0)), "uint": reflect.TypeOf(uint(0)),
+	"uint8": reflect.TypeOf(uint8(0)), "uint16": reflect.TypeOf(uint16(0)), "uint32": reflect.TypeOf(uint32(0)),
+	"uint64": reflect.TypeOf(uint64(0)), "float32": reflect.TypeOf(float32(0)), "float64": reflect.TypeOf(float64(0)),
+}
+
+// decodeValue decodes a value encoded by encodeValue.
+func decodeValue(val valueJSON) (interface{}, error) {
+	switch val.Type {
+	case "null":
+		return nil, nil
+	case "string":
+		return val.Value, nil
+	case "bool":
+		return strconv.ParseBool(val.Value)
+	case "time":
+		return time.Parse(time.RFC3339Nano, val.Value)
+	case "bytes":
+		return base64.StdEncoding.DecodeString(val.Value)
+	}
+	t, ok := numberTypes[val.Type]
+	if !ok {
+		return nil, fmt.Errorf("goql: unknown value type %q", val.Type)
+	}
+	var n interface{}
+	var err error
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64:
+		n, err = strconv.ParseFloat(val.Value, t.Bits())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err = strconv.ParseUint(val.Value, 10, t.Bits())
+	default:
+		n, err = strconv.ParseInt(val.Value, 10, t.Bits())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return reflect.ValueOf(n).Convert(t).Interface(), nil
+}
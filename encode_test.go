@@ -0,0 +1,79 @@
+package goql
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestQueryBuilderJSON(t *testing.T) {
+	Testing = false
+	created := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	qb := &QueryBuilder{}
+	qb.Select(User{}, Except("password")).SelectAs("total * $?", "gross", 1.16).
+		Where("created > $?", created).WhereIn("id", []int64{1, 2}).OrWhere("username = $?", "john").Where("password = $?", []byte("x")).
+		LeftJoin(`orders o ON o.user_id = "user"."id" AND o.paid = $?`, true).
+		GroupBy("id").Having("COUNT(*) > $?", uint8(2)).OrderByDesc("id").Limit(10).Offset(20).
+		ForUpdate(SkipLocked()).Tag("report").Comment("trace_id=abc").UseDialect(MySQL)
+
+	data, err := json.Marshal(qb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &QueryBuilder{}
+	if err := json.Unmarshal(data, decoded); err != nil {
+		t.Fatal(err)
+	}
+	sql, vals, err := qb.BuildWithArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decodedSQL, decodedVals, err := decoded.BuildWithArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decodedSQL != sql {
+		t.Errorf("Expected:\n%s\nGot:\n%s", sql, decodedSQL)
+	}
+	if !reflect.DeepEqual(decodedVals, vals) {
+		t.Errorf("Expected %#v, got %#v", vals, decodedVals)
+	}
+	if !reflect.DeepEqual(decoded.Columns(), qb.Columns()) || decoded.QueryTag() != "report" {
+		t.Errorf("Unexpected metadata %v %s", decoded.Columns(), decoded.QueryTag())
+	}
+
+	if err := json.Unmarshal([]byte(`{"version":2}`), decoded); err == nil {
+		t.Error("Expected an error for an unknown version")
+	}
+	if _, err := json.Marshal((&QueryBuilder{}).Select("id").From("t").Where("a = $?", struct{}{})); err == nil {
+		t.Error("Expected an error for a value that can't be encoded")
+	}
+	if _, err := json.Marshal((&QueryBuilder{}).From("t").WhereIn("id", 1)); err == nil {
+		t.Error("Expected the error of the query")
+	}
+}
+
+func TestQueryBuilderJSONQuery(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`INSERT INTO user (username, password) VALUES ('john', 'x'), ('jane', 'y')`)
+	qb := &QueryBuilder{}
+	qb.SelectExcept(User{}, "total").Where("username = $?", "jane").Where("id > $?", int64(1))
+	data, err := json.Marshal(qb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := &QueryBuilder{}
+	if err := json.Unmarshal(data, saved); err != nil {
+		t.Fatal(err)
+	}
+	user := User{}
+	if err := saved.QueryAndScan(db, &user); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprintf("%d %s", user.ID, user.Username) != "2 jane" {
+		t.Errorf("Unexpected user %+v", user)
+	}
+}
@@ -0,0 +1,56 @@
+package goql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// Explain returns the plan the database chooses for the query, one line
+// per row of the EXPLAIN output with its columns separated by spaces,
+// for example to check in tests that a query uses an index. SQLite
+// plans are taken from EXPLAIN QUERY PLAN.
+func (qb *QueryBuilder) Explain(Db Queryer) (string, error) {
+	return qb.ExplainContext(context.Background(), Db)
+}
+
+// ExplainContext is the same as Explain but the query is canceled when
+// ctx is done.
+func (qb *QueryBuilder) ExplainContext(ctx context.Context, Db Queryer) (string, error) {
+	qry, vals, err := qb.BuildWithArgs()
+	if err != nil {
+		return "", err
+	}
+	explain := "EXPLAIN "
+	if qb.getDialect().Name() == SQLite.Name() {
+		explain = "EXPLAIN QUERY PLAN "
+	}
+	rows, err := Db.QueryContext(qb.tagged(ctx), explain+qry, vals...)
+	if err != nil {
+		return "", canceled(ctx, err)
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	lines := []string{}
+	for rows.Next() {
+		row := make([]sql.NullString, len(cols))
+		pointers := make([]interface{}, len(cols))
+		for i := range row {
+			pointers[i] = &row[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return "", err
+		}
+		fields := []string{}
+		for _, field := range row {
+			if field.Valid {
+				fields = append(fields, field.String)
+			}
+		}
+		lines = append(lines, strings.Join(fields, " "))
+	}
+	return strings.Join(lines, "\n"), canceled(ctx, rows.Err())
+}
@@ -0,0 +1,20 @@
+package goql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	qb := QueryBuilder{}
+	qb.Select("id", "username").From("user").Where("id = ?", 1)
+	plan, err := qb.Explain(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(plan, "SEARCH user USING INTEGER PRIMARY KEY") {
+		t.Errorf("Unexpected plan %s", plan)
+	}
+}
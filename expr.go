@@ -0,0 +1,135 @@
+package goql
+
+import (
+	"strings"
+)
+
+// Expr is a SQL expression with its bound values, which are written as
+// $? in the SQL. It can be passed to Select and OrderBy, and as a value
+// of Where, Having or Assign, where it replaces its placeholder.
+type Expr interface {
+	SQL() (string, []interface{})
+}
+
+// CaseExpr is a CASE WHEN expression built with Case.
+type CaseExpr struct {
+	whens   []string
+	results []interface{}
+	elseVal interface{}
+	hasElse bool
+	alias   string
+}
+
+// Case starts a CASE WHEN expression, for example
+// queryBuilder.Select("id", Case().When("total > 100", "big").Else("small").As("size"))
+// generates SELECT id,CASE WHEN total > 100 THEN $1 ELSE $2 END "size"
+// The results are bound as values unless they are an Expr themselves.
+// It can be used in OrderBy and, as the value of a SET clause, in
+// UpdateTable(...).Set and in the Assign of InsertBuilder.DoUpdate.
+func Case() *CaseExpr {
+	return &CaseExpr{}
+}
+
+// When adds a WHEN cond THEN val branch.
+func (c *CaseExpr) When(cond string, val interface{}) *CaseExpr {
+	c.whens = append(c.whens, cond)
+	c.results = append(c.results, val)
+	return c
+}
+
+// Else sets the value of the expression when no branch matches, which
+// is NULL by default.
+func (c *CaseExpr) Else(val interface{}) *CaseExpr {
+	c.elseVal = val
+	c.hasElse = true
+	return c
+}
+
+// As names the column when the expression is selected.
+func (c *CaseExpr) As(alias string) *CaseExpr {
+	c.alias = alias
+	return c
+}
+
+// Alias returns the name set with As.
+func (c *CaseExpr) Alias() string {
+	return c.alias
+}
+
+// SQL implements Expr.
+func (c *CaseExpr) SQL() (string, []interface{}) {
+	return c.dialectSQL(activeDialect())
+}
+
+func (c *CaseExpr) dialectSQL(d Dialect) (string, []interface{}) {
+	vals := []interface{}{}
+	parts := []string{"CASE"}
+	for i, cond := range c.whens {
+		sql, resultVals := exprValue(d, c.results[i])
+		parts = append(parts, "WHEN "+cond+" THEN "+sql)
+		vals = append(vals, resultVals...)
+	}
+	if c.hasElse {
+		sql, elseVals := exprValue(d, c.elseVal)
+		parts = append(parts, "ELSE "+sql)
+		vals = append(vals, elseVals...)
+	}
+	parts = append(parts, "END")
+	return strings.Join(parts, " "), vals
+}
+
+// exprValue returns the SQL of val, a placeholder unless it's an Expr.
+func exprValue(d Dialect, val interface{}) (string, []interface{}) {
+	if expr, ok := val.(Expr); ok {
+		return exprSQL(d, expr)
+	}
+	return "$?", []interface{}{val}
+}
+
+// dialectExpr is an Expr whose SQL depends on the dialect, such as the
+// quoting of Col, so it follows the one of the statement it's used in.
+type dialectExpr interface {
+	dialectSQL(d Dialect) (string, []interface{})
+}
+
+// exprSQL returns the SQL of expr rendered with the dialect d.
+func exprSQL(d Dialect, expr Expr) (string, []interface{}) {
+	if dexpr, ok := expr.(dialectExpr); ok {
+		return dexpr.dialectSQL(d)
+	}
+	return expr.SQL()
+}
+
+// RawExpr is a hand written SQL expression built with Raw.
+type RawExpr struct {
+	sql   string
+	args  []interface{}
+	alias string
+}
+
+// Raw is an expression written by hand, which is added to the query as
+// it is, without quoting its identifiers, so it makes explicit where the
+// builder is bypassed, for example
+// queryBuilder.Select("id", Raw("price * $?", 1.16).As("gross")).Where("total > $?", Raw("avg_total * 2"))
+// args are bound to the $? placeholders of sql.
+// The sql must never be built from user input, which should be passed
+// in args instead.
+func Raw(sql string, args ...interface{}) *RawExpr {
+	return &RawExpr{sql: sql, args: args}
+}
+
+// As names the column when the expression is selected.
+func (r *RawExpr) As(alias string) *RawExpr {
+	r.alias = alias
+	return r
+}
+
+// Alias returns the name set with As.
+func (r *RawExpr) Alias() string {
+	return r.alias
+}
+
+// SQL implements Expr.
+func (r *RawExpr) SQL() (string, []interface{}) {
+	return r.sql, append([]interface{}{}, r.args...)
+}
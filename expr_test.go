@@ -0,0 +1,82 @@
+package goql
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCaseExpression(t *testing.T) {
+	Testing = false
+	size := Case().When("total > 100", "big").When("total > 10", "medium").Else("small").As("size")
+	priority := Case().When("status = 'urgent'", 0).Else(1)
+	qb := QueryBuilder{}
+	qb.Select("id", size).From("orders").Where("user_id = $?", 7).OrderBy(priority).OrderBy("id")
+	expected := `SELECT id,CASE WHEN total > 100 THEN $1 WHEN total > 10 THEN $2 ELSE $3 END "size" FROM orders WHERE user_id = $4 ORDER BY CASE WHEN status = 'urgent' THEN $5 ELSE $6 END, id`
+	sql, args, err := qb.BuildWithArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if fmt.Sprint(args) != "[big medium small 7 0 1]" {
+		t.Errorf("Unexpected args %v", args)
+	}
+
+	nested, vals := Case().When("a", Case().When("b", 1)).SQL()
+	if nested != "CASE WHEN a THEN CASE WHEN b THEN $? END END" || len(vals) != 1 {
+		t.Errorf("Unexpected nested case %s %v", nested, vals)
+	}
+}
+
+func TestRawExpression(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.Select("id", Raw("price * $?", 1.16).As("gross")).From("orders").
+		Where("user_id = $?", 7).
+		Where("total > $?", Raw("avg_total * $?", 2)).
+		OrderBy(Raw("coalesce(shipped_at, created_at) DESC"))
+	expected := `SELECT id,price * $1 "gross" FROM orders WHERE user_id = $2 AND total > avg_total * $3 ORDER BY coalesce(shipped_at, created_at) DESC`
+	sql, args, err := qb.BuildWithArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if fmt.Sprint(args) != "[1.16 7 2]" {
+		t.Errorf("Unexpected args %v", args)
+	}
+
+	SetDialect(Postgres)
+	sql, args, err = InsertInto("orders").Set("total", 10).Set("created_at", Raw("now()")).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = `INSERT INTO orders ("total","created_at") VALUES($1,now())`
+	if sql != expected || len(args) != 1 {
+		t.Errorf("Expected:\n%s\nGot:\n%s %v", expected, sql, args)
+	}
+}
+
+func TestSelectAs(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.Select("id").SelectAs("COALESCE(nickname, username)", "name").SelectAs("total * $?", "gross", 1.16).
+		From("user").Where("id > $?", 10)
+	expected := `SELECT id,COALESCE(nickname, username) "name",total * $1 "gross" FROM user WHERE id > $2`
+	sql, args, err := qb.BuildWithArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if fmt.Sprint(args) != "[1.16 10]" {
+		t.Errorf("Unexpected args %v", args)
+	}
+	cols := qb.ResultColumns()
+	if len(cols) != 3 || cols[1].Name != "name" || cols[2].Name != "gross" {
+		t.Errorf("Unexpected result columns %v", cols)
+	}
+}
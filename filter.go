@@ -0,0 +1,150 @@
+package goql
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Filter is a condition of a list request, such as the filters of the
+// ListX RPCs of a gRPC service, on the field Field of a model. Op is
+// one of =, !=, <, <=, >, >= and IN, whose Value must be a list. Value
+// can be a string, as the filters are often written in requests, and is
+// converted to the type of the field, numbers read from a
+// google.protobuf.Struct being float64. A nil Value matches NULL with
+// = and !=.
+type Filter struct {
+	Field string      `json:"field"`
+	Op    string      `json:"op"`
+	Value interface{} `json:"value"`
+}
+
+// filterOperators holds the operators accepted in a Filter.
+var filterOperators = map[string]bool{
+	"=": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true, "IN": true,
+}
+
+// WhereFilters adds the conditions of filters on the columns of model,
+// validating that each field is a column of model, the operator is
+// supported and the value can be converted to the type of the field, so
+// filters coming from clients never reach the SQL unchecked, for example
+//
+//	queryBuilder.Select(User{}).WhereFilters(User{}, []goql.Filter{
+//		{Field: "status", Op: "=", Value: "active"},
+//		{Field: "age", Op: ">=", Value: 18.0},
+//	})
+//
+// generates WHERE status = $1 AND age >= $2. A field is matched by its
+// "filter" tag, its "json" tag, as generated for protobuf messages, or
+// its column, in that order. Computed fields and the ones tagged with
+// filter:"-" can't be filtered. Invalid filters are reported by
+// BuildWithArgs.
+func (qb *QueryBuilder) WhereFilters(model interface{}, filters []Filter) (ret *QueryBuilder) {
+	ret = qb
+	t := modelType(model)
+	if t.Kind() != reflect.Struct {
+		qb.addError(fmt.Errorf("WhereFilters: model must be a struct, got %T", model))
+		return
+	}
+	fields := filterFields(t)
+	for _, filter := range filters {
+		field, ok := fields[filter.Field]
+		if !ok {
+			qb.addError(fmt.Errorf("WhereFilters: unknown field %q", filter.Field))
+			continue
+		}
+		op := strings.ToUpper(filter.Op)
+		if !filterOperators[op] {
+			qb.addError(fmt.Errorf("WhereFilters %s: unsupported operator %q", filter.Field, filter.Op))
+			continue
+		}
+		col := columnName(t, field)
+		if filter.Value == nil {
+			switch op {
+			case "=":
+				qb.WhereNull(col)
+			case "!=":
+				qb.WhereNotNull(col)
+			default:
+				qb.addError(fmt.Errorf("WhereFilters %s: null can only be compared with = and !=", filter.Field))
+			}
+			continue
+		}
+		if op != "IN" {
+			val, err := filterValue(field.Type, filter.Value)
+			if err != nil {
+				qb.addError(fmt.Errorf("WhereFilters %s: %s", filter.Field, err))
+				continue
+			}
+			qb.Where(fmt.Sprintf("%s %s $?", col, op), val)
+			continue
+		}
+		list := reflect.ValueOf(filter.Value)
+		if list.Kind() != reflect.Slice && list.Kind() != reflect.Array {
+			qb.addError(fmt.Errorf("WhereFilters %s: IN expects a list, got %T", filter.Field, filter.Value))
+			continue
+		}
+		vals := make([]interface{}, list.Len())
+		var err error
+		for i := range vals {
+			if vals[i], err = filterValue(field.Type, list.Index(i).Interface()); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			qb.addError(fmt.Errorf("WhereFilters %s: %s", filter.Field, err))
+			continue
+		}
+		qb.WhereIn(col, vals)
+	}
+	return
+}
+
+// filterFields returns the fields of the struct t that can be filtered
+// by their filter name.
+func filterFields(t reflect.Type) map[string]reflect.StructField {
+	fields := map[string]reflect.StructField{}
+	for i := 0; i <= t.NumField()-1; i++ {
+		field := t.Field(i)
+		col := columnName(t, field)
+		if len(col) <= 0 || len(field.Tag.Get("sql")) > 0 || len(field.Tag.Get("hashof")) > 0 {
+			continue
+		}
+		name := field.Tag.Get("filter")
+		if name == "-" {
+			continue
+		}
+		if len(name) <= 0 {
+			name = strings.Split(field.Tag.Get("json"), ",")[0]
+		}
+		if len(name) <= 0 || name == "-" {
+			name = col
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// filterValue converts val to the type of a field of kind typ, see
+// convertParam.
+func filterValue(typ reflect.Type, val interface{}) (interface{}, error) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == reflect.TypeOf(time.Time{}) {
+		return convertParam("time", val)
+	}
+	switch typ.Kind() {
+	case reflect.String:
+		return convertParam("string", val)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return convertParam("int", val)
+	case reflect.Float32, reflect.Float64:
+		return convertParam("float", val)
+	case reflect.Bool:
+		return convertParam("bool", val)
+	}
+	return nil, fmt.Errorf("fields of type %s can't be filtered", typ)
+}
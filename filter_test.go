@@ -0,0 +1,81 @@
+package goql
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type filterAccount struct {
+	ID        int64     `db:"id" pk:"true" json:"id"`
+	Status    string    `db:"status" json:"status"`
+	Age       int       `db:"age" json:"age,omitempty"`
+	Score     float64   `db:"score" filter:"rating"`
+	CreatedAt time.Time `db:"created_at" json:"createTime"`
+	Secret    string    `db:"secret" filter:"-"`
+	Total     int64     `db:"total" sql:"COUNT(id)"`
+}
+
+func TestWhereFilters(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.Select("id").From("accounts").WhereFilters(filterAccount{}, []Filter{
+		{Field: "status", Op: "=", Value: "active"},
+		{Field: "age", Op: ">=", Value: 18.0},
+		{Field: "rating", Op: "<", Value: "4.5"},
+		{Field: "createTime", Op: ">", Value: "2020-01-02T00:00:00Z"},
+		{Field: "id", Op: "in", Value: []interface{}{1.0, "2"}},
+		{Field: "age", Op: "!=", Value: nil},
+	})
+	sql, vals, err := qb.BuildWithArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `SELECT id FROM accounts WHERE status = $1 AND age >= $2 AND score < $3 AND created_at > $4 AND id IN ($5,$6) AND age IS NOT NULL`
+	if sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if fmt.Sprint(vals) != "[active 18 4.5 2020-01-02 00:00:00 +0000 UTC 1 2]" {
+		t.Errorf("Unexpected values %v", vals)
+	}
+}
+
+func TestWhereFiltersInvalid(t *testing.T) {
+	tests := []struct {
+		filter Filter
+		err    string
+	}{
+		{Filter{Field: "secret", Op: "=", Value: "x"}, `unknown field "secret"`},
+		{Filter{Field: "total", Op: "=", Value: 1}, `unknown field "total"`},
+		{Filter{Field: "Status", Op: "=", Value: "x"}, `unknown field "Status"`},
+		{Filter{Field: "status", Op: "LIKE", Value: "x"}, `unsupported operator "LIKE"`},
+		{Filter{Field: "status", Op: "= 1 OR 1 =", Value: "x"}, `unsupported operator`},
+		{Filter{Field: "age", Op: "=", Value: 1.5}, "expected an integer"},
+		{Filter{Field: "status", Op: "=", Value: 1}, "expected a string"},
+		{Filter{Field: "id", Op: "IN", Value: "1"}, "IN expects a list"},
+		{Filter{Field: "age", Op: "<", Value: nil}, "null can only be compared"},
+	}
+	for _, test := range tests {
+		qb := QueryBuilder{}
+		qb.Select("id").From("accounts").WhereFilters(&filterAccount{}, []Filter{test.filter})
+		_, _, err := qb.BuildWithArgs()
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%v: expected an error containing %q, got %v", test.filter, test.err, err)
+		}
+	}
+}
+
+func TestWhereFiltersQuery(t *testing.T) {
+	db := dbSetup()
+	db.Exec(`INSERT INTO user (username, password) VALUES ('john', 'x'), ('jane', 'y')`)
+	user := User{}
+	qb := QueryBuilder{}
+	err := qb.SelectExcept(User{}, "total").WhereFilters(User{}, []Filter{{Field: "username", Op: "=", Value: "jane"}}).QueryAndScan(db, &user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Username != "jane" || user.ID != 2 {
+		t.Errorf("Unexpected user %+v", user)
+	}
+}
@@ -0,0 +1,81 @@
+package goql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// FindByPKs loads the rows whose primary key is in ids with a single
+// IN query and stores them in dest, which must be a pointer to a slice
+// of structs, or of pointers to structs, with a field tagged with "pk".
+// The rows are stored in the order of ids, which is what dataloader
+// style batching needs, for example
+// goql.FindByPKs(db, &users, []int64{3, 1, 2})
+// The ids without a row are skipped and repeated ids repeat their row.
+func FindByPKs(db Queryer, dest interface{}, ids interface{}) error {
+	return FindByPKsContext(context.Background(), db, dest, ids)
+}
+
+// FindByPKsContext is the same as FindByPKs but the query is canceled
+// when ctx is done.
+func FindByPKsContext(ctx context.Context, db Queryer, dest interface{}, ids interface{}) error {
+	slice, elemType, err := destSlice(dest)
+	if err != nil {
+		return err
+	}
+	structType := elemType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	pk, ok := pkField(structType)
+	if !ok {
+		return fmt.Errorf("goql: %s has no field tagged with pk", structType.Name())
+	}
+	idList := reflect.ValueOf(ids)
+	if idList.Kind() != reflect.Slice && idList.Kind() != reflect.Array {
+		return fmt.Errorf("goql: ids must be a slice, got %T", ids)
+	}
+
+	qb := &QueryBuilder{}
+	qb.Select(reflect.New(structType).Elem().Interface())
+	qb.WhereIn(qb.quote(columnName(structType, pk)), ids)
+	sql, vals, err := qb.BuildWithArgs()
+	if err != nil {
+		return err
+	}
+	rows, err := db.QueryContext(ctx, sql, vals...)
+	if err != nil {
+		return err
+	}
+	found := reflect.New(slice.Type())
+	if err := scanAll(rows, found.Interface()); err != nil {
+		return err
+	}
+
+	// Keys are compared as text so an int id matches an int64 key
+	byKey := map[string]reflect.Value{}
+	for i := 0; i <= found.Elem().Len()-1; i++ {
+		elem := found.Elem().Index(i)
+		key := reflect.Indirect(elem).FieldByIndex(pk.Index)
+		byKey[fmt.Sprint(key.Interface())] = elem
+	}
+	ordered := reflect.MakeSlice(slice.Type(), 0, idList.Len())
+	for i := 0; i <= idList.Len()-1; i++ {
+		if elem, ok := byKey[fmt.Sprint(idList.Index(i).Interface())]; ok {
+			ordered = reflect.Append(ordered, elem)
+		}
+	}
+	slice.Set(ordered)
+	return nil
+}
+
+// pkField returns the first field of the struct t tagged with "pk".
+func pkField(t reflect.Type) (reflect.StructField, bool) {
+	for i := 0; i <= t.NumField()-1; i++ {
+		if len(t.Field(i).Tag.Get("pk")) > 0 {
+			return t.Field(i), true
+		}
+	}
+	return reflect.StructField{}, false
+}
@@ -0,0 +1,28 @@
+package goql
+
+import (
+	"testing"
+)
+
+func TestFindByPKs(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`CREATE TABLE member(id INTEGER PRIMARY KEY, username TEXT)`)
+	db.Exec(`INSERT INTO member(id, username) VALUES(1, 'a'), (2, 'b'), (3, 'c')`)
+
+	members := []member{}
+	if err := FindByPKs(db, &members, []int{3, 9, 1, 3}); err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 3 || members[0].Username != "c" || members[1].Username != "a" || members[2].Username != "c" {
+		t.Errorf("Unexpected members %+v", members)
+	}
+
+	pointers := []*member{}
+	if err := FindByPKs(db, &pointers, []int64{2}); err != nil {
+		t.Fatal(err)
+	}
+	if len(pointers) != 1 || pointers[0].Username != "b" {
+		t.Errorf("Unexpected members %+v", pointers)
+	}
+}
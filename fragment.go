@@ -0,0 +1,88 @@
+package goql
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// fragment is a named SQL condition registered with Fragment.
+type fragment struct {
+	sql    string
+	params []string
+}
+
+var (
+	fragmentsMu sync.RWMutex
+	fragments   = map[string]fragment{}
+)
+
+// Fragment registers a named SQL condition whose $? placeholders take
+// the declared params in order, so business predicates are written once
+// and included by name with WhereFragment across the codebase, for
+// example
+// goql.Fragment("active_users", "status = $? AND deleted_at IS NULL", "status")
+// It panics when the number of params doesn't match the placeholders or
+// name is already registered. It's meant to be called on start up.
+func Fragment(name string, sql string, params ...string) {
+	if strings.Count(sql, "$?") != len(params) {
+		panic(fmt.Sprintf("goql: fragment %s has %d placeholders and %d params", name, strings.Count(sql, "$?"), len(params)))
+	}
+	fragmentsMu.Lock()
+	defer fragmentsMu.Unlock()
+	if _, ok := fragments[name]; ok {
+		panic("goql: fragment " + name + " is already registered")
+	}
+	fragments[name] = fragment{sql: sql, params: params}
+}
+
+// WhereFragment adds the condition registered with Fragment as name,
+// parenthesized, the values are given in the order of its params or in
+// a single map[string]interface{} keyed by them, for example
+// queryBuilder.WhereFragment("active_users", "active") or
+// queryBuilder.WhereFragment("active_users", map[string]interface{}{"status": "active"})
+// generates WHERE (status = $1 AND deleted_at IS NULL). It panics when
+// there is no such fragment.
+func (qb *QueryBuilder) WhereFragment(name string, vals ...interface{}) (ret *QueryBuilder) {
+	return qb.addFragment("AND", name, vals)
+}
+
+// OrWhereFragment is the same as WhereFragment but the condition is
+// joined with OR.
+func (qb *QueryBuilder) OrWhereFragment(name string, vals ...interface{}) (ret *QueryBuilder) {
+	return qb.addFragment("OR", name, vals)
+}
+
+func (qb *QueryBuilder) addFragment(conj string, name string, vals []interface{}) (ret *QueryBuilder) {
+	ret = qb
+	fragmentsMu.RLock()
+	f, ok := fragments[name]
+	fragmentsMu.RUnlock()
+	if !ok {
+		panic("goql: unknown fragment " + name)
+	}
+	if named, ok := namedValues(vals); ok && len(f.params) > 0 {
+		vals = make([]interface{}, len(f.params))
+		for i, param := range f.params {
+			if vals[i], ok = named[param]; !ok {
+				qb.addError(fmt.Errorf("goql: missing param %s of fragment %s", param, name))
+				return
+			}
+		}
+	}
+	if len(vals) != len(f.params) {
+		qb.addError(fmt.Errorf("goql: fragment %s takes %d values, got %d", name, len(f.params), len(vals)))
+		return
+	}
+	return qb.addWhere(conj, "("+f.sql+")", vals)
+}
+
+// namedValues returns the map of vals when it's a single
+// map[string]interface{}.
+func namedValues(vals []interface{}) (map[string]interface{}, bool) {
+	if len(vals) != 1 {
+		return nil, false
+	}
+	named, ok := vals[0].(map[string]interface{})
+	return named, ok
+}
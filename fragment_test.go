@@ -0,0 +1,58 @@
+package goql
+
+import (
+	"fmt"
+	"testing"
+)
+
+func init() {
+	Fragment("active_users", "status = $? AND deleted_at IS NULL", "status")
+	Fragment("created_between", "created_at BETWEEN $? AND $?", "from", "to")
+}
+
+func TestWhereFragment(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.Select("id").From("users").Where("id > $?", 10).
+		WhereFragment("active_users", "active").
+		OrWhereFragment("created_between", map[string]interface{}{"to": "2020-02-01", "from": "2020-01-01"})
+	expected := `SELECT id FROM users WHERE id > $1 AND (status = $2 AND deleted_at IS NULL) OR (created_at BETWEEN $3 AND $4)`
+	sql, args, err := qb.BuildWithArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if fmt.Sprint(args) != "[10 active 2020-01-01 2020-02-01]" {
+		t.Errorf("Unexpected args %v", args)
+	}
+
+	qb = QueryBuilder{}
+	qb.Select("id").From("users").WhereFragment("created_between", "2020-01-01")
+	if _, _, err := qb.BuildWithArgs(); err == nil {
+		t.Error("Expected an error for the missing value")
+	}
+	qb = QueryBuilder{}
+	qb.Select("id").From("users").WhereFragment("created_between", map[string]interface{}{"from": 1})
+	if _, _, err := qb.BuildWithArgs(); err == nil {
+		t.Error("Expected an error for the missing param")
+	}
+}
+
+func TestFragmentMisuse(t *testing.T) {
+	for name, fn := range map[string]func(){
+		"unknown":      func() { (&QueryBuilder{}).WhereFragment("nope") },
+		"duplicated":   func() { Fragment("active_users", "status = 'a'") },
+		"placeholders": func() { Fragment("mismatch", "a = $? AND b = $?", "a") },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected a panic", name)
+				}
+			}()
+			fn()
+		}()
+	}
+}
@@ -1,18 +1,25 @@
 package goql
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"reflect"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"database/sql"
 )
 
-// Testing is a simple testing flag.
+// Testing is a simple testing flag, while it's set the queries are
+// built with the SQLite dialect regardless of the one set with
+// SetDialect.
+//
+// Deprecated: use SetDialect(SQLite) or UseDialect(SQLite) instead.
 var Testing = false
 
 const dbTypeDb = "db"
@@ -22,238 +29,1639 @@ const dbTypeTx = "tx"
 type QueryBuilder struct {
 	Sql string
 	// The select by struct will add qb alias to the sql id added
+	//
+	// Deprecated: pass WithAlias to Select instead.
 	SelectAlias string
 	// If set to true, the select will ignore fields with sql tag
+	//
+	// Deprecated: pass IgnoreComputed to Select instead.
 	IgnoreDynamic bool
 
-	columns   []string
-	where     []string
-	having    []string
-	orderBy   []string
-	limit     string
-	groupBy   []string
-	innerJoin []string
-	leftJoin  []string
-	from      string
-	values    map[string][]interface{}
+	columns    []string
+	distinct   string
+	where      []condition
+	scopes     []string
+	unscoped   bool
+	having     []string
+	orderBy    []orderTerm
+	defOrder   string
+	limit      string
+	offset     string
+	params     []selectParam
+	named      map[string]interface{}
+	groupBy    []string
+	innerJoin  []string
+	leftJoin   []string
+	joins      []join
+	from       string
+	fromAlias  string
+	asOf       string
+	systemTime string
+	lock       rowLock
+	compound   []string
+	ctes       []string
+	recursive  bool
+	readOnly   bool
+	subTables  []string
+	windows    []string
+	values     map[string][]interface{}
+	// dialect is the dialect set with UseDialect, the default one is
+	// used when it's nil.
+	dialect Dialect
+	// placeholders is the format set with Placeholders.
+	placeholders PlaceholderFormat
+	// resultColumns describes each one of the selected columns.
+	resultColumns []ResultColumn
+	// tag is the workload set with Tag.
+	tag string
+	// comments holds the texts added with Comment.
+	comments []string
+	// indexHints holds the hints set with UseIndex, ForceIndex and
+	// IgnoreIndex.
+	indexHints []indexHint
+	// err holds the first error found while building the query, it's
+	// returned by BuildWithArgs.
+	err error
+
+	// cache holds the memoized output of Build and BuildCount, it's reset
+	// by every method that changes the query.
+	cache      map[string]string
+	cacheState cacheState
+}
+
+// cacheState holds the public settings the memoized SQL was built with.
+type cacheState struct {
+	selectAlias string
+	dialect     Dialect
+	tagComments bool
+}
+
+// valueClauses holds the order in which the values of each clause
+// appear in the generated SQL.
+var valueClauses = []string{"with", "select", "from", "systemtime", "innerjoin", "leftjoin", "join", "asof", "scope", "where", "having", "compound", "order"}
+
+// comparisonOperators holds the operators accepted by the helpers that
+// build conditions from an operator.
+var comparisonOperators = map[string]bool{
+	"=": true, "!=": true, "<>": true, "<": true, "<=": true, ">": true, ">=": true,
 }
 
 // Select selects the columns of the query
-// col parameter must be either a string or a struct
-// with at least one parameter with the "db" tag set
-func (qb *QueryBuilder) Select(col interface{}) (ret *QueryBuilder) {
+// each col parameter must be either a string, a struct with at least
+// one parameter with the "db" tag set or a struct wrapped with Aliased.
+// The first struct sets the table of the query. When more than one
+// struct is passed in the same call, for example to select from a join,
+// each struct's columns are prefixed with its alias, which defaults to
+// its table name, and named <alias>_<column> in the result:
+// queryBuilder.Select(Aliased(User{}, "u"), Aliased(Order{}, "o"))
+// generates SELECT "u"."id" "u_id",...,"o"."id" "o_id",... FROM user u
+// ScanRow can then be used to scan each row back into both structs.
+// The columns of a struct are selected in the order its fields are
+// declared, see Columns.
+// Struct fields tagged with join:"<table>,<foreign key>" are joined to
+// their parent table and selected too, so a single QueryAndScan fills
+// both the parent and the nested struct:
+// User User `join:"users,user_id"`
+// generates INNER JOIN users "user" ON "user"."id" = "order"."user_id"
+// SelectOption values change how the structs of the same call are
+// selected, for example:
+// queryBuilder.Select(User{}, WithAlias("u"), IgnoreComputed(), Except("password"))
+func (qb *QueryBuilder) Select(cols ...interface{}) (ret *QueryBuilder) {
+	ret = qb
+	qb.invalidate()
+	options := selectOptions{}
+	structs := 0
+	for _, col := range cols {
+		if opt, ok := col.(SelectOption); ok {
+			opt(&options)
+		} else if _, ok := col.(Expr); ok {
+			continue
+		} else if _, ok := col.(AliasedStruct); ok || reflect.TypeOf(col).Kind() == reflect.Struct {
+			structs++
+		}
+	}
+	first := true
+	for _, col := range cols {
+		if _, ok := col.(SelectOption); ok {
+			continue
+		}
+		if expr, ok := col.(Expr); ok {
+			qb.selectExpr(expr)
+			continue
+		}
+		if aliased, ok := col.(AliasedStruct); ok {
+			opts := options
+			opts.alias = aliased.Alias
+			opts.setFrom = first
+			opts.qualified = structs > 1
+			qb.selectStruct(reflect.TypeOf(aliased.Obj), opts)
+			first = false
+			continue
+		}
+		switch reflect.TypeOf(col).Kind() {
+		case reflect.String:
+			// Passed in as a string
+			if qb.columns == nil {
+				qb.columns = []string{}
+			}
+			qb.columns = append(qb.columns, col.(string))
+			qb.resultColumns = append(qb.resultColumns, ResultColumn{Name: outputName(col.(string))})
+		case reflect.Struct:
+			// Passed in a a structure
+			t := reflect.TypeOf(col)
+			opts := options
+			opts.setFrom = first
+			if structs > 1 {
+				opts.alias = qb.guessTableNameFromStruct(t.Name())
+				opts.qualified = true
+			}
+			qb.selectStruct(t, opts)
+			first = false
+		default:
+			// All other types are unsupported
+			panic("Unsupported interface passed")
+		}
+	}
+	return
+}
+
+// selectExpr adds expr to the selected columns, named after its alias
+// when it has one.
+func (qb *QueryBuilder) selectExpr(expr Expr) {
+	sql, vals := exprSQL(qb.getDialect(), expr)
+	if aliased, ok := expr.(interface {
+		Alias() string
+	}); ok && len(aliased.Alias()) > 0 {
+		sql += " " + qb.quote(aliased.Alias())
+	}
+	qb.columns = append(qb.columns, sql)
+	qb.resultColumns = append(qb.resultColumns, ResultColumn{Name: outputName(sql)})
+	for _, val := range vals {
+		qb.params = append(qb.params, selectParam{val: val})
+	}
+	qb.bindNamedParams()
+}
+
+// SelectAs adds the expression expr to the selected columns named as
+// alias, its values are bound to the $? placeholders of expr, for example
+// queryBuilder.SelectAs("COALESCE(nickname, username)", "name").SelectAs("total * $?", "gross", 1.16)
+// generates SELECT COALESCE(nickname, username) "name",total * $1 "gross"
+func (qb *QueryBuilder) SelectAs(expr string, alias string, args ...interface{}) (ret *QueryBuilder) {
+	ret = qb
+	qb.invalidate()
+	qb.selectExpr(Raw(expr, args...).As(alias))
+	return
+}
+
+// Distinct removes the duplicated rows from the results with
+// SELECT DISTINCT.
+func (qb *QueryBuilder) Distinct() (ret *QueryBuilder) {
+	ret = qb
+	qb.invalidate()
+	qb.distinct = "DISTINCT "
+	return
+}
+
+// DistinctOn keeps only the first row of each set of rows with the same
+// values in cols with the Postgres SELECT DISTINCT ON (...), which is
+// usually combined with an ORDER BY that starts with cols.
+func (qb *QueryBuilder) DistinctOn(cols ...string) (ret *QueryBuilder) {
 	ret = qb
-	switch reflect.TypeOf(col).Kind() {
-	case reflect.String:
-		// Passed in as a string
-		if qb.columns == nil {
-			qb.columns = []string{}
-		}
-		qb.columns = append(qb.columns, col.(string))
-	case reflect.Struct:
-		// Passed in a a structure
-		t := reflect.TypeOf(col)
-		qb.From(qb.guessTableNameFromStruct(t.Name()))
-		cols := []string{}
-		// Loops all fields
-		for i := 0; i <= t.NumField()-1; i++ {
-			if name := t.Field(i).Tag.Get("db"); name != "" {
-				tSql := t.Field(i).Tag.Get("sql")
-				if len(tSql) > 0 && !qb.IgnoreDynamic {
-					name = fmt.Sprintf(`(%s) "%s"`, tSql, name)
+	qb.invalidate()
+	qb.distinct = "DISTINCT ON (" + strings.Join(cols, ", ") + ") "
+	return
+}
+
+// SelectExcept selects the db fields of the structure obj except the
+// ones whose column is in columns, it's a shortcut for
+// queryBuilder.Select(obj, Except(columns...))
+func (qb *QueryBuilder) SelectExcept(obj interface{}, columns ...string) (ret *QueryBuilder) {
+	if reflect.TypeOf(obj).Kind() != reflect.Struct {
+		panic("Unsupported interface passed")
+	}
+	return qb.Select(obj, Except(columns...))
+}
+
+// SelectOption changes how Select selects the fields of a struct.
+type SelectOption func(opts *selectOptions)
+
+// WithAlias prefixes the selected columns with alias and uses it as the
+// alias of the table, it takes precedence over SelectAlias.
+func WithAlias(alias string) SelectOption {
+	return func(opts *selectOptions) {
+		opts.alias = alias
+	}
+}
+
+// IgnoreComputed selects the fields with the "sql" tag as regular
+// columns instead of their expression, as IgnoreDynamic does.
+func IgnoreComputed() SelectOption {
+	return func(opts *selectOptions) {
+		opts.ignoreComputed = true
+	}
+}
+
+// Only selects just the fields mapped to the given columns.
+func Only(columns ...string) SelectOption {
+	only := map[string]bool{}
+	for _, col := range columns {
+		only[col] = true
+	}
+	return func(opts *selectOptions) {
+		opts.filter(func(t reflect.Type, field reflect.StructField) bool {
+			return only[columnName(t, field)]
+		})
+	}
+}
+
+// Except selects all the fields but the ones mapped to the given
+// columns. The excluded fields are left untouched by QueryAndScan.
+func Except(columns ...string) SelectOption {
+	except := map[string]bool{}
+	for _, col := range columns {
+		except[col] = true
+	}
+	return func(opts *selectOptions) {
+		opts.filter(func(t reflect.Type, field reflect.StructField) bool {
+			return !except[columnName(t, field)]
+		})
+	}
+}
+
+// ColumnSelector can be implemented by models to choose the SQL of the
+// columns selected by Select instead of the one derived from the tags,
+// for example to always read a column through an expression:
+// func (User) SelectColumns() []string { return []string{"id", "lower(email) email"} }
+// The columns are scanned by name when they don't match the db fields.
+type ColumnSelector interface {
+	SelectColumns() []string
+}
+
+// DefaultOrderer can be implemented by models to set the ORDER BY used
+// when the model is selected and the query doesn't call OrderBy.
+type DefaultOrderer interface {
+	DefaultOrder() string
+}
+
+// AliasedStruct is a struct selected with an alias, see Aliased.
+type AliasedStruct struct {
+	Obj   interface{}
+	Alias string
+}
+
+// Aliased wraps obj so that Select prefixes its columns with alias
+// instead of the query's SelectAlias.
+func Aliased(obj interface{}, alias string) AliasedStruct {
+	return AliasedStruct{Obj: obj, Alias: alias}
+}
+
+// ScanRow scans the current row of rows into each one of dests, which
+// must be pointers to the structs selected with Select in the same order.
+func ScanRow(rows *sql.Rows, dests ...interface{}) error {
+	pointers := []interface{}{}
+	for _, dest := range dests {
+		pointers = append(pointers, GetFieldPointers(dest)...)
+	}
+	if err := rows.Scan(pointers...); err != nil {
+		return err
+	}
+	for _, dest := range dests {
+		if err := runScanHooks(dest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// selectOptions tells selectStruct how to select the fields of a struct.
+type selectOptions struct {
+	// alias prefixes the columns, defaults to the SelectAlias
+	alias string
+	// setFrom sets the table of the query to the one of the struct
+	setFrom bool
+	// qualified names the result columns as <alias>_<column>
+	qualified bool
+	// include filters the fields to select when set
+	include func(t reflect.Type, field reflect.StructField) bool
+	// includeJoined filters the fields of the structs selected through
+	// join-tagged fields when set, path is the one of their parent
+	includeJoined func(path string, field reflect.StructField) bool
+	// ignoreComputed selects the fields with the "sql" tag as columns
+	ignoreComputed bool
+	// path prefixes the field names of the result columns
+	path string
+}
+
+// filter restricts the selected fields to the ones accepted by include
+// on top of the current filter.
+func (opts *selectOptions) filter(include func(t reflect.Type, field reflect.StructField) bool) {
+	prev := opts.include
+	opts.include = func(t reflect.Type, field reflect.StructField) bool {
+		return (prev == nil || prev(t, field)) && include(t, field)
+	}
+}
+
+// selectStruct adds the db fields of the structure t to the selected
+// columns.
+func (qb *QueryBuilder) selectStruct(t reflect.Type, opts selectOptions) {
+	checkFields(t)
+	model := reflect.New(t).Interface()
+	if opts.setFrom {
+		table := qb.guessTableNameFromStruct(t.Name())
+		qb.From(qualifyTable(t, table))
+		qb.fromAlias = opts.alias
+		qb.applyScopes(t, table)
+		if orderer, ok := model.(DefaultOrderer); ok {
+			qb.defOrder = orderer.DefaultOrder()
+		}
+	}
+	alias := opts.alias
+	if len(alias) <= 0 {
+		alias = qb.SelectAlias
+	}
+	joins := []reflect.StructField{}
+	for i := 0; i <= t.NumField()-1; i++ {
+		if len(t.Field(i).Tag.Get("join")) > 0 && (opts.include == nil || opts.include(t, t.Field(i))) {
+			joins = append(joins, t.Field(i))
+		}
+	}
+	// Columns must be qualified once other tables are joined
+	if len(joins) > 0 && len(alias) <= 0 {
+		alias = qb.guessTableNameFromStruct(t.Name())
+	}
+	cols := []string{}
+	results := []ResultColumn{}
+	// Loops all fields
+	for i := 0; i <= t.NumField()-1; i++ {
+		if opts.include != nil && !opts.include(t, t.Field(i)) {
+			continue
+		}
+		if name := columnName(t, t.Field(i)); name != "" {
+			col := name
+			output := ""
+			result := ResultColumn{Name: col, Field: opts.path + t.Field(i).Name, Type: t.Field(i).Type}
+			if opts.qualified {
+				output = " " + qb.quote(alias+"_"+col)
+				result.Name = alias + "_" + col
+			}
+			tSql := t.Field(i).Tag.Get("sql")
+			if of := t.Field(i).Tag.Get("hashof"); len(of) > 0 {
+				prefix := alias
+				if len(prefix) > 0 {
+					prefix = qb.quote(prefix) + "."
+				}
+				if len(output) <= 0 {
+					output = " " + qb.quote(col)
+				}
+				name = documentHash(qb.getDialect(), prefix+qb.quote(of)) + output
+			} else if len(tSql) > 0 && !qb.IgnoreDynamic && !opts.ignoreComputed {
+				result.Computed = true
+				if len(output) <= 0 {
+					output = " " + qb.quote(col)
+				}
+				name = fmt.Sprintf(`(%s)%s`, qb.addNamedParams(tSql), output)
+			} else {
+				prefix := t.Field(i).Tag.Get("prefix")
+				if len(prefix) <= 0 {
+					prefix = alias
+				}
+				if len(prefix) > 0 {
+					name = qb.quote(prefix) + "." + qb.quote(col)
 				} else {
-					prefix := t.Field(i).Tag.Get("prefix")
-					if len(prefix) <= 0 {
-						prefix = qb.SelectAlias
-					}
+					name = qb.quote(col)
+				}
+				// Read from the legacy column while the new one is being populated
+				if legacy, _ := parseLegacyTag(t.Field(i).Tag.Get("legacy")); len(legacy) > 0 {
 					if len(prefix) > 0 {
-						name = fmt.Sprintf(`"%s"."%s"`, prefix, name)
+						legacy = qb.quote(prefix) + "." + qb.quote(legacy)
 					} else {
-						name = fmt.Sprintf(`"%s"`, name)
+						legacy = qb.quote(legacy)
 					}
+					if len(output) <= 0 {
+						output = " " + qb.quote(col)
+					}
+					name = fmt.Sprintf(`COALESCE(%s, %s)`, name, legacy)
 				}
-				cols = append(cols, name)
+				name += output
 			}
+			cols = append(cols, name)
+			results = append(results, result)
 		}
-		// Validate if we have at leat 1 field or panic
-		if len(cols) <= 0 {
-			panic("The structure has no db fields to select")
+	}
+	if selector, ok := model.(ColumnSelector); ok {
+		cols = selector.SelectColumns()
+		results = selectorColumns(cols, results)
+	}
+	// Validate if we have at leat 1 field or panic
+	if len(cols) <= 0 {
+		panic("The structure has no db fields to select")
+	}
+	// All good
+	for _, v := range cols {
+		qb.columns = append(qb.columns, v)
+	}
+	qb.resultColumns = append(qb.resultColumns, results...)
+	// Nested structs are selected after the fields of their parent
+	for _, field := range joins {
+		qb.selectJoin(alias, field, opts)
+	}
+}
+
+// selectJoin joins the table of a nested struct field tagged with
+// join:"<table>,<foreign key>" and selects its fields, the field name
+// is used as the alias of the joined table.
+func (qb *QueryBuilder) selectJoin(parent string, field reflect.StructField, parentOpts selectOptions) {
+	opts := strings.Split(field.Tag.Get("join"), ",")
+	if len(opts) != 2 || field.Type.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("Invalid join field %s", field.Name))
+	}
+	alias := qb.guessTableNameFromStruct(field.Name)
+	pk := "id"
+	if pkf, ok := pkField(field.Type); ok {
+		pk = columnName(field.Type, pkf)
+	}
+	qb.innerJoin = append(qb.innerJoin, fmt.Sprintf(`%s %s ON %s.%s = %s.%s`,
+		qualifyTable(field.Type, strings.TrimSpace(opts[0])), qb.quote(alias), qb.quote(alias), qb.quote(pk), qb.quote(parent), qb.quote(strings.TrimSpace(opts[1]))))
+	joinOpts := selectOptions{alias: alias, qualified: true, ignoreComputed: parentOpts.ignoreComputed, path: parentOpts.path + field.Name + "."}
+	if include := parentOpts.includeJoined; include != nil {
+		path := joinOpts.path
+		joinOpts.include = func(_ reflect.Type, field reflect.StructField) bool {
+			return include(path, field)
 		}
-		// All good
-		for _, v := range cols {
-			qb.columns = append(qb.columns, v)
+		joinOpts.includeJoined = include
+	}
+	qb.selectStruct(field.Type, joinOpts)
+}
+
+func (qb *QueryBuilder) guessTableNameFromStruct(name string) string {
+	return strings.ToLower(name)
+}
+
+// From tells the compiler where to load the results from (table name)
+// from can also be another *QueryBuilder to select from a subquery,
+// which most databases require to have an alias set with SelectAlias.
+func (qb *QueryBuilder) From(from interface{}) (ret *QueryBuilder) {
+	ret = qb
+	qb.invalidate()
+	switch from := from.(type) {
+	case string:
+		qb.from = from
+		qb.setValues("from", nil)
+	case *QueryBuilder:
+		sql, vals := from.subquery()
+		qb.subTables = append(qb.subTables, from.Tables()...)
+		qb.from = sql
+		qb.setValues("from", vals)
+		if from.err != nil {
+			qb.addError(from.err)
 		}
 	default:
-		// All other types are unsupported
-		panic("Unsupported interface passed")
+		qb.addError(fmt.Errorf("From: unsupported type %T", from))
+	}
+	return
+}
+
+// InnerJoin is used if we want to user SQL joins
+// Can be used multiple times each one for each join
+// Values can be bound to the join condition in the same way as in Where:
+// queryBuilder.InnerJoin("orders o ON o.user_id = u.id AND o.status = $?", "paid")
+func (qb *QueryBuilder) InnerJoin(from string, vals ...interface{}) (ret *QueryBuilder) {
+	ret = qb
+	qb.invalidate()
+	from, vals = qb.expandSubqueries(from, vals)
+	qb.innerJoin = append(qb.innerJoin, from)
+	qb.addValues("innerjoin", vals)
+	return
+}
+
+// LeftJoin for building left joins, see InnerJoin
+func (qb *QueryBuilder) LeftJoin(from string, vals ...interface{}) (ret *QueryBuilder) {
+	ret = qb
+	qb.invalidate()
+	from, vals = qb.expandSubqueries(from, vals)
+	qb.leftJoin = append(qb.leftJoin, from)
+	qb.addValues("leftjoin", vals)
+	return
+}
+
+// RightJoin for building right joins, see InnerJoin
+func (qb *QueryBuilder) RightJoin(from string, vals ...interface{}) *QueryBuilder {
+	return qb.addJoin("RIGHT JOIN", from, vals)
+}
+
+// FullOuterJoin for building full outer joins. MySQL doesn't support
+// them so with the MySQL dialect they are emulated with the union of a
+// left and a right join.
+func (qb *QueryBuilder) FullOuterJoin(from string, vals ...interface{}) *QueryBuilder {
+	return qb.addJoin("FULL OUTER JOIN", from, vals)
+}
+
+// CrossJoin for building cross joins, from is just the table as cross
+// joins have no condition.
+func (qb *QueryBuilder) CrossJoin(from string, vals ...interface{}) *QueryBuilder {
+	return qb.addJoin("CROSS JOIN", from, vals)
+}
+
+// join is a join other than the inner and left ones, which are kept
+// apart for backwards compatibility.
+type join struct {
+	kind string
+	expr string
+}
+
+func (qb *QueryBuilder) addJoin(kind string, from string, vals []interface{}) (ret *QueryBuilder) {
+	ret = qb
+	qb.invalidate()
+	from, vals = qb.expandSubqueries(from, vals)
+	qb.joins = append(qb.joins, join{kind, from})
+	qb.addValues("join", vals)
+	return
+}
+
+// emulateFullJoins rewrites a query with full outer joins for the
+// databases that lack them as the union of the query with left joins
+// and the query with right joins, ORDER BY and LIMIT apply to the union.
+// When the left side of the join has a column that's NULL only for the
+// rows of the right side without a match, the right join is restricted
+// to those rows and combined with UNION ALL, so duplicated rows are
+// kept as a full outer join does and no rows have to be deduplicated.
+func (qb *QueryBuilder) emulateFullJoins() *QueryBuilder {
+	withKind := func(kind string) *QueryBuilder {
+		part := qb.unordered()
+		part.joins = make([]join, len(qb.joins))
+		for i, j := range qb.joins {
+			if j.kind == "FULL OUTER JOIN" {
+				j.kind = kind
+			}
+			part.joins[i] = j
+		}
+		return part
+	}
+	left, right := withKind("LEFT JOIN"), withKind("RIGHT JOIN")
+	var emulated *QueryBuilder
+	if col := qb.fullJoinLeftColumn(); len(col) > 0 {
+		emulated = left.UnionAll(right.Where(col + " IS NULL"))
+	} else {
+		emulated = left.Union(right)
+	}
+	emulated.orderBy, emulated.defOrder, emulated.limit, emulated.offset, emulated.lock = qb.orderBy, qb.defOrder, qb.limit, qb.offset, qb.lock
+	emulated.addValues("order", qb.values["order"])
+	return emulated
+}
+
+// joinOn splits a join expression into the joined table and the join
+// condition.
+var joinOn = regexp.MustCompile(`(?is)^(.*?)\s+ON\s+(.*)$`)
+
+// joinEquality matches the equalities between two columns of a join
+// condition.
+var joinEquality = regexp.MustCompile("([\\w.\"`]+)\\s*=\\s*([\\w.\"`]+)")
+
+// joinOr matches the OR of a join condition.
+var joinOr = regexp.MustCompile(`(?i)\sOR\s`)
+
+// fullJoinLeftColumn returns a column of the left side of the only full
+// outer join of the query that's compared for equality in the join
+// condition, which is NULL only for the rows of the right side without
+// a match. It's empty when there is no such column or more than one
+// full outer join.
+func (qb *QueryBuilder) fullJoinLeftColumn() string {
+	var full *join
+	for i := range qb.joins {
+		if qb.joins[i].kind == "FULL OUTER JOIN" {
+			if full != nil {
+				return ""
+			}
+			full = &qb.joins[i]
+		}
+	}
+	parts := joinOn.FindStringSubmatch(full.expr)
+	if parts == nil || joinOr.MatchString(parts[2]) {
+		return ""
+	}
+	table := strings.Fields(parts[1])
+	alias := strings.Trim(table[len(table)-1], "\"`")
+	if dot := strings.LastIndex(alias, "."); dot >= 0 {
+		alias = alias[dot+1:]
+	}
+	for _, eq := range joinEquality.FindAllStringSubmatch(parts[2], -1) {
+		for _, col := range eq[1:] {
+			names := strings.Split(strings.Replace(strings.Replace(col, `"`, "", -1), "`", "", -1), ".")
+			if len(names) >= 2 && names[len(names)-2] != alias {
+				return col
+			}
+		}
+	}
+	return ""
+}
+
+// swapRightJoin rewrites the query whose first join is a right join as
+// a left join of both tables swapped, for the databases that lack right
+// joins. ok is false when the query can't be rewritten: when other joins
+// come before the right join, when a value is bound to the FROM clause
+// or the joined table, or with ForSystemTime and index hints.
+func (qb *QueryBuilder) swapRightJoin() (swapped *QueryBuilder, ok bool) {
+	if len(qb.joins) <= 0 || qb.joins[0].kind != "RIGHT JOIN" || len(qb.innerJoin) > 0 || len(qb.leftJoin) > 0 ||
+		len(qb.systemTime) > 0 || len(qb.indexHints) > 0 || len(qb.values["from"]) > 0 {
+		return nil, false
+	}
+	parts := joinOn.FindStringSubmatch(qb.joins[0].expr)
+	if parts == nil || strings.Contains(parts[1], getPlaceholder()) {
+		return nil, false
+	}
+	from := qb.from
+	if len(qb.fromAlias) > 0 {
+		if qb.fromAlias != qb.from {
+			from += " " + qb.fromAlias
+		}
+	} else if len(qb.SelectAlias) > 0 {
+		from += " " + qb.SelectAlias
+	}
+	swapped = qb.clone()
+	swapped.from, swapped.fromAlias, swapped.SelectAlias = parts[1], "", ""
+	swapped.joins[0] = join{"LEFT JOIN", from + " ON " + parts[2]}
+	return swapped, true
+}
+
+// needsFullJoinEmulation tells whether the query has full outer joins
+// that its dialect must emulate.
+func (qb *QueryBuilder) needsFullJoinEmulation() bool {
+	if !featuresOf(qb.getDialect()).emulateFullJoins {
+		return false
+	}
+	for _, j := range qb.joins {
+		if j.kind == "FULL OUTER JOIN" {
+			return true
+		}
+	}
+	return false
+}
+
+// unordered returns a copy of the query without its ORDER BY, LIMIT,
+// OFFSET and locking clauses.
+func (qb *QueryBuilder) unordered() *QueryBuilder {
+	result := qb.clone()
+	result.orderBy, result.defOrder, result.limit, result.offset, result.lock = nil, "", "", "", rowLock{}
+	delete(result.values, "order")
+	return result
+}
+
+// clone returns a deep copy of the query so the copy can be changed,
+// for example to add the conditions of a page, without changing qb.
+func (qb *QueryBuilder) clone() *QueryBuilder {
+	result := *qb
+	result.invalidate()
+	result.columns = append([]string(nil), qb.columns...)
+	result.where = append([]condition(nil), qb.where...)
+	result.scopes = append([]string(nil), qb.scopes...)
+	result.having = append([]string(nil), qb.having...)
+	result.orderBy = append([]orderTerm(nil), qb.orderBy...)
+	result.params = append([]selectParam(nil), qb.params...)
+	result.groupBy = append([]string(nil), qb.groupBy...)
+	result.innerJoin = append([]string(nil), qb.innerJoin...)
+	result.leftJoin = append([]string(nil), qb.leftJoin...)
+	result.joins = append([]join(nil), qb.joins...)
+	result.lock.of = append([]string(nil), qb.lock.of...)
+	result.compound = append([]string(nil), qb.compound...)
+	result.ctes = append([]string(nil), qb.ctes...)
+	result.subTables = append([]string(nil), qb.subTables...)
+	result.windows = append([]string(nil), qb.windows...)
+	result.resultColumns = append([]ResultColumn(nil), qb.resultColumns...)
+	result.comments = append([]string(nil), qb.comments...)
+	result.indexHints = make([]indexHint, len(qb.indexHints))
+	for i, hint := range qb.indexHints {
+		result.indexHints[i] = indexHint{kind: hint.kind, indexes: append([]string(nil), hint.indexes...)}
+	}
+	if qb.named != nil {
+		result.named = make(map[string]interface{}, len(qb.named))
+		for name, val := range qb.named {
+			result.named[name] = val
+		}
+	}
+	result.values = make(map[string][]interface{}, len(qb.values))
+	for clause, vals := range qb.values {
+		result.values[clause] = append([]interface{}(nil), vals...)
+	}
+	return &result
+}
+
+// Where for filtering using WHERE sql statement
+// Can be used multiple times for multiple filters
+// IMPORTANT: wilcards MUST be passed as $? in the where string, for example:
+// queryBuilder.Where("id = $?", myId)
+// A value can be another *QueryBuilder to use it as a subquery, its
+// values are merged into the query in the right order:
+// queryBuilder.Where("id IN $?", subQueryBuilder)
+// Values can also be named when they are passed in a single map, which
+// is less error prone for long conditions:
+// queryBuilder.Where("id = :id AND status = :status", map[string]interface{}{"id": 1, "status": "a"})
+func (qb *QueryBuilder) Where(where string, vals ...interface{}) (ret *QueryBuilder) {
+	return qb.addWhere("AND", where, vals)
+}
+
+// OrWhere is the same as Where but the condition is joined to the
+// previous ones with OR instead of AND. As AND takes precedence over OR,
+// WhereGroup should be used to combine both of them, for example
+//
+//	queryBuilder.WhereGroup(func(g *QueryBuilder) {
+//		g.Where("a = $?", 1).OrWhere("b = $?", 2)
+//	}).Where("c = $?", 3)
+//
+// generates WHERE (a = $1 OR b = $2) AND c = $3
+func (qb *QueryBuilder) OrWhere(where string, vals ...interface{}) (ret *QueryBuilder) {
+	return qb.addWhere("OR", where, vals)
+}
+
+// WhereGroup adds the conditions set by fn on the given builder as a
+// single parenthesized condition joined with AND.
+func (qb *QueryBuilder) WhereGroup(fn func(qb *QueryBuilder)) (ret *QueryBuilder) {
+	return qb.addWhereGroup("AND", fn)
+}
+
+// OrWhereGroup is the same as WhereGroup but the group is joined with OR.
+func (qb *QueryBuilder) OrWhereGroup(fn func(qb *QueryBuilder)) (ret *QueryBuilder) {
+	return qb.addWhereGroup("OR", fn)
+}
+
+// WhereEqualFold adds a case insensitive "column = val" condition that
+// works the same in every database, for columns that are not declared
+// case insensitive with the ci tag:
+// queryBuilder.WhereEqualFold("email", email) generates WHERE lower(email) = lower($1)
+func (qb *QueryBuilder) WhereEqualFold(column string, val interface{}) *QueryBuilder {
+	return qb.Where(fmt.Sprintf("lower(%s) = lower($?)", column), val)
+}
+
+// WhereIn adds a "column IN (...)" condition with one placeholder for
+// each element of values, which must be a slice, for example
+// queryBuilder.WhereIn("id", []int64{1, 2, 3}) generates
+// WHERE id IN ($1,$2,$3). An empty slice matches no rows.
+func (qb *QueryBuilder) WhereIn(column string, values interface{}) (ret *QueryBuilder) {
+	ret = qb
+	v := reflect.ValueOf(values)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		qb.addError(fmt.Errorf("WhereIn %s: values must be a slice, got %T", column, values))
+		return
+	}
+	if v.Len() <= 0 {
+		return qb.Where("1 = 0")
+	}
+	placeholders := make([]string, v.Len())
+	vals := make([]interface{}, v.Len())
+	for i := range vals {
+		placeholders[i] = "$?"
+		vals[i] = v.Index(i).Interface()
+	}
+	return qb.Where(fmt.Sprintf("%s IN (%s)", column, strings.Join(placeholders, ",")), vals...)
+}
+
+// WhereNull adds a "column IS NULL" condition.
+func (qb *QueryBuilder) WhereNull(column string) *QueryBuilder {
+	return qb.Where(column + " IS NULL")
+}
+
+// WhereNotNull adds a "column IS NOT NULL" condition.
+func (qb *QueryBuilder) WhereNotNull(column string) *QueryBuilder {
+	return qb.Where(column + " IS NOT NULL")
+}
+
+// WhereEqNullSafe adds a "column = val" condition that holds when both
+// are NULL too, as needed to compare nullable columns when deduplicating
+// or syncing rows, rendered as IS NOT DISTINCT FROM in Postgres, <=> in
+// MySQL and IS in SQLite, for example
+// queryBuilder.WhereEqNullSafe("t.email", goql.Col("s.email"))
+// generates WHERE t.email IS NOT DISTINCT FROM "s"."email" in Postgres.
+// val is bound as a value unless it's an Expr.
+func (qb *QueryBuilder) WhereEqNullSafe(column string, val interface{}) *QueryBuilder {
+	return qb.Where(column+" "+featuresOf(qb.getDialect()).nullSafeEq+" $?", val)
+}
+
+// WhereBetween adds a "column BETWEEN lo AND hi" condition, both ends
+// included.
+func (qb *QueryBuilder) WhereBetween(column string, lo interface{}, hi interface{}) *QueryBuilder {
+	return qb.Where(column+" BETWEEN $? AND $?", lo, hi)
+}
+
+// WhereLike adds a "column LIKE pattern" condition. The % and _
+// wildcards of pattern match any text and any character, the ones that
+// come from user input must be escaped with EscapeLike, for example
+// queryBuilder.WhereLike("name", goql.EscapeLike(prefix)+"%")
+func (qb *QueryBuilder) WhereLike(column string, pattern string) *QueryBuilder {
+	return qb.Where(column+" LIKE $? "+likeEscape(qb.getDialect()), pattern)
+}
+
+// WhereILike is the same as WhereLike but the match is case insensitive,
+// with ILIKE in Postgres and lower() in the other databases.
+func (qb *QueryBuilder) WhereILike(column string, pattern string) *QueryBuilder {
+	d := qb.getDialect()
+	if featuresOf(d).ilike {
+		return qb.Where(column+" ILIKE $? "+likeEscape(d), pattern)
+	}
+	return qb.Where("lower("+column+") LIKE lower($?) "+likeEscape(d), pattern)
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// EscapeLike escapes the % and _ wildcards of s, so it's matched
+// literally by WhereLike and WhereILike.
+func EscapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// likeEscape returns the ESCAPE clause that makes \ the escape character
+// of the LIKE patterns in the dialect d, SQLite has none by default.
+func likeEscape(d Dialect) string {
+	if d.Name() == MySQL.Name() {
+		// Backslashes are escaped in MySQL strings too
+		return `ESCAPE '\\'`
+	}
+	return `ESCAPE '\'`
+}
+
+// condition is a WHERE condition and the operator that joins it
+// with the previous one.
+type condition struct {
+	conj string
+	expr string
+}
+
+func (qb *QueryBuilder) addWhere(conj string, where string, vals []interface{}) (ret *QueryBuilder) {
+	ret = qb
+	qb.invalidate()
+	where, vals, err := compileNamed(where, vals)
+	if err != nil {
+		qb.addError(err)
+	}
+	where, vals = qb.expandSubqueries(where, vals)
+	if qb.where == nil {
+		qb.where = []condition{}
+	}
+	qb.where = append(qb.where, condition{conj, where})
+	qb.addValues("where", vals)
+	return
+}
+
+func (qb *QueryBuilder) addWhereGroup(conj string, fn func(qb *QueryBuilder)) (ret *QueryBuilder) {
+	ret = qb
+	group := &QueryBuilder{dialect: qb.dialect}
+	fn(group)
+	if group.err != nil {
+		qb.addError(group.err)
+	}
+	if len(group.where) <= 0 {
+		return
+	}
+	return qb.addWhere(conj, "("+joinConditions(group.where)+")", group.values["where"])
+}
+
+// subquery returns the parenthesized SQL of qb, with its placeholders
+// left unnumbered so it can be embedded in another query, and its values.
+func (qb *QueryBuilder) subquery() (string, []interface{}) {
+	return "(" + qb.buildSQL() + ")", qb.GetValues()
+}
+
+// expandSubqueries replaces each placeholder of expr whose value is a
+// *QueryBuilder with the subquery and its values, so
+// Where("id IN $?", sub) generates WHERE id IN (SELECT ...). The
+// placeholders whose value is an Expr, such as Raw, are replaced with
+// the expression the same way.
+func (qb *QueryBuilder) expandSubqueries(expr string, vals []interface{}) (string, []interface{}) {
+	result := ""
+	expanded := []interface{}{}
+	for _, val := range vals {
+		pos := strings.Index(expr, "$?")
+		if inline, ok := val.(Expr); ok && pos >= 0 {
+			sql, exprVals := exprSQL(qb.getDialect(), inline)
+			result += expr[:pos] + sql
+			expr = expr[pos+2:]
+			expanded = append(expanded, exprVals...)
+			continue
+		}
+		sub, ok := val.(*QueryBuilder)
+		if pos < 0 || !ok {
+			if pos >= 0 {
+				result += expr[:pos+2]
+				expr = expr[pos+2:]
+			}
+			expanded = append(expanded, val)
+			continue
+		}
+		sql, subVals := sub.subquery()
+		qb.subTables = append(qb.subTables, sub.Tables()...)
+		if sub.err != nil {
+			qb.addError(sub.err)
+		}
+		result += expr[:pos] + sql
+		expr = expr[pos+2:]
+		expanded = append(expanded, subVals...)
+	}
+	return result + expr, expanded
+}
+
+// joinConditions joins conds with their operators.
+func joinConditions(conds []condition) string {
+	parts := []string{}
+	for i, cond := range conds {
+		if i > 0 {
+			parts = append(parts, cond.conj)
+		}
+		parts = append(parts, cond.expr)
+	}
+	return strings.Join(parts, " ")
+}
+
+// AsOf reads the data as it was at the given time using CockroachDB's
+// AS OF SYSTEM TIME clause. ts can be a time.Time or any expression
+// accepted by the database such as "-10s".
+func (qb *QueryBuilder) AsOf(ts interface{}) (ret *QueryBuilder) {
+	ret = qb
+	qb.invalidate()
+	qb.systemTime = ""
+	qb.setValues("systemtime", nil)
+	qb.asOf = "AS OF SYSTEM TIME $?"
+	qb.setValues("asof", []interface{}{ts})
+	return
+}
+
+// ForSystemTime reads a system versioned table as it was at the given
+// time using the FOR SYSTEM_TIME AS OF clause of MSSQL and MariaDB.
+func (qb *QueryBuilder) ForSystemTime(ts interface{}) (ret *QueryBuilder) {
+	ret = qb
+	qb.invalidate()
+	qb.asOf = ""
+	qb.setValues("asof", nil)
+	qb.systemTime = "FOR SYSTEM_TIME AS OF $?"
+	qb.setValues("systemtime", []interface{}{ts})
+	return
+}
+
+// Having performs having SQL statement
+// Values are bound in the same way as in Where and numbered after the
+// ones of the WHERE clause:
+// queryBuilder.GroupBy("user_id").Having("SUM(total) > $?", 100)
+func (qb *QueryBuilder) Having(having string, vals ...interface{}) (ret *QueryBuilder) {
+	ret = qb
+	qb.invalidate()
+	if qb.having == nil {
+		qb.having = []string{}
+	}
+	having, vals, err := compileNamed(having, vals)
+	if err != nil {
+		qb.addError(err)
+	}
+	having, vals = qb.expandSubqueries(having, vals)
+	qb.having = append(qb.having, having)
+	qb.addValues("having", vals)
+	return
+}
+
+// HavingCount filters the groups by their number of rows, for example
+// queryBuilder.GroupBy("user_id").HavingCount(">", 5)
+func (qb *QueryBuilder) HavingCount(op string, val interface{}) *QueryBuilder {
+	return qb.havingAggregate("COUNT(*)", op, val)
+}
+
+// HavingSum filters the groups by the sum of col, for example
+// queryBuilder.GroupBy("user_id").HavingSum("amount", ">=", 100)
+func (qb *QueryBuilder) HavingSum(col string, op string, val interface{}) *QueryBuilder {
+	return qb.havingAggregate("SUM("+col+")", op, val)
+}
+
+// HavingAvg filters the groups by the average of col.
+func (qb *QueryBuilder) HavingAvg(col string, op string, val interface{}) *QueryBuilder {
+	return qb.havingAggregate("AVG("+col+")", op, val)
+}
+
+// HavingMin filters the groups by the minimum value of col.
+func (qb *QueryBuilder) HavingMin(col string, op string, val interface{}) *QueryBuilder {
+	return qb.havingAggregate("MIN("+col+")", op, val)
+}
+
+// HavingMax filters the groups by the maximum value of col.
+func (qb *QueryBuilder) HavingMax(col string, op string, val interface{}) *QueryBuilder {
+	return qb.havingAggregate("MAX("+col+")", op, val)
+}
+
+func (qb *QueryBuilder) havingAggregate(aggregate string, op string, val interface{}) (ret *QueryBuilder) {
+	ret = qb
+	if !comparisonOperators[op] {
+		panic("Unsupported operator " + op)
+	}
+	return qb.Having(fmt.Sprintf("%s %s $?", aggregate, op), val)
+}
+
+// OrderBy for SQL ORDER BY
+// order can also be an Expr such as Case, its values are bound after
+// the ones of the HAVING clause.
+func (qb *QueryBuilder) OrderBy(order interface{}) (ret *QueryBuilder) {
+	ret = qb
+	qb.invalidate()
+	switch order := order.(type) {
+	case string:
+		qb.orderBy = append(qb.orderBy, parseOrderTerms(order)...)
+	case Expr:
+		sql, vals := exprSQL(qb.getDialect(), order)
+		qb.orderBy = append(qb.orderBy, orderTerm{expr: sql})
+		qb.addValues("order", vals)
+	default:
+		qb.addError(fmt.Errorf("OrderBy: unsupported type %T", order))
+	}
+	return
+}
+
+// OrderByAsc orders by col in ascending order, unlike OrderBy col must
+// be a column name, optionally qualified, for example
+// queryBuilder.OrderByAsc("u.name", NullsLast())
+func (qb *QueryBuilder) OrderByAsc(col string, opts ...OrderOption) *QueryBuilder {
+	return qb.orderByColumn(col, false, opts)
+}
+
+// OrderByDesc orders by col in descending order, see OrderByAsc.
+func (qb *QueryBuilder) OrderByDesc(col string, opts ...OrderOption) *QueryBuilder {
+	return qb.orderByColumn(col, true, opts)
+}
+
+// OrderOption changes how OrderByAsc and OrderByDesc sort.
+type OrderOption func(term *orderTerm)
+
+// NullsFirst sorts the NULL values before the rest.
+func NullsFirst() OrderOption {
+	return func(term *orderTerm) {
+		term.nulls = "FIRST"
+	}
+}
+
+// NullsLast sorts the NULL values after the rest.
+func NullsLast() OrderOption {
+	return func(term *orderTerm) {
+		term.nulls = "LAST"
+	}
+}
+
+// orderTerm is an ORDER BY term, either a free form expression or a
+// column with its direction and the placement of the NULL values.
+type orderTerm struct {
+	expr  string
+	col   string
+	desc  bool
+	nulls string
+}
+
+// nullsOrder matches the free form ORDER BY terms that place the NULL
+// values, so they can be emulated like the ones of OrderByAsc.
+var nullsOrder = regexp.MustCompile(`(?is)^\s*(.+?)(?:\s+(ASC|DESC))?\s+NULLS\s+(FIRST|LAST)\s*$`)
+
+// parseOrderTerms splits a free form ORDER BY into its terms.
+func parseOrderTerms(order string) []orderTerm {
+	terms := []orderTerm{}
+	for _, part := range splitTagOptions(order) {
+		match := nullsOrder.FindStringSubmatch(part)
+		if match == nil {
+			terms = append(terms, orderTerm{expr: strings.TrimSpace(part)})
+			continue
+		}
+		terms = append(terms, orderTerm{
+			col:   match[1],
+			desc:  strings.ToUpper(match[2]) == "DESC",
+			nulls: strings.ToUpper(match[3]),
+		})
+	}
+	return terms
+}
+
+func (qb *QueryBuilder) orderByColumn(col string, desc bool, opts []OrderOption) (ret *QueryBuilder) {
+	ret = qb
+	qb.invalidate()
+	if !identifierPattern.MatchString(col) {
+		qb.addError(fmt.Errorf("goql: invalid order by column %q", col))
+		return
+	}
+	term := orderTerm{col: col, desc: desc}
+	for _, opt := range opts {
+		opt(&term)
 	}
+	qb.orderBy = append(qb.orderBy, term)
 	return
 }
 
-func (qb *QueryBuilder) guessTableNameFromStruct(name string) string {
-	return strings.ToLower(name)
+// build renders the term, when emulateNulls is set NULLS FIRST and
+// NULLS LAST are emulated by sorting on "col IS NULL" first for the
+// databases that lack them.
+func (term orderTerm) build(emulateNulls bool) string {
+	if len(term.col) <= 0 {
+		return term.expr
+	}
+	sql := term.col + " ASC"
+	if term.desc {
+		sql = term.col + " DESC"
+	}
+	if len(term.nulls) <= 0 {
+		return sql
+	}
+	if !emulateNulls {
+		return sql + " NULLS " + term.nulls
+	}
+	if term.nulls == "LAST" {
+		return term.col + " IS NULL, " + sql
+	}
+	return term.col + " IS NULL DESC, " + sql
 }
 
-// From tells the compiler where to load the results from (table name)
-func (qb *QueryBuilder) From(from string) (ret *QueryBuilder) {
+// OrderByExpr orders by an expression with bound values, for example
+// to rank by similarity to a search term:
+// queryBuilder.OrderByExpr("similarity(name, $?) DESC", term)
+// The values are returned by GetValues after the values of Where.
+func (qb *QueryBuilder) OrderByExpr(expr string, vals ...interface{}) (ret *QueryBuilder) {
 	ret = qb
-	qb.from = from
+	qb.OrderBy(expr)
+	qb.addValues("order", vals)
 	return
 }
 
-// InnerJoin is used if we want to user SQL joins
-// Can be used multiple times each one for each join
-func (qb *QueryBuilder) InnerJoin(from string) (ret *QueryBuilder) {
+// GroupBy for SQL GROUP BY
+func (qb *QueryBuilder) GroupBy(group string) (ret *QueryBuilder) {
 	ret = qb
-	qb.innerJoin = append(qb.innerJoin, from)
+	qb.invalidate()
+	if qb.groupBy == nil {
+		qb.groupBy = []string{}
+	}
+	qb.groupBy = append(qb.groupBy, group)
 	return
 }
 
-// LeftJoin for building left joins
-func (qb *QueryBuilder) LeftJoin(from string) (ret *QueryBuilder) {
+// GroupByStruct groups by every db field of obj that is not computed
+// with the "sql" tag, so a struct that mixes plain columns with
+// aggregates doesn't need a GROUP BY kept in sync by hand, for example
+// queryBuilder.Select(UserTotal{}).GroupByStruct(UserTotal{})
+func (qb *QueryBuilder) GroupByStruct(obj interface{}) (ret *QueryBuilder) {
 	ret = qb
-	qb.leftJoin = append(qb.leftJoin, from)
+	t := reflect.TypeOf(obj)
+	if t.Kind() != reflect.Struct {
+		panic("Unsupported interface passed")
+	}
+	alias := qb.fromAlias
+	if len(alias) <= 0 {
+		alias = qb.SelectAlias
+	}
+	for i := 0; i <= t.NumField()-1; i++ {
+		name := columnName(t, t.Field(i))
+		if len(name) <= 0 || len(t.Field(i).Tag.Get("sql")) > 0 {
+			continue
+		}
+		if len(alias) > 0 {
+			qb.GroupBy(qb.quote(alias) + "." + qb.quote(name))
+		} else {
+			qb.GroupBy(qb.quote(name))
+		}
+	}
 	return
 }
 
-// Where for filtering using WHERE sql statement
-// Can be used multiple times for multiple filters
-// IMPORTANT: wilcards MUST be passed as $? in the where string, for example:
-// queryBuilder.Where("id = $?", myId)
-func (qb *QueryBuilder) Where(where string, vals ...interface{}) (ret *QueryBuilder) {
+// BindNamed binds val to the $name parameters of the expressions of
+// computed columns, so they can be parameterized at query time:
+// Total float64 `db:"total" sql:"SUM(price * $rate)"`
+// queryBuilder.Select(Order{}).BindNamed("rate", 1.16)
+func (qb *QueryBuilder) BindNamed(name string, val interface{}) (ret *QueryBuilder) {
 	ret = qb
-	if qb.where == nil {
-		qb.where = []string{}
+	qb.invalidate()
+	if qb.named == nil {
+		qb.named = map[string]interface{}{}
 	}
-	qb.where = append(qb.where, where)
-	if vals != nil {
-		if qb.values == nil {
-			qb.values = map[string][]interface{}{}
-		}
-		if _, ok := qb.values["where"]; !ok {
-			qb.values["where"] = vals
-		} else {
-			for _, v := range vals {
-				qb.values["where"] = append(qb.values["where"], v)
-			}
+	qb.named[name] = val
+	qb.bindNamedParams()
+	return
+}
+
+// namedParam matches the $name parameters of computed columns.
+var namedParam = regexp.MustCompile(`\$([a-zA-Z_][a-zA-Z0-9_]*)`)
+
+// addNamedParams replaces the named parameters of expr with
+// placeholders and records their names in order.
+func (qb *QueryBuilder) addNamedParams(expr string) string {
+	for _, match := range namedParam.FindAllStringSubmatch(expr, -1) {
+		qb.params = append(qb.params, selectParam{name: match[1]})
+	}
+	expr = namedParam.ReplaceAllString(expr, "$$?")
+	qb.bindNamedParams()
+	return expr
+}
+
+// selectParam is a value bound to the selected columns, either a named
+// parameter bound with BindNamed or the value of an expression.
+type selectParam struct {
+	name string
+	val  interface{}
+}
 
+// bindNamedParams sets the values of the select clause from the values
+// of the expressions and the ones bound to the named parameters.
+func (qb *QueryBuilder) bindNamedParams() {
+	vals := make([]interface{}, len(qb.params))
+	for i, param := range qb.params {
+		vals[i] = param.val
+		if len(param.name) > 0 {
+			vals[i] = qb.named[param.name]
 		}
 	}
-	return
+	qb.setValues("select", vals)
 }
 
-// Having performs having SQL statement
-func (qb *QueryBuilder) Having(having string) (ret *QueryBuilder) {
+// Limit is used for LIMIT SQL query
+// limit can be either the number of rows, of any integer type, or a
+// string with the SQL of the limit, which is kept for backwards
+// compatibility.
+func (qb *QueryBuilder) Limit(limit interface{}) (ret *QueryBuilder) {
 	ret = qb
-	if qb.having == nil {
-		qb.having = []string{}
+	qb.invalidate()
+	if sql, ok := limit.(string); ok {
+		qb.limit = sql
+		return
+	}
+	v := reflect.ValueOf(limit)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() < 0 {
+			qb.addError(fmt.Errorf("Limit: negative limit %d", v.Int()))
+			return
+		}
+		qb.limit = strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		qb.limit = strconv.FormatUint(v.Uint(), 10)
+	default:
+		qb.addError(fmt.Errorf("Limit: unsupported type %T", limit))
 	}
-	qb.having = append(qb.having, having)
 	return
 }
 
-// OrderBy for SQL ORDER BY
-func (qb *QueryBuilder) OrderBy(order string) (ret *QueryBuilder) {
+// Offset skips the first offset rows of the results, for example
+// queryBuilder.Limit(10).Offset(20) generates LIMIT 10 OFFSET 20
+func (qb *QueryBuilder) Offset(offset int) (ret *QueryBuilder) {
 	ret = qb
-	if qb.orderBy == nil {
-		qb.orderBy = []string{}
+	qb.invalidate()
+	if offset < 0 {
+		qb.addError(fmt.Errorf("Offset: negative offset %d", offset))
+		return
 	}
-	qb.orderBy = append(qb.orderBy, order)
+	qb.offset = strconv.Itoa(offset)
 	return
 }
 
-// GroupBy for SQL GROUP BY
-func (qb *QueryBuilder) GroupBy(group string) (ret *QueryBuilder) {
+// With adds a common table expression named name to the query, it can
+// be referenced as a table in the rest of the query, for example
+// queryBuilder.With("recent", recentQueryBuilder).Select("id").From("recent")
+// generates WITH recent AS (SELECT ...) SELECT id FROM recent
+// The name can list the columns of the expression, as in "tree(id, parent)".
+func (qb *QueryBuilder) With(name string, sub *QueryBuilder) (ret *QueryBuilder) {
 	ret = qb
-	if qb.groupBy == nil {
-		qb.groupBy = []string{}
+	qb.invalidate()
+	if sub.err != nil {
+		qb.addError(sub.err)
 	}
-	qb.groupBy = append(qb.groupBy, group)
+	sql, vals := sub.subquery()
+	qb.subTables = append(qb.subTables, sub.Tables()...)
+	qb.ctes = append(qb.ctes, name+" AS "+sql)
+	qb.addValues("with", vals)
 	return
 }
 
-// Limit is used for LIMIT SQL query
-func (qb *QueryBuilder) Limit(limit string) (ret *QueryBuilder) {
+// WithRecursive is the same as With but the expression can reference
+// itself, which is usually done by combining a base query with a
+// recursive one with UnionAll.
+func (qb *QueryBuilder) WithRecursive(name string, sub *QueryBuilder) (ret *QueryBuilder) {
+	ret = qb.With(name, sub)
+	qb.recursive = true
+	return
+}
+
+// Union combines the results of the query with the ones of other
+// removing duplicates. ORDER BY and LIMIT apply to the combined results
+// so they must be set on the first builder only, for example
+// queryBuilder.Select("id").From("users").Union(otherQueryBuilder).OrderBy("id")
+// generates SELECT id FROM users UNION SELECT ... ORDER BY id
+func (qb *QueryBuilder) Union(other *QueryBuilder) *QueryBuilder {
+	return qb.combine("UNION", other)
+}
+
+// UnionAll is the same as Union but duplicated rows are kept.
+func (qb *QueryBuilder) UnionAll(other *QueryBuilder) *QueryBuilder {
+	return qb.combine("UNION ALL", other)
+}
+
+// Intersect keeps only the rows returned by both the query and other.
+func (qb *QueryBuilder) Intersect(other *QueryBuilder) *QueryBuilder {
+	return qb.combine("INTERSECT", other)
+}
+
+// Except removes the rows returned by other from the results.
+func (qb *QueryBuilder) Except(other *QueryBuilder) *QueryBuilder {
+	return qb.combine("EXCEPT", other)
+}
+
+// combine appends other to the query with the given set operator, its
+// values are bound after the ones of the query's HAVING clause.
+func (qb *QueryBuilder) combine(op string, other *QueryBuilder) (ret *QueryBuilder) {
 	ret = qb
-	qb.limit = limit
+	qb.invalidate()
+	if other.err != nil {
+		qb.addError(other.err)
+	}
+	qb.compound = append(qb.compound, op+" "+other.buildSQL())
+	qb.subTables = append(qb.subTables, other.Tables()...)
+	qb.addValues("compound", other.GetValues())
 	return
 }
 
+// addValues appends vals to the values bound to the given clause.
+func (qb *QueryBuilder) addValues(clause string, vals []interface{}) {
+	if len(vals) <= 0 {
+		return
+	}
+	if qb.values == nil {
+		qb.values = map[string][]interface{}{}
+	}
+	qb.values[clause] = append(qb.values[clause], vals...)
+}
+
+// setValues replaces the values bound to the given clause.
+func (qb *QueryBuilder) setValues(clause string, vals []interface{}) {
+	delete(qb.values, clause)
+	qb.addValues(clause, vals)
+}
+
 // GetValues gets the values passed to Where() in the second
 // parameter. qb is used when building the query, for example:
 // queryBuilder.Select("name").From("user").Where("id_user = $?", id)
 // DB.QueryRow(queryBuilder.Build(), queryBuilder.GetValues()...)
 func (qb *QueryBuilder) GetValues() []interface{} {
+	if qb.needsFullJoinEmulation() {
+		return qb.emulateFullJoins().GetValues()
+	}
 	ret := []interface{}{}
-	if _, ok := qb.values["where"]; ok {
-		for _, v := range qb.values["where"] {
-			ret = append(ret, v)
-		}
-
+	for _, clause := range valueClauses {
+		ret = append(ret, qb.values[clause]...)
 	}
 	return ret
 }
 
-// Build generates the resulting SQL of the query builder
+// Build generates the resulting SQL of the query builder.
+// The result is memoized so building the same query more than once
+// doesn't assemble the SQL again unless the query changed in between.
+// Build can't return the errors of the query, such as an unsupported
+// Limit, so they are logged and the query is built without the clauses
+// that failed, BuildWithArgs returns them instead.
 func (qb *QueryBuilder) Build() string {
-	qb.Sql = qb.buildSQL()
-	qb.replaceWhereValues()
+	qb.Sql = qb.memoize("select", func() string {
+		if qb.err != nil {
+			log.Println(qb.err)
+		}
+		qb.Sql = qb.buildSQL()
+		qb.replaceWhereValues(1)
+		return qb.Sql + qb.tagComment()
+	})
 	return qb.Sql
 }
 
-func (qb *QueryBuilder) replaceWhereValues() {
+// BuildWithArgs generates the resulting SQL along with the values that
+// must be passed with it, so both can't get out of sync:
+// sql, args, err := queryBuilder.BuildWithArgs()
+// DB.Query(sql, args...)
+// Unlike Build it reports the errors found in the query, such as
+// clauses that could not be built or a number of values that doesn't
+// match the number of placeholders.
+func (qb *QueryBuilder) BuildWithArgs() (string, []interface{}, error) {
+	if qb.err != nil {
+		return "", nil, qb.err
+	}
+	if len(strings.TrimSpace(qb.from)) <= 0 {
+		return "", nil, errors.New("goql: the query has no table to select from")
+	}
+	for _, param := range qb.params {
+		if _, ok := qb.named[param.name]; !ok && len(param.name) > 0 {
+			return "", nil, fmt.Errorf("goql: no value bound to $%s", param.name)
+		}
+	}
+	if strings.HasPrefix(qb.distinct, "DISTINCT ON") && !featuresOf(qb.getDialect()).distinctOn {
+		return "", nil, fmt.Errorf("goql: the %s dialect doesn't support DISTINCT ON", qb.getDialect().Name())
+	}
+	if len(qb.lock.mode) > 0 && !lockable(qb.getDialect()) {
+		return "", nil, fmt.Errorf("goql: the %s dialect doesn't support FOR %s", qb.getDialect().Name(), qb.lock.mode)
+	}
 	vals := qb.GetValues()
-	if len(vals) > 0 {
-		for i := range vals {
-			qb.Sql = strings.Replace(qb.Sql, getPlaceholder(), getPlaceholderWithCounter(i+1), 1)
+	raw := qb.memoize("raw", qb.buildSQL)
+	if placeholders := strings.Count(raw, getPlaceholder()); placeholders != len(vals) {
+		return "", nil, fmt.Errorf("goql: the query has %d placeholders but %d values", placeholders, len(vals))
+	}
+	if qb.readOnly {
+		if err := checkReadOnly(raw); err != nil {
+			return "", nil, err
+		}
+	}
+	if ValidateSQL {
+		if err := checkSQL(qb.getDialect(), raw); err != nil {
+			return "", nil, err
 		}
 	}
+	return qb.Build(), vals, nil
+}
+
+// addError records err to be returned by BuildWithArgs, only the first
+// error is kept.
+func (qb *QueryBuilder) addError(err error) {
+	if qb.err == nil {
+		qb.err = err
+	}
+}
+
+// BuildAt is the same as Build() with the difference that placeholders
+// are numbered starting at start instead of 1, which is useful when the
+// query is embedded in a statement that already has placeholders.
+// It returns the SQL and the number of placeholders it consumed, so the
+// next free placeholder is start+consumed.
+func (qb *QueryBuilder) BuildAt(start int) (string, int) {
+	qb.Sql = qb.memoize(fmt.Sprintf("select@%d", start), func() string {
+		qb.Sql = qb.buildSQL()
+		qb.replaceWhereValues(start)
+		return qb.Sql
+	})
+	return qb.Sql, len(qb.GetValues())
+}
+
+// memoize returns the cached SQL for the given kind of statement or
+// builds and caches it. The cache is discarded when the public settings
+// that affect the generated SQL change.
+func (qb *QueryBuilder) memoize(kind string, build func() string) string {
+	// The dialect of the query and its placeholders discard the cache
+	// when they are set, only the global dialect can change behind it
+	state := cacheState{selectAlias: qb.SelectAlias, tagComments: TagComments}
+	if qb.dialect == nil {
+		state.dialect = activeDialect()
+	}
+	if qb.cache == nil || !qb.cacheState.equal(state) {
+		qb.cache = map[string]string{}
+		qb.cacheState = state
+	}
+	if sql, ok := qb.cache[kind]; ok {
+		return sql
+	}
+	sql := build()
+	qb.cache[kind] = sql
+	return sql
+}
+
+// equal tells whether the SQL built with the settings s can be reused
+// with the settings other. The dialects whose values can't be compared
+// are never equal.
+func (s cacheState) equal(other cacheState) bool {
+	if s.selectAlias != other.selectAlias || s.tagComments != other.tagComments {
+		return false
+	}
+	if s.dialect == nil || other.dialect == nil {
+		return s.dialect == other.dialect
+	}
+	if !reflect.TypeOf(s.dialect).Comparable() {
+		return false
+	}
+	return s.dialect == other.dialect
+}
+
+// invalidate discards the memoized SQL, it must be called by every
+// method that changes the query.
+func (qb *QueryBuilder) invalidate() {
+	qb.cache = nil
+}
+
+func (qb *QueryBuilder) replaceWhereValues(start int) {
+	if Testing {
+		// Placeholders generated by the builder are always $?
+		qb.Sql = strings.Replace(qb.Sql, "$?", "?", -1)
+	}
+	vals := qb.GetValues()
+	d := qb.getDialect()
+	for i := range vals {
+		qb.Sql = strings.Replace(qb.Sql, getPlaceholder(), d.Placeholder(start+i), 1)
+	}
 }
 
 func (qb *QueryBuilder) buildSQL() string {
+	if qb.needsFullJoinEmulation() {
+		return qb.emulateFullJoins().buildSQL()
+	}
+	if featuresOf(qb.getDialect()).swapRightJoins {
+		if swapped, ok := qb.swapRightJoin(); ok {
+			return swapped.buildSQL()
+		}
+	}
+	return qb.buildPaginatedSQL(featuresOf(qb.getDialect()).pagination)
+}
+
+// buildPaginatedSQL builds the query expressing its LIMIT and OFFSET
+// with the given style.
+func (qb *QueryBuilder) buildPaginatedSQL(style paginationStyle) string {
+	top := ""
+	if style == paginateTop && len(qb.limit) > 0 && len(qb.offset) <= 0 {
+		top = "TOP " + qb.limit + " "
+	}
 	parts := []string{
-		qb.buildSelect(),
+		qb.buildHintComment(),
+		qb.buildWith(),
+		qb.buildSelect(top),
 		qb.buildFrom(),
 		qb.buildInnerJoin(),
 		qb.buildLeftJoin(),
+		qb.buildJoins(),
+		qb.asOf,
 		qb.buildWhere(),
 		qb.buildGroupBy(),
 		qb.buildHaving(),
-		qb.buildOrderBy(),
-		qb.buildLimit(),
+		qb.buildWindow(),
+		strings.Join(qb.compound, " "),
+		qb.buildOrderBy(featuresOf(qb.getDialect()).emulateNulls),
 	}
 	parts = reduceEmptyElements(parts)
-	return strings.Join(parts, " ")
+	sql := strings.Join(parts, " ")
+	if len(top) <= 0 {
+		sql = qb.paginate(style, sql)
+	}
+	if lock := qb.lock.build(); len(lock) > 0 && featuresOf(qb.getDialect()).locking {
+		sql += " " + lock
+	}
+	return sql
+}
+
+// countWrapped tells whether the rows of the query can only be counted
+// by wrapping it in a subquery, as its rows are not the rows of its
+// tables.
+func (qb *QueryBuilder) countWrapped() bool {
+	return len(qb.compound) > 0 || qb.needsFullJoinEmulation() || len(qb.groupBy) > 0 || len(qb.having) > 0 || len(qb.distinct) > 0
 }
 
+// buildCountSQL builds the query that counts the rows of the query
+// regardless of its ORDER BY, LIMIT and OFFSET.
 func (qb *QueryBuilder) buildCountSQL() string {
+	if qb.countWrapped() {
+		return "SELECT COUNT(*) FROM (" + qb.unordered().buildSQL() + ") counted"
+	}
 	parts := []string{
+		qb.buildHintComment(),
+		qb.buildWith(),
 		"SELECT COUNT(*)",
 		qb.buildFrom(),
 		qb.buildInnerJoin(),
 		qb.buildLeftJoin(),
+		qb.buildJoins(),
+		qb.asOf,
 		qb.buildWhere(),
-		qb.buildGroupBy(),
-		qb.buildHaving(),
-		qb.buildOrderBy(),
-		qb.buildLimit(),
 	}
 	parts = reduceEmptyElements(parts)
 	return strings.Join(parts, " ")
 }
 
-func (qb *QueryBuilder) buildSelect() string {
+// countValues returns the values of the query built by buildCountSQL.
+func (qb *QueryBuilder) countValues() []interface{} {
+	if qb.countWrapped() {
+		return qb.unordered().GetValues()
+	}
+	ret := []interface{}{}
+	for _, clause := range valueClauses {
+		if clause != "select" && clause != "order" {
+			ret = append(ret, qb.values[clause]...)
+		}
+	}
+	return ret
+}
+
+func (qb *QueryBuilder) buildWith() string {
+	if len(qb.ctes) <= 0 {
+		return ""
+	}
+	if qb.recursive {
+		return "WITH RECURSIVE " + strings.Join(qb.ctes, ", ")
+	}
+	return "WITH " + strings.Join(qb.ctes, ", ")
+}
+
+func (qb *QueryBuilder) buildSelect(top string) string {
 	if len(qb.columns) > 0 {
-		return `SELECT ` + strings.Join(qb.columns, `,`)
+		return `SELECT ` + qb.distinct + top + strings.Join(qb.columns, `,`)
 	}
-	return "SELECT * "
+	return "SELECT " + qb.distinct + top + "* "
 }
 
 func (qb *QueryBuilder) buildFrom() string {
 	result := `FROM ` + qb.from
-	if len(qb.SelectAlias) > 0 {
+	if len(qb.systemTime) > 0 {
+		result += " " + qb.systemTime
+	}
+	if len(qb.fromAlias) > 0 {
+		if qb.fromAlias != qb.from {
+			result += " " + qb.fromAlias
+		}
+	} else if len(qb.SelectAlias) > 0 {
 		result += " " + qb.SelectAlias
 	}
+	if hints := qb.buildIndexHints(); len(hints) > 0 {
+		result += " " + hints
+	}
 	return result
 }
 
@@ -271,11 +1679,26 @@ func (qb *QueryBuilder) buildLeftJoin() string {
 	return ""
 }
 
+func (qb *QueryBuilder) buildJoins() string {
+	parts := []string{}
+	for _, j := range qb.joins {
+		parts = append(parts, j.kind+" "+j.expr)
+	}
+	return strings.Join(parts, " ")
+}
+
 func (qb *QueryBuilder) buildWhere() string {
+	if len(qb.scopes) <= 0 {
+		if len(qb.where) > 0 {
+			return "WHERE " + joinConditions(qb.where)
+		}
+		return ""
+	}
+	conds := append([]string{}, qb.scopes...)
 	if len(qb.where) > 0 {
-		return "WHERE " + strings.Join(qb.where, " AND ")
+		conds = append(conds, parenthesizeOr(joinConditions(qb.where)))
 	}
-	return ""
+	return "WHERE " + strings.Join(conds, " AND ")
 }
 
 func (qb *QueryBuilder) buildGroupBy() string {
@@ -292,44 +1715,182 @@ func (qb *QueryBuilder) buildHaving() string {
 	return ""
 }
 
-func (qb *QueryBuilder) buildOrderBy() string {
-	if len(qb.orderBy) > 0 {
-		return "ORDER BY " + strings.Join(qb.orderBy, ", ")
+// buildOrderBy builds the ORDER BY clause, emulating NULLS FIRST and
+// NULLS LAST when emulateNulls is set.
+func (qb *QueryBuilder) buildOrderBy(emulateNulls bool) string {
+	orderBy := qb.orderBy
+	if len(orderBy) <= 0 && len(qb.defOrder) > 0 {
+		orderBy = parseOrderTerms(qb.defOrder)
 	}
-	return ""
+	if len(orderBy) <= 0 {
+		return ""
+	}
+	terms := make([]string, len(orderBy))
+	for i, term := range orderBy {
+		terms[i] = term.build(emulateNulls)
+	}
+	return "ORDER BY " + strings.Join(terms, ", ")
 }
 
 func (qb *QueryBuilder) buildLimit() string {
-	if len(qb.limit) > 0 {
-		return "LIMIT " + qb.limit
-	}
-	return ""
+	return qb.getDialect().Limit(qb.limit, qb.offset)
 }
 
 // BuildCount is the same as Build() with the difference that
 // it ignores the values passed to Select() function and replaces it
-// with COUNT(*)
+// with COUNT(*). The ORDER BY, LIMIT and OFFSET clauses are left out, so
+// the total number of rows is counted, and the queries with GROUP BY,
+// HAVING, DISTINCT or UNION are wrapped as
+// SELECT COUNT(*) FROM (<query>) counted
+// so their rows are counted instead of the rows of each group. The
+// values of the count are returned by BuildCountWithArgs.
 func (qb *QueryBuilder) BuildCount() string {
-	qb.Sql = qb.buildCountSQL()
-	qb.replaceWhereValues()
+	qb.Sql = qb.memoize("count", func() string {
+		qb.Sql = qb.buildCountSQL()
+		qb.replaceWhereValues(1)
+		return qb.Sql + qb.tagComment()
+	})
+	return qb.Sql
+}
+
+// BuildCountWithArgs is the same as BuildCount but it returns the values
+// of the count too and reports the errors of the query as BuildWithArgs
+// does.
+func (qb *QueryBuilder) BuildCountWithArgs() (string, []interface{}, error) {
+	if _, _, err := qb.BuildWithArgs(); err != nil {
+		return "", nil, err
+	}
+	return qb.BuildCount(), qb.countValues(), nil
+}
+
+// Count runs the count built by BuildCount and returns the number of
+// rows of the query, for example
+// total, err := queryBuilder.Select("user_id").From("orders").GroupBy("user_id").Count(db)
+func (qb *QueryBuilder) Count(Db Queryer) (int64, error) {
+	return qb.CountContext(context.Background(), Db)
+}
+
+// CountContext is the same as Count but the query is canceled when ctx
+// is done.
+func (qb *QueryBuilder) CountContext(ctx context.Context, Db Queryer) (int64, error) {
+	sql, vals, err := qb.BuildCountWithArgs()
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	err = queryRow(qb.tagged(ctx), Db, sql, vals...).Scan(&count)
+	return count, canceled(ctx, err)
+}
+
+// Exists tells whether the query returns any row, for example
+// taken, err := queryBuilder.Select("id").From("users").Where("email = $?", email).Exists(db)
+// The query is run as SELECT EXISTS(<query>), or as
+// SELECT 1 FROM (<query>) probe LIMIT 1 with the dialects that can't
+// select EXISTS.
+func (qb *QueryBuilder) Exists(Db Queryer) (bool, error) {
+	return qb.ExistsContext(context.Background(), Db)
+}
+
+// ExistsContext is the same as Exists but the query is canceled when ctx
+// is done.
+func (qb *QueryBuilder) ExistsContext(ctx context.Context, Db Queryer) (bool, error) {
+	exists := featuresOf(qb.getDialect()).exists
+	qry, vals, err := qb.buildExists(exists)
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		var one int
+		err = queryRow(qb.tagged(ctx), Db, qry, vals...).Scan(&one)
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return err == nil, canceled(ctx, err)
+	}
+	var found bool
+	err = queryRow(qb.tagged(ctx), Db, qry, vals...).Scan(&found)
+	return found, canceled(ctx, err)
+}
+
+// buildExists builds the query that tells whether the query returns any
+// row, selecting EXISTS when exists is true.
+func (qb *QueryBuilder) buildExists(exists bool) (string, []interface{}, error) {
+	if exists {
+		sql, vals, err := qb.BuildWithArgs()
+		if err != nil {
+			return "", nil, err
+		}
+		return "SELECT EXISTS(" + sql + ")", vals, nil
+	}
+	probe := &QueryBuilder{dialect: qb.dialect, placeholders: qb.placeholders}
+	probe.Select("1").From(qb).Limit(1)
+	probe.fromAlias = "probe"
+	return probe.BuildWithArgs()
+}
+
+// BuildCountWithLimit counts the rows the query returns, keeping its
+// LIMIT and OFFSET, up to max rows, which is cheaper than a full count
+// to tell whether an export would be too big, for example
+// queryBuilder.Select("id").From("users").Limit(5000).BuildCountWithLimit(1000)
+// generates SELECT COUNT(*) FROM (SELECT * FROM (SELECT id FROM users LIMIT 5000) limited LIMIT 1000) counted
+// When max is 0 the rows are not capped.
+func (qb *QueryBuilder) BuildCountWithLimit(max int) string {
+	qb.Sql = qb.memoize(fmt.Sprintf("count@limit%d", max), func() string {
+		qb.Sql = qb.buildSQL()
+		if max > 0 {
+			// The cap is rendered by the dialect, as the LIMIT of the query
+			limited := &QueryBuilder{dialect: qb.dialect, placeholders: qb.placeholders}
+			limited.Select("*").From("(" + qb.Sql + ") limited").Limit(max)
+			qb.Sql = limited.buildSQL()
+		}
+		qb.Sql = "SELECT COUNT(*) FROM (" + qb.Sql + ") counted"
+		qb.replaceWhereValues(1)
+		return qb.Sql
+	})
 	return qb.Sql
 }
 
 // Query is a shortcut for building the query, passing it to the DB driver
 // and passing it the values
 func (qb *QueryBuilder) Query(Db *sql.DB) (*sql.Rows, error) {
-	return Db.Query(qb.Build(), qb.GetValues()...)
+	return qb.QueryContext(context.Background(), Db)
+}
+
+// QueryContext is the same as Query but the query is canceled when ctx
+// is done. Db can be a *sql.DB, a *sql.Tx or any other Queryer.
+func (qb *QueryBuilder) QueryContext(ctx context.Context, Db Queryer) (*sql.Rows, error) {
+	sql, vals, err := qb.BuildWithArgs()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := Db.QueryContext(qb.tagged(ctx), sql, vals...)
+	return rows, canceled(ctx, err)
 }
 
 // QueryAndScan is used for executing a query and scanning it's result
 // into the struct's parameters passed in obj.
 func (qb *QueryBuilder) QueryAndScan(Db *sql.DB, obj interface{}) error {
-	sql := qb.Build()
-	vals := qb.GetValues()
-	pointers := GetFieldPointers(obj)
-	err := Db.QueryRow(sql, vals...).Scan(pointers...)
+	return qb.QueryAndScanContext(context.Background(), Db, obj)
+}
+
+// QueryAndScanContext is the same as QueryAndScan but the query is
+// canceled when ctx is done. Db can be a *sql.DB, a *sql.Tx or any other
+// Queryer.
+func (qb *QueryBuilder) QueryAndScanContext(ctx context.Context, Db Queryer, obj interface{}) error {
+	sql, vals, err := qb.BuildWithArgs()
 	if err != nil {
-		log.Println(err)
+		return err
+	}
+	rows, err := Db.QueryContext(qb.tagged(ctx), sql, vals...)
+	if err == nil {
+		err = scanOne(rows, obj)
+	}
+	if err = canceled(ctx, err); err != nil {
+		if len(qb.tag) > 0 {
+			log.Printf("%s: %s", qb.tag, err)
+		} else {
+			log.Println(err)
+		}
 	}
 	return err
 }
@@ -343,14 +1904,28 @@ func GetFieldPointers(obj interface{}) []interface{} {
 	v := reflect.ValueOf(obj).Elem()
 	fields := []interface{}{}
 	// Loops all fields
-	for i := 0; i <= v.NumField(); i++ {
-		if len(t.Field(i).Tag.Get("db")) > 0 {
+	for i := 0; i <= v.NumField()-1; i++ {
+		if len(columnName(t, t.Field(i))) > 0 {
 			fields = append(fields, v.Field(i).Addr().Interface())
 		}
 	}
+	// Nested structs are scanned after their parent, as Select does
+	for i := 0; i <= v.NumField()-1; i++ {
+		if len(t.Field(i).Tag.Get("join")) > 0 {
+			fields = append(fields, GetFieldPointers(v.Field(i).Addr().Interface())...)
+		}
+	}
 	return fields
 }
 
+// Queryer is the common interface of *sql.DB, *sql.Tx and *sql.Conn, it
+// can be used to write code that runs both inside and outside transactions.
+type Queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
 // QueryStructInfo represents a parsed information that
 // holds metadata of the object after parsing tags, position of
 // each field and actual values of the structure in each field.
@@ -362,35 +1937,168 @@ type QueryStructInfo struct {
 	PrimaryKeys      string
 	PrimaryKeyQuery  []string
 	PrimaryKeyValues []interface{}
+
+	primaryKeyColumns []string
+	// guards are the documents whose hash must not have changed for
+	// Update to succeed, see the hashof tag.
+	guards []documentGuard
 }
 
 // Insert inserts a new record in a table
 // The fields in the structure obj must be added the
-// "db" tag in the declaration of the structure
+// "db" tag in the declaration of the structure.
+// Db can be a *sql.DB, a *sql.Tx or any other Queryer, the same
+// applies to Update and Delete.
 func Insert(Db interface{}, table string, obj interface{}) (sql.Result, error) {
-	dbType := getDbType(Db)
+	return InsertContext(context.Background(), Db, table, obj)
+}
 
+// InsertContext is the same as Insert but the statement is canceled
+// when ctx is done.
+func InsertContext(ctx context.Context, Db interface{}, table string, obj interface{}) (sql.Result, error) {
 	queryInfo, err := creatQueryStructInfo(obj)
 	if err != nil {
 		return nil, err
 	}
 
 	// Build the query
-	qry := fmt.Sprintf(`INSERT INTO %s ("%s") VALUES(%s)`, table, strings.Join(queryInfo.Fields, `","`), strings.Join(queryInfo.Positions, ","))
-	err = nil
+	d := activeDialect()
+	cols := make([]string, len(queryInfo.Fields))
+	for i, field := range queryInfo.Fields {
+		cols[i] = d.QuoteIdent(field)
+	}
+	qry := fmt.Sprintf(`INSERT INTO %s (%s) VALUES(%s)`, table, strings.Join(cols, ","), strings.Join(queryInfo.Positions, ","))
+	return execStatement(ctx, Db, KindInsert, table, qry, queryInfo.Values)
+}
+
+// Upsert inserts a record or updates it when a record with the same
+// primary key already exists, the fields tagged with "pk" are inserted
+// too as they are the key the conflict is detected on. The statement is
+// generated for the dialect set with SetDialect, for example with MySQL
+// INSERT INTO user (`id`,`username`) VALUES(?,?) ON DUPLICATE KEY UPDATE `username` = VALUES(`username`)
+func Upsert(Db interface{}, table string, obj interface{}) (sql.Result, error) {
+	return UpsertContext(context.Background(), Db, table, obj)
+}
+
+// UpsertContext is the same as Upsert but the statement is canceled
+// when ctx is done.
+func UpsertContext(ctx context.Context, Db interface{}, table string, obj interface{}) (sql.Result, error) {
+	qry, values, err := buildUpsert(activeDialect(), table, obj, false)
+	if err != nil {
+		return nil, err
+	}
+	return execStatement(ctx, Db, KindInsert, table, qry, values)
+}
+
+// UpsertInserted is the same as Upsert but it reports whether the record
+// was inserted or an existing one was updated. Postgres tells it with
+// the xmax system column of the returned row and MySQL with the number
+// of affected rows, which is 1 for inserts. Other databases, and the
+// dialects whose SupportsReturning is false, first try
+// to insert the record ignoring conflicts and update it when nothing was
+// inserted, Db should be a *sql.Tx for both statements to be atomic.
+func UpsertInserted(Db interface{}, table string, obj interface{}) (inserted bool, err error) {
+	return UpsertInsertedContext(context.Background(), Db, table, obj)
+}
+
+// UpsertInsertedContext is the same as UpsertInserted but the statements
+// are canceled when ctx is done.
+func UpsertInsertedContext(ctx context.Context, Db interface{}, table string, obj interface{}) (inserted bool, err error) {
+	d := activeDialect()
+	f := featuresOf(d)
+	returning := f.systemColumns && d.SupportsReturning()
+	qry, values, err := buildUpsert(d, table, obj, !returning && f.upsert != upsertOnDuplicateKey)
+	if err != nil {
+		return false, err
+	}
+	if returning {
+		err = queryRowStatement(ctx, Db, KindInsert, table, qry+" RETURNING (xmax = 0)", values, &inserted)
+		return inserted, err
+	}
+	result, err := execStatement(ctx, Db, KindInsert, table, qry, values)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 1 {
+		return err == nil, err
+	}
+	if f.upsert == upsertOnDuplicateKey {
+		// 2 rows are affected by an update and 0 when nothing changed
+		return false, nil
+	}
+	_, err = UpdateContext(ctx, Db, table, obj)
+	return false, err
+}
+
+// buildUpsert builds the upsert statement of obj with the dialect d,
+// when doNothing is set the conflicting rows are left as they are.
+func buildUpsert(d Dialect, table string, obj interface{}, doNothing bool) (string, []interface{}, error) {
+	queryInfo, err := creatQueryStructInfo(obj)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(queryInfo.primaryKeyColumns) <= 0 {
+		return "", nil, errors.New("there is no primary key in the structure")
+	}
+
+	style := featuresOf(d).upsert
+	if style == upsertUnsupported {
+		return "", nil, fmt.Errorf("goql: the %s dialect doesn't support upserts", d.Name())
+	}
+	keys := make([]string, len(queryInfo.primaryKeyColumns))
+	for i, col := range queryInfo.primaryKeyColumns {
+		keys[i] = d.QuoteIdent(col)
+	}
+	cols := append([]string{}, keys...)
+	sets := []string{}
+	for _, field := range queryInfo.Fields {
+		col := d.QuoteIdent(field)
+		cols = append(cols, col)
+		if style == upsertOnDuplicateKey {
+			sets = append(sets, fmt.Sprintf("%s = VALUES(%s)", col, col))
+		} else {
+			sets = append(sets, fmt.Sprintf("%s = EXCLUDED.%s", col, col))
+		}
+	}
+	if doNothing {
+		sets = nil
+	}
+	values := append(append([]interface{}{}, queryInfo.PrimaryKeyValues...), queryInfo.Values...)
+	positions := make([]string, len(values))
+	for i := range values {
+		positions[i] = d.Placeholder(i + 1)
+	}
 
-	if dbType == dbTypeDb {
-		return Db.(*sql.DB).Exec(qry, queryInfo.Values...)
+	qry := fmt.Sprintf(`INSERT INTO %s (%s) VALUES(%s)`, table, strings.Join(cols, ","), strings.Join(positions, ","))
+	switch {
+	case style == upsertOnDuplicateKey:
+		// Setting a key to itself keeps the statement valid without fields
+		if len(sets) <= 0 {
+			sets = append(sets, fmt.Sprintf("%s = %s", keys[0], keys[0]))
+		}
+		qry += " ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+	case len(sets) <= 0:
+		qry += fmt.Sprintf(" ON CONFLICT (%s) DO NOTHING", strings.Join(keys, ","))
+	default:
+		qry += fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(keys, ","), strings.Join(sets, ", "))
 	}
-	return Db.(*sql.Tx).Exec(qry, queryInfo.Values...)
+	return qry, values, nil
 }
 
 // Update updates a record. Note that this only works for atomic updates
 // and not for massive updates. The field with primary tag will serve as
 // update reference, in case there is no field with primary, the update will fail
+// The documents of the fields with a hashof tag are only updated when
+// they didn't change since they were read, otherwise ErrDocumentChanged
+// is returned, see the hashof tag.
 func Update(Db interface{}, table string, obj interface{}) (sql.Result, error) {
-	dbType := getDbType(Db)
+	return UpdateContext(context.Background(), Db, table, obj)
+}
 
+// UpdateContext is the same as Update but the statement is canceled
+// when ctx is done.
+func UpdateContext(ctx context.Context, Db interface{}, table string, obj interface{}) (sql.Result, error) {
 	queryInfo, err := creatQueryStructInfo(obj)
 	if err != nil {
 		return nil, err
@@ -401,18 +2109,32 @@ func Update(Db interface{}, table string, obj interface{}) (sql.Result, error) {
 	}
 
 	// Build the query
-	qry := fmt.Sprintf(`UPDATE %s SET %s WHERE (%s)`, table, strings.Join(queryInfo.FieldsForUpdate, `,`), strings.Join(queryInfo.PrimaryKeyQuery, ` AND `))
+	conds := queryInfo.PrimaryKeyQuery
 	values := append(queryInfo.Values, queryInfo.PrimaryKeyValues...)
-	if dbType == dbTypeDb {
-		return Db.(*sql.DB).Exec(qry, values...)
+	d := activeDialect()
+	for _, guard := range queryInfo.guards {
+		conds = append(conds, fmt.Sprintf(`%s = %s`, documentHash(d, d.QuoteIdent(guard.column)), d.Placeholder(len(values)+1)))
+		values = append(values, guard.hash)
+	}
+	qry := fmt.Sprintf(`UPDATE %s SET %s WHERE (%s)`, table, strings.Join(queryInfo.FieldsForUpdate, `,`), strings.Join(conds, ` AND `))
+	result, err := execStatement(ctx, Db, KindUpdate, table, qry, values)
+	if err != nil || len(queryInfo.guards) <= 0 {
+		return result, err
 	}
-	return Db.(*sql.Tx).Exec(qry, values...)
+	if affected, err := result.RowsAffected(); err == nil && affected <= 0 {
+		return result, ErrDocumentChanged
+	}
+	return result, nil
 }
 
 // Delete function deletes the structure based on the pk tag of the attribute
 func Delete(Db interface{}, table string, obj interface{}) (sql.Result, error) {
-	dbType := getDbType(Db)
+	return DeleteContext(context.Background(), Db, table, obj)
+}
 
+// DeleteContext is the same as Delete but the statement is canceled
+// when ctx is done.
+func DeleteContext(ctx context.Context, Db interface{}, table string, obj interface{}) (sql.Result, error) {
 	queryInfo, err := creatQueryStructInfo(obj)
 	if err != nil {
 		return nil, err
@@ -421,12 +2143,8 @@ func Delete(Db interface{}, table string, obj interface{}) (sql.Result, error) {
 	if len(queryInfo.PrimaryKeyQuery) <= 0 {
 		return nil, errors.New("There is no primary key in the structure")
 	}
-	qry := fmt.Sprintf(`DELETE FROM %s WHERE (%s)`, table, strings.Join(queryInfo.PrimaryKeyQuery, ","))
-
-	if dbType == dbTypeDb {
-		return Db.(*sql.DB).Exec(qry, queryInfo.PrimaryKeyValues...)
-	}
-	return Db.(*sql.Tx).Exec(qry, queryInfo.PrimaryKeyValues...)
+	qry := fmt.Sprintf(`DELETE FROM %s WHERE (%s)`, table, strings.Join(queryInfo.primaryKeyQuery(1), ` AND `))
+	return execStatement(ctx, Db, KindDelete, table, qry, queryInfo.PrimaryKeyValues)
 }
 
 // Helpers
@@ -442,10 +2160,7 @@ func reduceEmptyElements(items []string) []string {
 }
 
 func getPlaceholderWithCounter(i int) string {
-	if Testing {
-		return "?"
-	}
-	return fmt.Sprintf("$%d", i)
+	return activeDialect().Placeholder(i)
 }
 
 func getPlaceholder() string {
@@ -457,7 +2172,7 @@ func getPlaceholder() string {
 
 func getDbType(Db interface{}) string {
 	switch Db.(type) {
-	case *sql.DB:
+	case *sql.DB, *SessionDB:
 		return dbTypeDb
 	case *sql.Tx:
 		return dbTypeTx
@@ -466,6 +2181,13 @@ func getDbType(Db interface{}) string {
 	}
 }
 
+func toQueryer(Db interface{}) Queryer {
+	if q, ok := Db.(Queryer); ok {
+		return q
+	}
+	panic("invalid db type struct")
+}
+
 func creatQueryStructInfo(obj interface{}) (*QueryStructInfo, error) {
 	result := QueryStructInfo{}
 
@@ -477,27 +2199,36 @@ func creatQueryStructInfo(obj interface{}) (*QueryStructInfo, error) {
 	if num <= 0 {
 		return nil, errors.New("obj has no properties")
 	}
+	checkFields(t)
 
+	d := activeDialect()
 	j := 1
 	for i := 0; i <= num-1; i++ {
 		fType := t.Field(i)
 		fVal := v.Field(i)
-		// Check if the field is calculated
-		if len(fType.Tag.Get("sql")) > 0 {
+		col := columnName(t, fType)
+		// Check if the field is calculated or generated by the database
+		if len(fType.Tag.Get("sql")) > 0 || len(fType.Tag.Get("generated")) > 0 {
+			continue
+		}
+		if of := fType.Tag.Get("hashof"); len(of) > 0 {
+			if hash := fVal.Interface(); !isZero(hash) {
+				result.guards = append(result.guards, documentGuard{column: of, hash: hash})
+			}
 			continue
 		}
 		if len(fType.Tag.Get("pk")) > 0 {
-			result.PrimaryKeyQuery = append(result.PrimaryKeyQuery, fmt.Sprintf(`"%s" = %s`, fType.Tag.Get("db"), getPlaceholderWithCounter(j)))
-			result.PrimaryKeys = fType.Tag.Get("db")
+			result.primaryKeyColumns = append(result.primaryKeyColumns, col)
+			result.PrimaryKeys = col
 			result.PrimaryKeyValues = append(result.PrimaryKeyValues, fVal.Interface())
 			continue
 		}
 		// Check for the database field tag
-		if len(fType.Tag.Get("db")) <= 0 {
+		if len(col) <= 0 {
 			continue
 		}
 		if len(fType.Tag.Get("pk")) <= 0 {
-			result.FieldsForUpdate = append(result.FieldsForUpdate, fmt.Sprintf(`"%s" = %s`, fType.Tag.Get("db"), getPlaceholderWithCounter(j)))
+			result.FieldsForUpdate = append(result.FieldsForUpdate, fmt.Sprintf(`%s = %s`, d.QuoteIdent(col), d.Placeholder(j)))
 		}
 		// Special tags
 		var appendVal interface{}
@@ -521,11 +2252,47 @@ func creatQueryStructInfo(obj interface{}) (*QueryStructInfo, error) {
 			appendVal = fVal.Interface()
 		}
 		result.Values = append(result.Values, appendVal)
-		result.Fields = append(result.Fields, fType.Tag.Get("db"))
+		result.Fields = append(result.Fields, col)
 
-		result.Positions = append(result.Positions, getPlaceholderWithCounter(j))
+		result.Positions = append(result.Positions, d.Placeholder(j))
 		j++
+
+		// Write the same value to the legacy column during renames
+		if legacy, dualWrite := parseLegacyTag(fType.Tag.Get("legacy")); dualWrite {
+			result.FieldsForUpdate = append(result.FieldsForUpdate, fmt.Sprintf(`%s = %s`, d.QuoteIdent(legacy), d.Placeholder(j)))
+			result.Values = append(result.Values, appendVal)
+			result.Fields = append(result.Fields, legacy)
+			result.Positions = append(result.Positions, d.Placeholder(j))
+			j++
+		}
 	}
 
+	// The primary keys are compared after the updated fields are set
+	result.PrimaryKeyQuery = result.primaryKeyQuery(j)
 	return &result, nil
 }
+
+// primaryKeyQuery returns the conditions that match the primary keys,
+// their placeholders are numbered starting at start.
+func (info *QueryStructInfo) primaryKeyQuery(start int) []string {
+	d := activeDialect()
+	conds := make([]string, len(info.primaryKeyColumns))
+	for i, col := range info.primaryKeyColumns {
+		conds[i] = fmt.Sprintf(`%s = %s`, d.QuoteIdent(col), d.Placeholder(start+i))
+	}
+	return conds
+}
+
+// parseLegacyTag parses the legacy tag used while a column is renamed
+// online, for example `db:"email" legacy:"email_address,dualwrite"`.
+// Selects read the new column falling back to the legacy one and, when
+// the dualwrite option is set, Insert and Update write both columns.
+func parseLegacyTag(tag string) (column string, dualWrite bool) {
+	opts := strings.Split(tag, ",")
+	for _, opt := range opts[1:] {
+		if strings.TrimSpace(opt) == "dualwrite" {
+			dualWrite = true
+		}
+	}
+	return strings.TrimSpace(opts[0]), dualWrite && len(strings.TrimSpace(opts[0])) > 0
+}
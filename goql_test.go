@@ -1,8 +1,13 @@
 package goql
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
 	"fmt"
+	"log"
+	"os"
+	"sort"
 	"strings"
 	"testing"
 
@@ -95,6 +100,7 @@ func TestSelectWithoutInvalidStructAsArg(t *testing.T) {
 }
 
 func TestSimpleWhere(t *testing.T) {
+	Testing = false
 	expected := `SELECT user FROM users WHERE id = $?`
 	qb := QueryBuilder{}
 	qb.Select("user").From("users").Where("id = $?")
@@ -181,6 +187,45 @@ func TestUpdate(t *testing.T) {
 	}
 }
 
+func TestUpsert(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+
+	for _, password := range []string{"a", "b"} {
+		if _, err := Upsert(db, "user", User{ID: 7, Username: "john", Password: password}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var count int
+	var password string
+	if err := db.QueryRow("SELECT COUNT(*), MAX(password) FROM user WHERE id = 7").Scan(&count, &password); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 || password != "b" {
+		t.Errorf("Expected the row to be updated, got %d rows with password %s", count, password)
+	}
+}
+
+func TestUpsertInserted(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+
+	for i, expected := range []bool{true, false} {
+		inserted, err := UpsertInserted(db, "user", User{ID: 7, Username: "john", Password: fmt.Sprint(i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if inserted != expected {
+			t.Errorf("Expected inserted to be %t", expected)
+		}
+	}
+	var password string
+	db.QueryRow("SELECT password FROM user WHERE id = 7").Scan(&password)
+	if password != "1" {
+		t.Errorf("Expected the row to be updated, got password %s", password)
+	}
+}
+
 func TestDelete(t *testing.T) {
 	db := dbSetup()
 	defer db.Close()
@@ -203,3 +248,1056 @@ func TestDelete(t *testing.T) {
 		t.Error("Delete didn't delete the row")
 	}
 }
+
+func TestAsOfSystemTime(t *testing.T) {
+	Testing = false
+	expected := `SELECT id FROM users INNER JOIN config USING(id) AS OF SYSTEM TIME $1 WHERE id = $2`
+	qb := QueryBuilder{}
+	qb.Select("id").From("users").InnerJoin("config USING(id)").Where("id = $?", 3).AsOf("-10s")
+	qb.Build()
+	if strings.Trim(qb.Sql, " ") != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, qb.Sql)
+	}
+	vals := qb.GetValues()
+	if len(vals) != 2 || vals[0] != "-10s" || vals[1] != 3 {
+		t.Errorf("Unexpected values %v", vals)
+	}
+}
+
+func TestForSystemTime(t *testing.T) {
+	Testing = false
+	expected := `SELECT id FROM users FOR SYSTEM_TIME AS OF $1 u WHERE id = $2`
+	qb := QueryBuilder{SelectAlias: "u"}
+	qb.Select("id").From("users").Where("id = $?", 3).AsOf("-10s").ForSystemTime("2020-01-01")
+	qb.Build()
+	if strings.Trim(qb.Sql, " ") != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, qb.Sql)
+	}
+	if vals := qb.GetValues(); len(vals) != 2 || vals[0] != "2020-01-01" {
+		t.Errorf("Unexpected values %v", vals)
+	}
+}
+
+func TestBuildIsMemoized(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.Select("id").From("users").Where("id = $?", 1)
+	first := qb.Build()
+	if _, ok := qb.cache["select"]; !ok {
+		t.Fatal("Expected Build to be memoized")
+	}
+	if second := qb.Build(); second != first {
+		t.Errorf("Expected %s, got %s", first, second)
+	}
+
+	qb.Where("active = $?", true)
+	expected := `SELECT id FROM users WHERE id = $1 AND active = $2`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+
+	qb.SelectAlias = "u"
+	expected = `SELECT id FROM users u WHERE id = $1 AND active = $2`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+
+	SetDialect(MySQL)
+	defer SetDialect(Postgres)
+	expected = `SELECT id FROM users u WHERE id = ? AND active = ?`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+
+	// The dialects that can't be compared are never reused nor panic
+	SetDialect(uncomparableDialect{postgres{}, []string{"x"}})
+	expected = `SELECT id FROM users u WHERE id = $1 AND active = $2`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+}
+
+type uncomparableDialect struct {
+	postgres
+	names []string
+}
+
+type renamedUser struct {
+	ID    int64  `db:"id" pk:"true"`
+	Email string `db:"email" legacy:"email_address,dualwrite"`
+}
+
+func TestSelectWithLegacyColumn(t *testing.T) {
+	expected := `SELECT "u"."id",COALESCE("u"."email", "u"."email_address") "email" FROM users u`
+	qb := QueryBuilder{SelectAlias: "u"}
+	qb.Select(renamedUser{}).From("users")
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+}
+
+func TestInsertAndUpdateDualWriteLegacyColumn(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`CREATE TABLE renamed(id INTEGER PRIMARY KEY AUTOINCREMENT, email CHAR(255), email_address CHAR(255))`)
+
+	if _, err := Insert(db, "renamed", renamedUser{Email: "a@b.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Update(db, "renamed", renamedUser{ID: 1, Email: "c@d.com"}); err != nil {
+		t.Fatal(err)
+	}
+	var email, legacy string
+	if err := db.QueryRow("SELECT email, email_address FROM renamed").Scan(&email, &legacy); err != nil {
+		t.Fatal(err)
+	}
+	if email != "c@d.com" || legacy != "c@d.com" {
+		t.Errorf("Expected both columns to be written, got %s and %s", email, legacy)
+	}
+}
+
+func TestBuildAt(t *testing.T) {
+	Testing = false
+	expected := `SELECT id FROM users WHERE id = $3 AND active = $4`
+	qb := QueryBuilder{}
+	qb.Select("id").From("users").Where("id = $?", 1).Where("active = $?", true)
+	sql, consumed := qb.BuildAt(3)
+	if sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if consumed != 2 {
+		t.Errorf("Expected 2 placeholders to be consumed, got %d", consumed)
+	}
+	if sql := qb.Build(); sql != `SELECT id FROM users WHERE id = $1 AND active = $2` {
+		t.Errorf("Expected Build to start at 1, got %s", sql)
+	}
+}
+
+func TestOrderByExpr(t *testing.T) {
+	Testing = false
+	expected := `SELECT name FROM products WHERE active = $1 ORDER BY similarity(name, $2) DESC, id`
+	qb := QueryBuilder{}
+	qb.Select("name").From("products").OrderByExpr("similarity(name, $?) DESC", "shoe").OrderBy("id").Where("active = $?", true)
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if vals := qb.GetValues(); len(vals) != 2 || vals[0] != true || vals[1] != "shoe" {
+		t.Errorf("Unexpected values %v", vals)
+	}
+}
+
+func TestContextVariants(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := InsertContext(ctx, db, "user", User{Username: "john", Password: "doe"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UpdateContext(ctx, db, "user", User{ID: 1, Username: "jane", Password: "doe"}); err != nil {
+		t.Fatal(err)
+	}
+	user := User{}
+	qb := QueryBuilder{}
+	qb.Select("id, username, password, 'x' AS total").From("user").Where("id = ?", 1)
+	if err := qb.QueryAndScanContext(ctx, db, &user); err != nil {
+		t.Fatal(err)
+	}
+	if user.Username != "jane" {
+		t.Errorf("Expected jane, got %s", user.Username)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := DeleteContext(canceled, db, "user", user); err == nil {
+		t.Error("Expected an error deleting with a canceled context")
+	}
+	if _, err := qb.QueryContext(canceled, db); err == nil {
+		t.Error("Expected an error querying with a canceled context")
+	}
+}
+
+func TestHavingAggregates(t *testing.T) {
+	Testing = false
+	expected := `SELECT user_id FROM orders WHERE status = $1 GROUP BY user_id HAVING COUNT(*) > $2 AND SUM(amount) >= $3 ORDER BY similarity(name, $4)`
+	qb := QueryBuilder{}
+	qb.Select("user_id").From("orders").GroupBy("user_id").
+		HavingCount(">", 5).
+		HavingSum("amount", ">=", 100).
+		OrderByExpr("similarity(name, $?)", "x").
+		Where("status = $?", "paid")
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	vals := qb.GetValues()
+	if len(vals) != 4 || vals[0] != "paid" || vals[1] != 5 || vals[2] != 100 || vals[3] != "x" {
+		t.Errorf("Unexpected values %v", vals)
+	}
+}
+
+func TestHavingAggregateRejectsInvalidOperator(t *testing.T) {
+	defer func() {
+		if rec := recover(); rec == nil {
+			t.Error("Expected to panic")
+		}
+	}()
+	qb := QueryBuilder{}
+	qb.HavingCount("> 0 OR 1 =", 1)
+}
+
+func TestBuildWithArgs(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.Select("id").From("users").Where("id = $?", 1).HavingCount(">", 2)
+	sql, args, err := qb.BuildWithArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != `SELECT id FROM users WHERE id = $1 HAVING COUNT(*) > $2` {
+		t.Errorf("Unexpected SQL %s", sql)
+	}
+	if len(args) != 2 || args[0] != 1 || args[1] != 2 {
+		t.Errorf("Unexpected args %v", args)
+	}
+}
+
+func TestBuildWithArgsReportsErrors(t *testing.T) {
+	Testing = false
+	invalid := []*QueryBuilder{
+		(&QueryBuilder{}).Select("id").From("users").Where("id = $?"),
+		(&QueryBuilder{}).Select("id").From("users").Where("id = $?", 1, 2),
+		(&QueryBuilder{}).Select("id").Where("id = 1"),
+	}
+	for _, qb := range invalid {
+		if _, _, err := qb.BuildWithArgs(); err == nil {
+			t.Errorf("Expected an error building %s", qb.Build())
+		}
+	}
+}
+
+func TestOrWhereAndGroups(t *testing.T) {
+	Testing = false
+	expected := `SELECT id FROM users WHERE (a = $1 OR b = $2) AND c = $3 OR (d = $4 AND (e = 1 OR f = 2))`
+	qb := QueryBuilder{}
+	qb.Select("id").From("users").
+		WhereGroup(func(g *QueryBuilder) {
+			g.Where("a = $?", 1).OrWhere("b = $?", 2)
+		}).
+		Where("c = $?", 3).
+		OrWhereGroup(func(g *QueryBuilder) {
+			g.Where("d = $?", 4).WhereGroup(func(g *QueryBuilder) {
+				g.Where("e = 1").OrWhere("f = 2")
+			})
+		}).
+		WhereGroup(func(g *QueryBuilder) {})
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if vals := qb.GetValues(); len(vals) != 4 || vals[0] != 1 || vals[3] != 4 {
+		t.Errorf("Unexpected values %v", vals)
+	}
+}
+
+type member struct {
+	ID       int64  `db:"id" pk:"true"`
+	Username string `db:"username"`
+}
+
+type order struct {
+	ID     int64 `db:"id" pk:"true"`
+	UserID int64 `db:"user_id"`
+}
+
+func TestSelectMultipleStructs(t *testing.T) {
+	expected := `SELECT "u"."id" "u_id","u"."username" "u_username","o"."id" "o_id","o"."user_id" "o_user_id" FROM member u INNER JOIN "order" o ON o.user_id = u.id`
+	qb := QueryBuilder{}
+	qb.Select(Aliased(member{}, "u"), Aliased(order{}, "o")).InnerJoin(`"order" o ON o.user_id = u.id`)
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+
+	expected = `SELECT "user"."id" "user_id","user"."username" "user_username","user"."password" "user_password",(COUNT(col)) "user_total","order"."id" "order_id","order"."user_id" "order_user_id" FROM user`
+	qb = QueryBuilder{}
+	qb.Select(User{}, order{})
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+}
+
+func TestScanRowSplitsJoinedRow(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`CREATE TABLE "order"(id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER)`)
+	db.Exec(`CREATE TABLE member(id INTEGER PRIMARY KEY AUTOINCREMENT, username CHAR(255))`)
+	db.Exec(`INSERT INTO member(username) VALUES('ricardo')`)
+	db.Exec(`INSERT INTO "order"(user_id) VALUES(1), (1)`)
+
+	qb := QueryBuilder{}
+	qb.Select(Aliased(member{}, "u"), Aliased(order{}, "o")).
+		InnerJoin(`"order" o ON o.user_id = u.id`).
+		OrderBy(`"o"."id" DESC`)
+	rows, err := qb.Query(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		t.Fatal("Expected a row")
+	}
+	var u member
+	var o order
+	if err := ScanRow(rows, &u, &o); err != nil {
+		t.Fatal(err)
+	}
+	if u.ID != 1 || u.Username != "ricardo" || o.ID != 2 || o.UserID != 1 {
+		t.Errorf("Unexpected row %+v %+v", u, o)
+	}
+}
+
+type orderWithMember struct {
+	ID     int64  `db:"id" pk:"true"`
+	Member member `join:"member,member_id"`
+}
+
+func TestSelectAndScanNestedStruct(t *testing.T) {
+	expected := `SELECT "orderwithmember"."id","member"."id" "member_id","member"."username" "member_username" FROM orderwithmember INNER JOIN member "member" ON "member"."id" = "orderwithmember"."member_id"`
+	qb := QueryBuilder{}
+	qb.Select(orderWithMember{})
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`CREATE TABLE member(id INTEGER PRIMARY KEY AUTOINCREMENT, username CHAR(255))`)
+	db.Exec(`CREATE TABLE orderwithmember(id INTEGER PRIMARY KEY AUTOINCREMENT, member_id INTEGER)`)
+	db.Exec(`INSERT INTO member(username) VALUES('ricardo')`)
+	db.Exec(`INSERT INTO orderwithmember(member_id) VALUES(1)`)
+
+	order := orderWithMember{}
+	qb = QueryBuilder{}
+	if err := qb.Select(orderWithMember{}).QueryAndScan(db, &order); err != nil {
+		t.Fatal(err)
+	}
+	if order.ID != 1 || order.Member.ID != 1 || order.Member.Username != "ricardo" {
+		t.Errorf("Unexpected order %+v", order)
+	}
+}
+
+func TestWhereIn(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.Select("id").From("users").Where("active = $?", true).WhereIn("id", []int64{1, 2, 3})
+	expected := `SELECT id FROM users WHERE active = $1 AND id IN ($2,$3,$4)`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if vals := qb.GetValues(); len(vals) != 4 || vals[3] != int64(3) {
+		t.Errorf("Unexpected values %v", vals)
+	}
+
+	qb = QueryBuilder{}
+	qb.Select("id").From("users").WhereIn("id", []string{})
+	expected = `SELECT id FROM users WHERE 1 = 0`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+
+	qb = QueryBuilder{}
+	qb.Select("id").From("users").WhereIn("id", 1)
+	if _, _, err := qb.BuildWithArgs(); err == nil {
+		t.Error("Expected an error for a non slice value")
+	}
+}
+
+func TestSelectExcept(t *testing.T) {
+	expected := `SELECT "id","username",(COUNT(col)) "total" FROM user`
+	qb := QueryBuilder{}
+	qb.SelectExcept(User{}, "password")
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`INSERT INTO user(username, password) VALUES('ricardo', 'secret')`)
+	user := User{}
+	qb = QueryBuilder{IgnoreDynamic: true}
+	if err := qb.SelectExcept(User{}, "password", "total").QueryAndScan(db, &user); err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != 1 || user.Username != "ricardo" || user.Password != "" {
+		t.Errorf("Unexpected user %+v", user)
+	}
+}
+
+func TestSubqueries(t *testing.T) {
+	Testing = false
+	sub := &QueryBuilder{}
+	sub.Select("user_id").From("orders").Where("total > $?", 100)
+	qb := QueryBuilder{}
+	qb.Select("id").From("users").Where("active = $?", true).Where("id IN $? AND age > $?", sub, 18)
+	expected := `SELECT id FROM users WHERE active = $1 AND id IN (SELECT user_id FROM orders WHERE total > $2) AND age > $3`
+	sql, args, err := qb.BuildWithArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if fmt.Sprint(args) != "[true 100 18]" {
+		t.Errorf("Unexpected args %v", args)
+	}
+
+	qb = QueryBuilder{SelectAlias: "t"}
+	qb.Select("t.user_id").From(sub).Where("t.user_id > $?", 5)
+	expected = `SELECT t.user_id FROM (SELECT user_id FROM orders WHERE total > $1) t WHERE t.user_id > $2`
+	sql, args, err = qb.BuildWithArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if fmt.Sprint(args) != "[100 5]" {
+		t.Errorf("Unexpected args %v", args)
+	}
+}
+
+func TestSelectOptions(t *testing.T) {
+	expected := `SELECT "u"."id","u"."username","u"."total" FROM user u`
+	qb := QueryBuilder{}
+	qb.Select(User{}, WithAlias("u"), IgnoreComputed(), Except("password"))
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+
+	expected = `SELECT "id","username" FROM users`
+	qb = QueryBuilder{}
+	qb.Select(User{}, Only("id", "username")).From("users")
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+}
+
+func TestSetOperations(t *testing.T) {
+	Testing = false
+	admins := &QueryBuilder{}
+	admins.Select("id").From("admins").Where("active = $?", true)
+	banned := &QueryBuilder{}
+	banned.Select("user_id").From("bans").Where("until > $?", 10)
+	qb := QueryBuilder{}
+	qb.Select("id").From("users").Where("age > $?", 18).
+		UnionAll(admins).
+		Except(banned).
+		OrderBy("id").
+		OrderByExpr("id > $?", 5)
+	expected := `SELECT id FROM users WHERE age > $1 UNION ALL SELECT id FROM admins WHERE active = $2 EXCEPT SELECT user_id FROM bans WHERE until > $3 ORDER BY id, id > $4`
+	sql, args, err := qb.BuildWithArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if fmt.Sprint(args) != "[18 true 10 5]" {
+		t.Errorf("Unexpected args %v", args)
+	}
+
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`INSERT INTO user(username) VALUES('a'), ('b'), ('c')`)
+	first := &QueryBuilder{}
+	first.Select("username").From("user").Where("id <= ?", 2)
+	second := &QueryBuilder{}
+	second.Select("username").From("user").Where("id >= ?", 2)
+	qb = QueryBuilder{}
+	qb.Select("username").From("user").Where("id >= ?", 1).Intersect(first).Union(second)
+	rows, err := qb.Query(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	names := []string{}
+	for rows.Next() {
+		var name string
+		rows.Scan(&name)
+		names = append(names, name)
+	}
+	if strings.Join(names, ",") != "a,b,c" {
+		t.Errorf("Unexpected results %v", names)
+	}
+}
+
+func TestWithCommonTableExpressions(t *testing.T) {
+	Testing = false
+	recent := &QueryBuilder{}
+	recent.Select("id").From("orders").Where("created > $?", 10)
+	qb := QueryBuilder{}
+	qb.With("recent", recent).Select("id").From("users").Where("id IN (SELECT id FROM recent) AND age > $?", 18)
+	expected := `WITH recent AS (SELECT id FROM orders WHERE created > $1) SELECT id FROM users WHERE id IN (SELECT id FROM recent) AND age > $2`
+	sql, args, err := qb.BuildWithArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if fmt.Sprint(args) != "[10 18]" {
+		t.Errorf("Unexpected args %v", args)
+	}
+
+	db := dbSetup()
+	defer db.Close()
+	base := &QueryBuilder{}
+	base.Select("1").From("(SELECT 1)")
+	step := &QueryBuilder{}
+	step.Select("n + 1").From("seq").Where("n < ?", 5)
+	qb = QueryBuilder{}
+	qb.WithRecursive("seq(n)", base.UnionAll(step)).Select("SUM(n)").From("seq")
+	var sum int
+	sql, args, err = qb.BuildWithArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow(sql, args...).Scan(&sum); err != nil {
+		t.Fatal(err)
+	}
+	if sum != 15 {
+		t.Errorf("Expected 15, got %d", sum)
+	}
+}
+
+type customUser struct {
+	ID       int64  `db:"id" pk:"true"`
+	Username string `db:"username"`
+}
+
+func (customUser) SelectColumns() []string {
+	return []string{"id", "upper(username) username"}
+}
+
+func (*customUser) DefaultOrder() string {
+	return "id DESC"
+}
+
+func TestModelInterfaces(t *testing.T) {
+	expected := `SELECT id,upper(username) username FROM user ORDER BY id DESC`
+	qb := QueryBuilder{}
+	qb.Select(customUser{}).From("user")
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	qb.OrderBy("username")
+	expected = `SELECT id,upper(username) username FROM user ORDER BY username`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`INSERT INTO user(username) VALUES('a'), ('b')`)
+	user := customUser{}
+	qb = QueryBuilder{}
+	if err := qb.Select(customUser{}).From("user").QueryAndScan(db, &user); err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != 2 || user.Username != "B" {
+		t.Errorf("Unexpected user %+v", user)
+	}
+}
+
+func TestOuterAndCrossJoins(t *testing.T) {
+	expected := `SELECT * FROM users u INNER JOIN a ON a.id = u.a_id LEFT JOIN b ON b.id = u.b_id RIGHT JOIN c ON c.id = u.c_id FULL OUTER JOIN d ON d.id = u.d_id CROSS JOIN e`
+	qb := QueryBuilder{}
+	qb.From("users u").
+		RightJoin("c ON c.id = u.c_id").
+		FullOuterJoin("d ON d.id = u.d_id").
+		CrossJoin("e").
+		InnerJoin("a ON a.id = u.a_id").
+		LeftJoin("b ON b.id = u.b_id")
+	if sql := strings.Replace(qb.Build(), "  ", " ", -1); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+}
+
+func TestEmulateFullJoins(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.Select("u.id", "o.id").From("users u").FullOuterJoin("orders o ON o.user_id = u.id").
+		Where("u.active = $?", true).OrderByExpr("u.id > $?", 3).Limit("10")
+	expected := `SELECT u.id,o.id FROM users u LEFT JOIN orders o ON o.user_id = u.id WHERE u.active = $1 UNION ALL SELECT u.id,o.id FROM users u RIGHT JOIN orders o ON o.user_id = u.id WHERE u.active = $2 AND u.id IS NULL ORDER BY u.id > $3 LIMIT 10`
+	sql, args, err := qb.emulateFullJoins().BuildWithArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if fmt.Sprint(args) != "[true true 3]" {
+		t.Errorf("Unexpected args %v", args)
+	}
+}
+
+func TestEmulateFullJoinsWithoutEquality(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.UseDialect(MySQL).Select("a.id").From("a").FullOuterJoin("b ON b.n > a.n")
+	expected := `SELECT a.id FROM a LEFT JOIN b ON b.n > a.n UNION SELECT a.id FROM a RIGHT JOIN b ON b.n > a.n`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+}
+
+func TestLegacySQLiteJoins(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`INSERT INTO user (id, username, password) VALUES (1, 'a', ''), (2, 'b', ''), (3, 'b', '')`)
+	db.Exec(`CREATE TABLE name (name VARCHAR(10))`)
+	db.Exec(`INSERT INTO name (name) VALUES ('b'), ('c')`)
+
+	qb := QueryBuilder{}
+	qb.UseDialect(LegacySQLite).Select("u.id", "n.name").From("user u").
+		RightJoin("name n ON n.name = u.username").Where("n.name <> $?", "x").OrderBy("n.name, u.id")
+	expected := `SELECT u.id,n.name FROM name n LEFT JOIN user u ON n.name = u.username WHERE n.name <> ? ORDER BY n.name, u.id`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	pairs := func(qb *QueryBuilder) string {
+		rows, err := qb.Query(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		result := []string{}
+		for rows.Next() {
+			var id sql.NullInt64
+			var name sql.NullString
+			rows.Scan(&id, &name)
+			result = append(result, fmt.Sprintf("%d:%s", id.Int64, name.String))
+		}
+		return strings.Join(result, ",")
+	}
+	if got := pairs(&qb); got != "2:b,3:b,0:c" {
+		t.Errorf("Unexpected rows %s", got)
+	}
+
+	// Both sides are kept and the duplicated rows are not removed
+	qb = QueryBuilder{}
+	qb.UseDialect(LegacySQLite).Select("u.username", "n.name").From("user u").
+		FullOuterJoin("name n ON n.name = u.username")
+	db.Exec(`INSERT INTO name (name) VALUES ('b')`)
+	rows, err := qb.Query(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	result := []string{}
+	for rows.Next() {
+		var username, name sql.NullString
+		rows.Scan(&username, &name)
+		result = append(result, username.String+":"+name.String)
+	}
+	sort.Strings(result)
+	if got := strings.Join(result, ","); got != ":c,a:,b:b,b:b,b:b,b:b" {
+		t.Errorf("Unexpected rows %s", got)
+	}
+}
+
+func TestJoinsWithValues(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.Select("u.id").From("users u").
+		RightJoin("c ON c.id = u.c_id AND c.kind = $?", "x").
+		LeftJoin("b ON b.id = u.b_id AND b.size > $?", 2).
+		InnerJoin("orders o ON o.user_id = u.id AND o.status = $?", "paid").
+		Where("u.active = $?", true)
+	expected := `SELECT u.id FROM users u INNER JOIN orders o ON o.user_id = u.id AND o.status = $1 LEFT JOIN b ON b.id = u.b_id AND b.size > $2 RIGHT JOIN c ON c.id = u.c_id AND c.kind = $3 WHERE u.active = $4`
+	sql, args, err := qb.BuildWithArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if fmt.Sprint(args) != "[paid 2 x true]" {
+		t.Errorf("Unexpected args %v", args)
+	}
+}
+
+func TestLimitAndOffset(t *testing.T) {
+	qb := QueryBuilder{}
+	qb.Select("id").From("users").Limit(10).Offset(20)
+	expected := `SELECT id FROM users LIMIT 10 OFFSET 20`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	qb.Limit("5")
+	expected = `SELECT id FROM users LIMIT 5 OFFSET 20`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+
+	qb = QueryBuilder{}
+	qb.Select("id").From("users").Limit(10).Offset(-1)
+	if _, _, err := qb.BuildWithArgs(); err == nil {
+		t.Error("Expected an error for a negative offset")
+	}
+
+	type pageSize uint8
+	for _, limit := range []interface{}{int64(7), uint(7), int32(7), pageSize(7)} {
+		qb = QueryBuilder{}
+		qb.Select("id").From("users").Limit(limit)
+		if sql, _, err := qb.BuildWithArgs(); err != nil || sql != `SELECT id FROM users LIMIT 7` {
+			t.Errorf("Unexpected SQL for %T: %s %v", limit, sql, err)
+		}
+	}
+
+	// Build can't return the error so it's logged
+	out := bytes.Buffer{}
+	log.SetOutput(&out)
+	defer log.SetOutput(os.Stderr)
+	qb = QueryBuilder{}
+	qb.Select("id").From("users").Limit(7.5)
+	qb.Build()
+	if !strings.Contains(out.String(), "Limit: unsupported type float64") {
+		t.Errorf("Expected the error to be logged, got %q", out.String())
+	}
+}
+
+func TestBuildCountWithLimit(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.Select("id").From("users").Where("active = $?", true).Limit(5000)
+	expected := `SELECT COUNT(*) FROM (SELECT * FROM (SELECT id FROM users WHERE active = $1 LIMIT 5000) limited LIMIT 1000) counted`
+	if sql := qb.BuildCountWithLimit(1000); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+
+	qb = QueryBuilder{}
+	qb.UseDialect(MSSQL).Select("id").From("users").Where("active = $?", true)
+	expected = `SELECT COUNT(*) FROM (SELECT TOP 1000 * FROM (SELECT id FROM users WHERE active = @p1) limited) counted`
+	if sql := qb.BuildCountWithLimit(1000); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	qb = QueryBuilder{}
+	qb.UseDialect(MySQL).Select("id").From("users").Offset(10)
+	expected = "SELECT COUNT(*) FROM (SELECT * FROM (SELECT id FROM users LIMIT 10, 18446744073709551615) limited LIMIT 1000) counted"
+	if sql := qb.BuildCountWithLimit(1000); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`INSERT INTO user(username) VALUES('a'), ('b'), ('c'), ('d')`)
+	qb = QueryBuilder{}
+	qb.Select("id").From("user").Where("id > ?", 1).Limit(10)
+	var count int
+	if err := db.QueryRow(qb.BuildCountWithLimit(2), qb.GetValues()...).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2, got %d", count)
+	}
+	if err := db.QueryRow(qb.BuildCountWithLimit(0), qb.GetValues()...).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3, got %d", count)
+	}
+}
+
+func TestBuildCount(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.Select("id", Raw("total * $?", 2).As("double")).From("orders").Where("active = $?", true).
+		OrderBy(Raw("id + $?", 1)).Limit(10).Offset(20)
+	expected := `SELECT COUNT(*) FROM orders WHERE active = $1`
+	sql, vals, err := qb.BuildCountWithArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != expected || fmt.Sprint(vals) != "[true]" {
+		t.Errorf("Expected:\n%s\nGot:\n%s %v", expected, sql, vals)
+	}
+
+	qb = QueryBuilder{}
+	qb.Select("user_id").From("orders").Where("active = $?", true).GroupBy("user_id").
+		Having("SUM(total) > $?", 100).OrderBy("user_id").Limit(10)
+	expected = `SELECT COUNT(*) FROM (SELECT user_id FROM orders WHERE active = $1 GROUP BY user_id HAVING SUM(total) > $2) counted`
+	sql, vals, err = qb.BuildCountWithArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != expected || fmt.Sprint(vals) != "[true 100]" {
+		t.Errorf("Expected:\n%s\nGot:\n%s %v", expected, sql, vals)
+	}
+
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`INSERT INTO user(username, password) VALUES('a', 'x'), ('b', 'x'), ('c', 'y'), ('d', 'z')`)
+	qb = QueryBuilder{}
+	qb.Select("password").From("user").GroupBy("password").OrderBy("password").Limit(1)
+	count, err := qb.Count(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 groups, got %d", count)
+	}
+	qb = QueryBuilder{}
+	qb.Select("password").Distinct().From("user").Where("username != ?", "d")
+	if count, err = qb.Count(db); err != nil || count != 2 {
+		t.Errorf("Expected 2 distinct rows, got %d %v", count, err)
+	}
+	qb = QueryBuilder{}
+	qb.Select("id").From("user").Limit(2)
+	if count, err = qb.Count(db); err != nil || count != 4 {
+		t.Errorf("Expected 4 rows, got %d %v", count, err)
+	}
+}
+
+func TestExists(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.Select("id").From("users").Where("email = $?", "a@b.com")
+	for exists, expected := range map[bool]string{
+		true:  `SELECT EXISTS(SELECT id FROM users WHERE email = $1)`,
+		false: `SELECT 1 FROM (SELECT id FROM users WHERE email = $1) probe LIMIT 1`,
+	} {
+		sql, vals, err := qb.buildExists(exists)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sql != expected || len(vals) != 1 {
+			t.Errorf("Expected:\n%s\nGot:\n%s %v", expected, sql, vals)
+		}
+	}
+
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`INSERT INTO user(username, password) VALUES('john', 'doe')`)
+	for username, expected := range map[string]bool{"john": true, "jane": false} {
+		qb = QueryBuilder{}
+		found, err := qb.Select("id").From("user").Where("username = ?", username).Exists(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found != expected {
+			t.Errorf("%s: expected %v, got %v", username, expected, found)
+		}
+	}
+}
+
+type userTotal struct {
+	UserID int64   `db:"user_id"`
+	Status string  `db:"status"`
+	Total  float64 `db:"total" sql:"SUM(o.amount)"`
+}
+
+func TestGroupByStruct(t *testing.T) {
+	expected := `SELECT "o"."user_id","o"."status",(SUM(o.amount)) "total" FROM orders o GROUP BY "o"."user_id", "o"."status"`
+	qb := QueryBuilder{}
+	qb.Select(userTotal{}, WithAlias("o")).From("orders").GroupByStruct(userTotal{})
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+}
+
+type orderTotal struct {
+	UserID int64   `db:"user_id"`
+	Total  float64 `db:"total" sql:"SUM(amount * $rate) + $fee"`
+}
+
+func TestBindNamedComputedColumns(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.Select(orderTotal{}).From("orders").Where("status = $?", "paid").GroupByStruct(orderTotal{}).
+		BindNamed("rate", 1.5).BindNamed("fee", 2)
+	expected := `SELECT "user_id",(SUM(amount * $1) + $2) "total" FROM orders WHERE status = $3 GROUP BY "user_id"`
+	sql, args, err := qb.BuildWithArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if fmt.Sprint(args) != "[1.5 2 paid]" {
+		t.Errorf("Unexpected args %v", args)
+	}
+
+	qb = QueryBuilder{}
+	qb.BindNamed("rate", 1.5).Select(orderTotal{})
+	if _, _, err := qb.BuildWithArgs(); err == nil || !strings.Contains(err.Error(), "$fee") {
+		t.Errorf("Expected an error for the unbound $fee, got %v", err)
+	}
+}
+
+func TestDistinct(t *testing.T) {
+	qb := QueryBuilder{}
+	qb.Select("user_id", "status").From("orders").Distinct()
+	expected := `SELECT DISTINCT user_id,status FROM orders`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+
+	qb = QueryBuilder{}
+	qb.Select("user_id", "created").From("orders").DistinctOn("user_id").OrderBy("user_id, created DESC")
+	expected = `SELECT DISTINCT ON (user_id) user_id,created FROM orders ORDER BY user_id, created DESC`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+}
+
+func TestTypedOrderBy(t *testing.T) {
+	qb := QueryBuilder{}
+	qb.Select("id").From("users u").OrderByDesc("u.created", NullsLast()).OrderBy("random()").OrderByAsc("id")
+	expected := `SELECT id FROM users u ORDER BY u.created DESC NULLS LAST, random(), id ASC`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+
+	emulated := orderTerm{col: "name", nulls: "LAST"}
+	if sql := emulated.build(true); sql != "name IS NULL, name ASC" {
+		t.Errorf("Unexpected emulation %s", sql)
+	}
+	emulated = orderTerm{col: "name", desc: true, nulls: "FIRST"}
+	if sql := emulated.build(true); sql != "name IS NULL DESC, name DESC" {
+		t.Errorf("Unexpected emulation %s", sql)
+	}
+
+	qb = QueryBuilder{}
+	qb.Select("id").From("users").OrderByAsc("id; DROP TABLE users")
+	if _, _, err := qb.BuildWithArgs(); err == nil {
+		t.Error("Expected an error for an invalid column")
+	}
+}
+
+func TestHavingWithValues(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.Select("user_id").From("orders").GroupBy("user_id").
+		Having("SUM(total) > $?", 100).
+		Where("status = $?", "paid").
+		HavingCount(">", 2)
+	expected := `SELECT user_id FROM orders WHERE status = $1 GROUP BY user_id HAVING SUM(total) > $2 AND COUNT(*) > $3`
+	sql, args, err := qb.BuildWithArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if fmt.Sprint(args) != "[paid 100 2]" {
+		t.Errorf("Unexpected args %v", args)
+	}
+}
+
+func TestNullsOrderingEmulation(t *testing.T) {
+	qb := QueryBuilder{}
+	qb.Select("id").From("users").OrderBy("lower(name) DESC NULLS FIRST, id").OrderByAsc("email", NullsLast())
+	expected := `ORDER BY lower(name) DESC NULLS FIRST, id, email ASC NULLS LAST`
+	if sql := qb.buildOrderBy(false); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	expected = `ORDER BY lower(name) IS NULL DESC, lower(name) DESC, id, email IS NULL, email ASC`
+	if sql := qb.buildOrderBy(true); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`INSERT INTO user(username) VALUES('b'), (NULL), ('a')`)
+	qb = QueryBuilder{}
+	qb.Select("id").From("user").OrderBy("username NULLS LAST")
+	rows, err := db.Query("SELECT id FROM user " + qb.buildOrderBy(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		rows.Scan(&id)
+		ids = append(ids, id)
+	}
+	if fmt.Sprint(ids) != "[3 1 2]" {
+		t.Errorf("Unexpected order %v", ids)
+	}
+}
+
+func TestConditionHelpers(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.Select("id").From("users").WhereNull("deleted_at").WhereNotNull("email").
+		WhereBetween("age", 18, 65).WhereLike("name", EscapeLike("50%_off")+"%").WhereILike("email", "%@example.com")
+	expected := `SELECT id FROM users WHERE deleted_at IS NULL AND email IS NOT NULL AND age BETWEEN $1 AND $2 AND name LIKE $3 ESCAPE '\' AND email ILIKE $4 ESCAPE '\'`
+	sql, vals, err := qb.BuildWithArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if fmt.Sprint(vals) != `[18 65 50\%\_off% %@example.com]` {
+		t.Errorf("Unexpected values %v", vals)
+	}
+
+	qb = QueryBuilder{}
+	qb.UseDialect(MySQL).Select("id").From("users").WhereILike("email", "%@example.com")
+	expected = "SELECT id FROM users WHERE lower(email) LIKE lower(?) ESCAPE '\\\\'"
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`INSERT INTO user(username, password) VALUES('50%_off', 'x'), ('50 off', 'x'), ('A_B', NULL)`)
+	cases := map[*QueryBuilder]int{
+		(&QueryBuilder{}).Select("id").From("user").WhereLike("username", EscapeLike("50%_")+"%"): 1,
+		(&QueryBuilder{}).Select("id").From("user").WhereLike("username", "50%"):                  2,
+		(&QueryBuilder{}).Select("id").From("user").WhereILike("username", EscapeLike("a_")+"%"):  1,
+		(&QueryBuilder{}).Select("id").From("user").WhereNull("password"):                         1,
+		(&QueryBuilder{}).Select("id").From("user").WhereBetween("id", 2, 3):                      2,
+	}
+	for qb, expected := range cases {
+		count, err := qb.Count(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int(count) != expected {
+			t.Errorf("%s: expected %d rows, got %d", qb.Build(), expected, count)
+		}
+	}
+}
+
+func TestWhereEqNullSafe(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.Select("t.id").From("target t").InnerJoin("source s ON s.id = t.id").WhereEqNullSafe("t.email", Col("s.email")).WhereEqNullSafe("t.phone", nil)
+	expected := `SELECT t.id FROM target t INNER JOIN source s ON s.id = t.id WHERE t.email IS NOT DISTINCT FROM "s"."email" AND t.phone IS NOT DISTINCT FROM $1`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	qb = QueryBuilder{}
+	qb.UseDialect(MySQL).Select("id").From("users").WhereEqNullSafe("email", "a@b.c")
+	if sql := qb.Build(); sql != "SELECT id FROM users WHERE email <=> ?" {
+		t.Errorf("Unexpected query %s", sql)
+	}
+
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`INSERT INTO user(username, password) VALUES('john', 'x'), ('jane', NULL)`)
+	for val, expected := range map[interface{}]int64{nil: 1, "x": 1, "y": 0} {
+		count, err := (&QueryBuilder{}).Select("id").From("user").WhereEqNullSafe("password", val).Count(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != expected {
+			t.Errorf("%v: expected %d rows, got %d", val, expected, count)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// Package goqltest provides helpers for testing code that uses goql
+// against a real database.
+package goqltest
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/rgamba/goql"
+)
+
+// WithRollback runs fn inside a transaction that is always rolled back
+// once fn returns, so tests can write to the database without having to
+// clean up or truncate tables afterwards.
+// Code under test that calls goql.WithTx with the given Queryer is nested
+// using savepoints, so its own commits and rollbacks keep working.
+func WithRollback(t testing.TB, db *sql.DB, fn func(tx goql.Queryer)) {
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("goqltest: unable to begin transaction: %s", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			t.Errorf("goqltest: unable to rollback transaction: %s", err)
+		}
+	}()
+	fn(tx)
+}
+
+// seqScan matches the steps of a plan that read a whole table, in the
+// output of Postgres, SQLite and MySQL.
+var seqScan = regexp.MustCompile(`(?im)Seq Scan|\bSCAN (TABLE )?[^\s]+\s*$|Table scan`)
+
+// AssertUsesIndex fails the test when the plan of qb, as returned by
+// Explain, doesn't use index or reads a whole table, which guards the
+// critical queries against the changes that make them skip their index:
+// goqltest.AssertUsesIndex(t, db, qb, "idx_users_email")
+func AssertUsesIndex(t testing.TB, db goql.Queryer, qb *goql.QueryBuilder, index string) {
+	plan, err := qb.Explain(db)
+	if err != nil {
+		t.Fatalf("goqltest: unable to explain the query: %s", err)
+	}
+	if !strings.Contains(plan, index) {
+		t.Errorf("goqltest: the query doesn't use %s, plan:\n%s", index, plan)
+	}
+	if scan := seqScan.FindString(plan); len(scan) > 0 {
+		t.Errorf("goqltest: the query reads a whole table (%s), plan:\n%s", strings.TrimSpace(scan), plan)
+	}
+}
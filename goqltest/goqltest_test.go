@@ -0,0 +1,97 @@
+package goqltest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/rgamba/goql"
+)
+
+type User struct {
+	ID       int64  `db:"id" pk:"true"`
+	Username string `db:"username"`
+}
+
+func dbSetup(t *testing.T) *sql.DB {
+	goql.Testing = true
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	db.Exec(`CREATE TABLE user(id INTEGER PRIMARY KEY AUTOINCREMENT, username CHAR(255))`)
+	return db
+}
+
+func count(db goql.Queryer) (total int) {
+	db.QueryRowContext(context.Background(), "SELECT COUNT(*) FROM user").Scan(&total)
+	return
+}
+
+func TestWithRollback(t *testing.T) {
+	db := dbSetup(t)
+	defer db.Close()
+
+	WithRollback(t, db, func(tx goql.Queryer) {
+		if _, err := goql.Insert(tx, "user", User{Username: "john"}); err != nil {
+			t.Fatal(err)
+		}
+		// Nested transactions are turned into savepoints
+		err := goql.WithTx(tx, func(tx *sql.Tx) error {
+			goql.Insert(tx, "user", User{Username: "jane"})
+			return errors.New("rollback jane")
+		})
+		if err == nil {
+			t.Error("Expected WithTx to return the error")
+		}
+		if total := count(tx); total != 1 {
+			t.Errorf("Expected 1 row inside the transaction, got %d", total)
+		}
+	})
+
+	if total := count(db); total != 0 {
+		t.Errorf("Expected the transaction to be rolled back, got %d rows", total)
+	}
+}
+
+// recorder records the failures of a test instead of failing it.
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+}
+
+func TestAssertUsesIndex(t *testing.T) {
+	db := dbSetup(t)
+	defer db.Close()
+	if _, err := db.Exec(`CREATE INDEX idx_user_username ON user(username)`); err != nil {
+		t.Fatal(err)
+	}
+
+	qb := &goql.QueryBuilder{}
+	qb.Select(User{}).Where("username = ?", "john")
+	r := &recorder{TB: t}
+	AssertUsesIndex(r, db, qb, "idx_user_username")
+	if len(r.failures) > 0 {
+		t.Errorf("Unexpected failures %v", r.failures)
+	}
+
+	qb = &goql.QueryBuilder{}
+	qb.Select(User{}).Where("username LIKE ?", "%john")
+	r = &recorder{TB: t}
+	AssertUsesIndex(r, db, qb, "idx_user_username")
+	if len(r.failures) != 2 {
+		t.Errorf("Expected the missing index and the scan to be reported, got %v", r.failures)
+	}
+}
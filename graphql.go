@@ -0,0 +1,113 @@
+package goql
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// SelectFields returns a QueryBuilder that selects only the columns of
+// obj backing the requested GraphQL fields, so resolvers don't load
+// columns the client didn't ask for. A field is matched by its "graphql"
+// tag, its "json" tag or its name in lower camel case, in that order.
+// Nested selections, such as "author.name", are matched through the
+// fields tagged with join, whose tables are joined as Select does, for
+// example with
+//
+//	type Article struct {
+//		ID     int64  `db:"id" pk:"true"`
+//		Title  string `db:"title"`
+//		Author Author `join:"author,author_id"`
+//	}
+//
+// SelectFields(Article{}, []string{"title", "author.name"}) selects the
+// title of the article and the name of its author, and the author table
+// is only joined when one of its fields is requested. Primary keys are
+// always selected and __typename is ignored. Unknown fields are rejected.
+func SelectFields(obj interface{}, fields []string) (*QueryBuilder, error) {
+	t := reflect.TypeOf(obj)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("goql: obj must be a struct")
+	}
+
+	wanted := map[string]bool{}
+	for _, name := range fields {
+		if name == "__typename" || strings.HasSuffix(name, ".__typename") {
+			continue
+		}
+		path, err := graphQLPath(t, name)
+		if err != nil {
+			return nil, err
+		}
+		// The join fields leading to the field are selected too
+		for i := range path {
+			if path[i] == '.' {
+				wanted[path[:i]] = true
+			}
+		}
+		wanted[path] = true
+	}
+
+	include := func(path string, field reflect.StructField) bool {
+		return wanted[path+field.Name] || len(field.Tag.Get("pk")) > 0
+	}
+	qb := &QueryBuilder{}
+	qb.selectStruct(t, selectOptions{setFrom: true, includeJoined: include, include: func(_ reflect.Type, field reflect.StructField) bool {
+		return include("", field)
+	}})
+	return qb, nil
+}
+
+// graphQLPath returns the path of the struct field of t backing the
+// GraphQL field name, such as "Author.Name" for "author.name".
+func graphQLPath(t reflect.Type, name string) (string, error) {
+	parts := strings.SplitN(name, ".", 2)
+	for i := 0; i <= t.NumField()-1; i++ {
+		field := t.Field(i)
+		if graphQLName(field) != parts[0] {
+			continue
+		}
+		if len(parts) == 1 && len(columnName(t, field)) > 0 {
+			return field.Name, nil
+		}
+		if len(parts) == 2 && len(field.Tag.Get("join")) > 0 {
+			nested, err := graphQLPath(field.Type, parts[1])
+			if err != nil {
+				return "", fmt.Errorf("goql: unknown field %q", name)
+			}
+			return field.Name + "." + nested, nil
+		}
+	}
+	return "", fmt.Errorf("goql: unknown field %q", name)
+}
+
+// graphQLName returns the name of the GraphQL field backed by field.
+func graphQLName(field reflect.StructField) string {
+	if name := field.Tag.Get("graphql"); len(name) > 0 {
+		return name
+	}
+	if name := strings.Split(field.Tag.Get("json"), ",")[0]; len(name) > 0 && name != "-" {
+		return name
+	}
+	return lowerCamel(field.Name)
+}
+
+// lowerCamel lowers the leading upper case letters of name so that
+// "Name" becomes "name", "ID" becomes "id" and "URLPath" becomes "urlPath".
+func lowerCamel(name string) string {
+	runes := []rune(name)
+	for i := range runes {
+		if !unicode.IsUpper(runes[i]) {
+			break
+		}
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
@@ -0,0 +1,80 @@
+package goql
+
+import (
+	"testing"
+)
+
+type Article struct {
+	ID        int64  `db:"id" pk:"true"`
+	Title     string `db:"title"`
+	Body      string `db:"body" json:"content"`
+	AuthorID  int64  `db:"author_id"`
+	SourceURL string `db:"source_url" graphql:"source"`
+}
+
+func TestSelectFields(t *testing.T) {
+	expected := `SELECT "id","body","source_url" FROM article`
+	qb, err := SelectFields(Article{}, []string{"__typename", "content", "source"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+}
+
+type Writer struct {
+	ID    int64  `db:"id" pk:"true"`
+	Name  string `db:"name"`
+	Email string `db:"email"`
+}
+
+type Post struct {
+	ID       int64  `db:"id" pk:"true"`
+	Title    string `db:"title"`
+	WriterID int64  `db:"writer_id"`
+	Writer   Writer `join:"writer,writer_id"`
+}
+
+func TestSelectFieldsNested(t *testing.T) {
+	Testing = false
+	qb, err := SelectFields(Post{}, []string{"title", "writer.name", "writer.__typename"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `SELECT "post"."id","post"."title","writer"."id" "writer_id","writer"."name" "writer_name" FROM post INNER JOIN writer "writer" ON "writer"."id" = "post"."writer_id"`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+
+	// The table is not joined when none of its fields is requested
+	qb, err = SelectFields(Post{}, []string{"title"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = `SELECT "id","title" FROM post`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+}
+
+func TestSelectFieldsRejectsUnknownFields(t *testing.T) {
+	for _, field := range []string{"password", "author.name", "title.length", "writer.password", "writer"} {
+		if _, err := SelectFields(Post{}, []string{"title", field}); err == nil {
+			t.Errorf("Expected an error selecting %s", field)
+		}
+	}
+	for _, field := range []string{"password", "author.name"} {
+		if _, err := SelectFields(Article{}, []string{"title", field}); err == nil {
+			t.Errorf("Expected an error selecting %s", field)
+		}
+	}
+}
+
+func TestLowerCamel(t *testing.T) {
+	for name, expected := range map[string]string{"Name": "name", "ID": "id", "URLPath": "urlPath", "AuthorID": "authorID"} {
+		if got := lowerCamel(name); got != expected {
+			t.Errorf("Expected %s for %s, got %s", expected, name, got)
+		}
+	}
+}
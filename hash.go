@@ -0,0 +1,97 @@
+package goql
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"math"
+	"time"
+)
+
+// ResultHash executes the query and returns a stable hash of its result,
+// see HashRows.
+func (qb *QueryBuilder) ResultHash(db Queryer) (string, error) {
+	rows, err := qb.QueryContext(context.Background(), db)
+	if err != nil {
+		return "", err
+	}
+	return HashRows(rows)
+}
+
+// HashRows computes a stable SHA-256 hash of the column names and values
+// of rows, reading them one at a time so the result is never held in
+// memory. The hash only changes when the result changes, which makes it
+// suitable as an HTTP ETag or for change detection. Note the rows must be
+// ordered for the hash to be stable. The rows are closed once read.
+func HashRows(rows *sql.Rows) (string, error) {
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, col := range cols {
+		writeHashValue(h, col)
+	}
+
+	vals := make([]interface{}, len(cols))
+	pointers := make([]interface{}, len(cols))
+	for i := range vals {
+		pointers[i] = &vals[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return "", err
+		}
+		h.Write([]byte{'r'})
+		for _, v := range vals {
+			writeHashValue(h, v)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeHashValue writes v prefixed with its type and length so that
+// different sequences of values never produce the same input.
+func writeHashValue(h hash.Hash, v interface{}) {
+	var kind byte
+	var data []byte
+	switch val := v.(type) {
+	case nil:
+		kind = 'n'
+	case int64:
+		kind = 'i'
+		data = make([]byte, 8)
+		binary.BigEndian.PutUint64(data, uint64(val))
+	case float64:
+		kind = 'f'
+		data = make([]byte, 8)
+		binary.BigEndian.PutUint64(data, math.Float64bits(val))
+	case bool:
+		kind = 'b'
+		data = []byte{0}
+		if val {
+			data[0] = 1
+		}
+	case []byte:
+		kind, data = 'x', val
+	case string:
+		kind, data = 's', []byte(val)
+	case time.Time:
+		kind, data = 't', []byte(val.UTC().Format(time.RFC3339Nano))
+	default:
+		kind, data = 'v', []byte(fmt.Sprintf("%v", val))
+	}
+	size := make([]byte, 8)
+	binary.BigEndian.PutUint64(size, uint64(len(data)))
+	h.Write([]byte{kind})
+	h.Write(size)
+	h.Write(data)
+}
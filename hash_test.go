@@ -0,0 +1,26 @@
+package goql
+
+import (
+	"testing"
+)
+
+func TestResultHash(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	Insert(db, "user", User{Username: "john", Password: "a"})
+
+	qb := QueryBuilder{}
+	qb.Select("id, username, password").From("user").OrderBy("id")
+	first, err := qb.ResultHash(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second, _ := qb.ResultHash(db); second != first {
+		t.Error("Expected the hash to be stable for the same result")
+	}
+
+	Update(db, "user", User{ID: 1, Username: "john", Password: "b"})
+	if changed, _ := qb.ResultHash(db); changed == first {
+		t.Error("Expected the hash to change when the result changes")
+	}
+}
@@ -0,0 +1,50 @@
+package goql
+
+import (
+	"context"
+	"time"
+)
+
+// HealthCheckTimeout is the longest HealthCheck waits for the database
+// to answer, ctx can still set a shorter deadline.
+var HealthCheckTimeout = 2 * time.Second
+
+// HealthQuerier can be implemented by dialects whose databases can't
+// run the default health check query, SELECT 1.
+type HealthQuerier interface {
+	HealthQuery() string
+}
+
+// HealthStatus is the result of HealthCheck.
+type HealthStatus struct {
+	// Healthy tells whether the database answered the query in time
+	Healthy bool
+	// Latency is the time the database took to answer
+	Latency time.Duration
+	// Err is the reason the database is not healthy, nil otherwise
+	Err error
+}
+
+// HealthCheck runs a cheap query on db to tell whether the database is
+// ready to serve queries, so every service can wire it the same way
+// into its readiness endpoint, for example
+//
+//	status := goql.HealthCheck(r.Context(), db)
+//	if !status.Healthy {
+//		http.Error(w, status.Err.Error(), http.StatusServiceUnavailable)
+//	}
+//
+// The query is the one of the dialect set with SetDialect.
+func HealthCheck(ctx context.Context, db Queryer) HealthStatus {
+	qry := "SELECT 1"
+	if querier, ok := activeDialect().(HealthQuerier); ok {
+		qry = querier.HealthQuery()
+	}
+	ctx, cancel := context.WithTimeout(ctx, HealthCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	var one int
+	err := queryRow(ctx, db, qry).Scan(&one)
+	return HealthStatus{Healthy: err == nil, Latency: time.Since(start), Err: err}
+}
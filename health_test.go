@@ -0,0 +1,17 @@
+package goql
+
+import (
+	"context"
+	"testing"
+)
+
+func TestHealthCheck(t *testing.T) {
+	db := dbSetup()
+	if status := HealthCheck(context.Background(), db); !status.Healthy || status.Err != nil {
+		t.Errorf("Expected a healthy database, got %+v", status)
+	}
+	db.Close()
+	if status := HealthCheck(context.Background(), db); status.Healthy || status.Err == nil {
+		t.Errorf("Expected an unhealthy database, got %+v", status)
+	}
+}
@@ -0,0 +1,110 @@
+package goql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// indexHintStyle is the way a database is told which indexes to use.
+type indexHintStyle int
+
+const (
+	// hintPgHintPlan renders the hints as a pg_hint_plan comment, which
+	// Postgres ignores unless the extension is loaded
+	hintPgHintPlan indexHintStyle = iota
+	// hintMySQL renders USE, FORCE and IGNORE INDEX after the table
+	hintMySQL
+	// hintSQLite renders INDEXED BY for a forced index and ignores the
+	// rest of the hints
+	hintSQLite
+)
+
+// indexHint is a hint set with UseIndex, ForceIndex or IgnoreIndex.
+type indexHint struct {
+	kind    string
+	indexes []string
+}
+
+// UseIndex hints the planner to pick one of indexes to read the table of
+// the FROM clause, for the performance critical queries that the planner
+// gets wrong, for example
+// queryBuilder.Select("id").From("orders").UseIndex("orders_user_id_idx")
+// generates SELECT id FROM orders USE INDEX (orders_user_id_idx) in
+// MySQL and adds the pg_hint_plan comment /*+ IndexScan(orders orders_user_id_idx) */
+// to the start of the query in Postgres, which is a no-op unless the
+// extension is loaded. SQLite ignores it.
+func (qb *QueryBuilder) UseIndex(indexes ...string) *QueryBuilder {
+	return qb.addIndexHint("USE", indexes)
+}
+
+// ForceIndex is the same as UseIndex but a table scan is only used when
+// none of indexes can be. It's rendered as FORCE INDEX in MySQL and as
+// INDEXED BY in SQLite, which only takes one index and fails when it
+// can't be used.
+func (qb *QueryBuilder) ForceIndex(indexes ...string) *QueryBuilder {
+	return qb.addIndexHint("FORCE", indexes)
+}
+
+// IgnoreIndex hints the planner not to use indexes, see UseIndex. It's
+// rendered as IGNORE INDEX in MySQL and ignored by Postgres and SQLite,
+// which can't leave out a given index.
+func (qb *QueryBuilder) IgnoreIndex(indexes ...string) *QueryBuilder {
+	return qb.addIndexHint("IGNORE", indexes)
+}
+
+func (qb *QueryBuilder) addIndexHint(kind string, indexes []string) (ret *QueryBuilder) {
+	ret = qb
+	qb.invalidate()
+	if len(indexes) <= 0 {
+		panic(kind + " INDEX needs at least one index")
+	}
+	for _, index := range indexes {
+		if !identifierPattern.MatchString(index) {
+			panic(fmt.Sprintf("Invalid index %q", index))
+		}
+	}
+	qb.indexHints = append(qb.indexHints, indexHint{kind: kind, indexes: indexes})
+	return
+}
+
+// buildIndexHints returns the hints rendered after the table of the FROM
+// clause.
+func (qb *QueryBuilder) buildIndexHints() string {
+	parts := []string{}
+	for _, hint := range qb.indexHints {
+		switch featuresOf(qb.getDialect()).indexHints {
+		case hintMySQL:
+			parts = append(parts, hint.kind+" INDEX ("+strings.Join(hint.indexes, ", ")+")")
+		case hintSQLite:
+			if hint.kind == "FORCE" {
+				parts = append(parts, "INDEXED BY "+hint.indexes[0])
+			}
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// buildHintComment returns the pg_hint_plan comment that starts the
+// query in Postgres.
+func (qb *QueryBuilder) buildHintComment() string {
+	if featuresOf(qb.getDialect()).indexHints != hintPgHintPlan {
+		return ""
+	}
+	table := qb.fromAlias
+	if len(table) <= 0 {
+		table = qb.SelectAlias
+	}
+	if len(table) <= 0 {
+		table = qb.from
+	}
+	hints := []string{}
+	for _, hint := range qb.indexHints {
+		if hint.kind != "IGNORE" {
+			hints = append(hints, "IndexScan("+table+" "+strings.Join(hint.indexes, " ")+")")
+		}
+	}
+	if len(hints) <= 0 {
+		return ""
+	}
+	return "/*+ " + strings.Join(hints, " ") + " */"
+}
@@ -0,0 +1,60 @@
+package goql
+
+import (
+	"testing"
+)
+
+func TestIndexHints(t *testing.T) {
+	Testing = false
+	build := func(d Dialect) string {
+		qb := QueryBuilder{}
+		qb.UseDialect(d).Select("id").From("orders").UseIndex("orders_user_id_idx", "orders_created_idx").
+			IgnoreIndex("orders_status_idx").Where("user_id = $?", 1).ReadOnly()
+		sql, _, err := qb.BuildWithArgs()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sql
+	}
+	expected := `/*+ IndexScan(orders orders_user_id_idx orders_created_idx) */ SELECT id FROM orders WHERE user_id = $1`
+	if sql := build(Postgres); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	expected = `SELECT id FROM orders USE INDEX (orders_user_id_idx, orders_created_idx) IGNORE INDEX (orders_status_idx) WHERE user_id = ?`
+	if sql := build(MySQL); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	expected = `SELECT id FROM orders WHERE user_id = ?`
+	if sql := build(SQLite); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+
+	qb := QueryBuilder{}
+	qb.UseDialect(MySQL).Select(User{}, WithAlias("u")).ForceIndex("user_username_idx")
+	expected = "SELECT `u`.`id`,`u`.`username`,`u`.`password`,(COUNT(col)) `total` FROM user u FORCE INDEX (user_username_idx)"
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic for an invalid index")
+		}
+	}()
+	(&QueryBuilder{}).UseIndex("idx) WHERE (1")
+}
+
+func TestForceIndexSQLite(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`CREATE INDEX user_username_idx ON user (username)`)
+	db.Exec(`INSERT INTO user (username, password) VALUES ('john', 'x')`)
+	qb := QueryBuilder{}
+	qb.Select("id").From("user").ForceIndex("user_username_idx").Where("username = $?", "john")
+	if sql := qb.Build(); sql != `SELECT id FROM user INDEXED BY user_username_idx WHERE username = ?` {
+		t.Errorf("Unexpected query %s", sql)
+	}
+	if n, err := qb.Count(db); err != nil || n != 1 {
+		t.Errorf("Expected 1 row, got %d %v", n, err)
+	}
+}
@@ -0,0 +1,183 @@
+package goql
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"sync"
+)
+
+// StatementKind identifies the kind of a SQL statement.
+type StatementKind string
+
+// Statement kinds.
+const (
+	KindSelect StatementKind = "select"
+	KindInsert StatementKind = "insert"
+	KindUpdate StatementKind = "update"
+	KindDelete StatementKind = "delete"
+)
+
+// StatementEvent describes a statement run by the Insert, Update and
+// Delete helpers or by RawQuery.
+type StatementEvent struct {
+	Kind  StatementKind
+	Table string
+	Query string
+	Args  []interface{}
+	// Tag is the workload of the statement, as set with WithQueryTag in
+	// its context.
+	Tag string
+	// Err is the error returned by the database, it's only set for the
+	// hooks registered with AfterStatement.
+	Err error
+}
+
+// StatementHook is a callback run around statements.
+type StatementHook func(e *StatementEvent) error
+
+type statementHook struct {
+	kind  StatementKind
+	table string
+	fn    StatementHook
+}
+
+var (
+	hooksMu     sync.RWMutex
+	beforeHooks []statementHook
+	afterHooks  []statementHook
+)
+
+// BeforeStatement registers a hook run before every statement of the
+// given kind on the given table, an empty kind or table matches all of
+// them. When a hook returns an error the statement is not executed and
+// the error is returned to the caller.
+// Unlike model hooks these target statements, which makes them useful for
+// infrastructure concerns such as auditing every insert into a table.
+func BeforeStatement(kind StatementKind, table string, hook StatementHook) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	beforeHooks = append(beforeHooks, statementHook{kind, table, hook})
+}
+
+// AfterStatement registers a hook run after every statement of the given
+// kind on the given table, an empty kind or table matches all of them.
+// The hook receives the error of the statement, if any, and an error
+// returned by the hook is returned to the caller when the statement
+// itself succeeded.
+func AfterStatement(kind StatementKind, table string, hook StatementHook) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	afterHooks = append(afterHooks, statementHook{kind, table, hook})
+}
+
+func runStatementHooks(after bool, e *StatementEvent) error {
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+	hooks := beforeHooks
+	if after {
+		hooks = afterHooks
+	}
+	for _, h := range hooks {
+		if (h.kind == "" || h.kind == e.Kind) && (h.table == "" || h.table == e.Table) {
+			if err := h.fn(e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// execStatement executes a statement of the CRUD helpers running the
+// registered statement hooks around it.
+func execStatement(ctx context.Context, Db interface{}, kind StatementKind, table string, qry string, args []interface{}) (sql.Result, error) {
+	e := &StatementEvent{Kind: kind, Table: table, Query: qry, Args: args, Tag: QueryTagFrom(ctx)}
+	if err := runStatementHooks(false, e); err != nil {
+		return nil, err
+	}
+	result, err := toQueryer(Db).ExecContext(ctx, qry, args...)
+	e.Err = err
+	if hookErr := runStatementHooks(true, e); hookErr != nil && err == nil {
+		return result, hookErr
+	}
+	return result, canceled(ctx, err)
+}
+
+// queryRowStatement is the same as execStatement for statements that
+// return a row, which is scanned into dest.
+func queryRowStatement(ctx context.Context, Db interface{}, kind StatementKind, table string, qry string, args []interface{}, dest ...interface{}) error {
+	e := &StatementEvent{Kind: kind, Table: table, Query: qry, Args: args, Tag: QueryTagFrom(ctx)}
+	if err := runStatementHooks(false, e); err != nil {
+		return err
+	}
+	err := queryRow(ctx, toQueryer(Db), qry, args...).Scan(dest...)
+	e.Err = err
+	if hookErr := runStatementHooks(true, e); hookErr != nil && err == nil {
+		return hookErr
+	}
+	return err
+}
+
+// AfterScanner can be implemented by models to post-process each row
+// right after it's scanned, for example to compute derived fields.
+type AfterScanner interface {
+	AfterScan() error
+}
+
+// ScanHook is a callback run on each scanned row, obj is a pointer to
+// the struct.
+type ScanHook func(obj interface{}) error
+
+var (
+	scanHooksMu sync.RWMutex
+	scanHooks   = map[reflect.Type][]ScanHook{}
+)
+
+// AfterScan registers a hook run on every row of the type of model
+// scanned by QueryAndScan, ScanRow, RawQuery, Paginate and the rest of
+// the scanning helpers, for example to decrypt fields or normalize time
+// zones without repeating it in every handler:
+//
+//	goql.AfterScan(User{}, func(obj interface{}) error {
+//		u := obj.(*User)
+//		u.CreatedAt = u.CreatedAt.UTC()
+//		return nil
+//	})
+//
+// The hooks run in the order they were registered, after the AfterScan
+// method of the model if it implements AfterScanner, and the first error
+// is returned by the scan.
+func AfterScan(model interface{}, hook ScanHook) {
+	t := modelType(model)
+	scanHooksMu.Lock()
+	defer scanHooksMu.Unlock()
+	scanHooks[t] = append(scanHooks[t], hook)
+}
+
+// runScanHooks runs the scan hooks of obj, a pointer to a scanned
+// struct, once the ones of its nested join structs have run.
+func runScanHooks(obj interface{}) error {
+	v := reflect.ValueOf(obj).Elem()
+	t := v.Type()
+	for i := 0; i <= t.NumField()-1; i++ {
+		if len(t.Field(i).Tag.Get("join")) > 0 {
+			if err := runScanHooks(v.Field(i).Addr().Interface()); err != nil {
+				return err
+			}
+		}
+	}
+	if scanner, ok := obj.(AfterScanner); ok {
+		if err := scanner.AfterScan(); err != nil {
+			return err
+		}
+	}
+	scanHooksMu.RLock()
+	hooks := scanHooks[t]
+	scanHooksMu.RUnlock()
+	for _, hook := range hooks {
+		if err := hook(obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package goql
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func resetStatementHooks() {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	beforeHooks = nil
+	afterHooks = nil
+}
+
+func TestStatementHooks(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	defer resetStatementHooks()
+
+	events := []StatementKind{}
+	AfterStatement("", "user", func(e *StatementEvent) error {
+		events = append(events, e.Kind)
+		return e.Err
+	})
+	AfterStatement(KindDelete, "other", func(e *StatementEvent) error {
+		t.Error("Hook registered for another table was called")
+		return nil
+	})
+
+	Insert(db, "user", User{Username: "john"})
+	Update(db, "user", User{ID: 1, Username: "jane"})
+	Delete(db, "user", User{ID: 1})
+	if len(events) != 3 || events[0] != KindInsert || events[1] != KindUpdate || events[2] != KindDelete {
+		t.Errorf("Unexpected events %v", events)
+	}
+}
+
+func TestBeforeStatementHookAborts(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	defer resetStatementHooks()
+
+	expected := errors.New("read only")
+	BeforeStatement(KindInsert, "", func(e *StatementEvent) error {
+		return expected
+	})
+	if _, err := Insert(db, "user", User{Username: "john"}); err != expected {
+		t.Errorf("Expected %v, got %v", expected, err)
+	}
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM user").Scan(&count)
+	if count != 0 {
+		t.Error("Expected the insert to be aborted")
+	}
+}
+
+type scannedUser struct {
+	ID       int64  `db:"id" pk:"true"`
+	Username string `db:"username"`
+	Display  string
+}
+
+func (u *scannedUser) AfterScan() error {
+	u.Display = "@" + u.Username
+	return nil
+}
+
+func TestAfterScan(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`INSERT INTO user(username, password) VALUES('john', 'doe'), ('jane', 'doe')`)
+	AfterScan(scannedUser{}, func(obj interface{}) error {
+		u := obj.(*scannedUser)
+		u.Display = strings.ToUpper(u.Display)
+		return nil
+	})
+	defer func() { scanHooks = map[reflect.Type][]ScanHook{} }()
+
+	users := []scannedUser{}
+	if err := RawQuery(db, "SELECT id, username FROM user ORDER BY id").ScanAll(&users); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[0].Display != "@JOHN" || users[1].Display != "@JANE" {
+		t.Errorf("Expected the hooks to run on every row, got %v", users)
+	}
+
+	qb := QueryBuilder{}
+	qb.Select("id", "username").From("user").Where("username = ?", "jane")
+	user := scannedUser{}
+	if err := qb.QueryAndScan(db, &user); err != nil {
+		t.Fatal(err)
+	}
+	if user.Display != "@JANE" {
+		t.Errorf("Expected @JANE, got %q", user.Display)
+	}
+
+	AfterScan(&scannedUser{}, func(obj interface{}) error {
+		return errors.New("cannot decrypt")
+	})
+	if err := qb.QueryAndScan(db, &user); err == nil || err.Error() != "cannot decrypt" {
+		t.Errorf("Expected the hook error, got %v", err)
+	}
+}
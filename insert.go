@@ -0,0 +1,270 @@
+package goql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// InsertBuilder builds an INSERT statement whose conflicts can update
+// the existing row with expressions, for example to accumulate counters:
+//
+//	goql.InsertInto("counters").Values(Counter{ID: 1, Count: 5}).
+//		OnConflict("id").
+//		DoUpdate(goql.Assign("count", goql.Add(goql.Col("counters.count"), goql.Excluded("count"))))
+//
+// generates INSERT INTO counters ("id","count") VALUES($1,$2) ON CONFLICT ("id")
+// DO UPDATE SET "count" = "counters"."count" + EXCLUDED."count"
+// The statement is generated for the dialect set with SetDialect, or
+// with UseDialect, MySQL ignores the conflict columns and renders DO
+// UPDATE as ON DUPLICATE KEY UPDATE and Excluded as VALUES(col).
+type InsertBuilder struct {
+	dialect   Dialect
+	table     string
+	cols      []string
+	vals      []interface{}
+	conflict  []string
+	sets      []Assignment
+	doNothing bool
+	err       error
+}
+
+// Assignment is a column set by the update of a conflicting row.
+type Assignment struct {
+	col string
+	val interface{}
+}
+
+// Assign sets col to val, which is bound as a value unless it's an Expr.
+func Assign(col string, val interface{}) Assignment {
+	return Assignment{col: col, val: val}
+}
+
+// InsertInto starts an INSERT statement into table.
+func InsertInto(table string) *InsertBuilder {
+	return &InsertBuilder{table: table}
+}
+
+// UseDialect sets the dialect of the statement, overriding the one set
+// with SetDialect, see QueryBuilder.UseDialect.
+func (ib *InsertBuilder) UseDialect(d Dialect) *InsertBuilder {
+	ib.dialect = d
+	return ib
+}
+
+// getDialect returns the dialect the statement is generated for.
+func (ib *InsertBuilder) getDialect() Dialect {
+	if ib.dialect != nil {
+		return ib.dialect
+	}
+	return activeDialect()
+}
+
+// Set adds a column and its value to the inserted row, val is bound as
+// a value unless it's an Expr, such as Raw("now()").
+func (ib *InsertBuilder) Set(col string, val interface{}) *InsertBuilder {
+	ib.cols = append(ib.cols, col)
+	ib.vals = append(ib.vals, val)
+	return ib
+}
+
+// Values adds the db fields of the struct obj to the inserted row, as
+// Insert does, along with its primary keys when they are not zero.
+func (ib *InsertBuilder) Values(obj interface{}) *InsertBuilder {
+	queryInfo, err := creatQueryStructInfo(obj)
+	if err != nil {
+		ib.err = err
+		return ib
+	}
+	for i, col := range queryInfo.primaryKeyColumns {
+		if val := queryInfo.PrimaryKeyValues[i]; !isZero(val) {
+			ib.Set(col, val)
+		}
+	}
+	for i, col := range queryInfo.Fields {
+		ib.Set(col, queryInfo.Values[i])
+	}
+	return ib
+}
+
+// isZero tells whether val is nil or the zero value of its type.
+func isZero(val interface{}) bool {
+	v := reflect.ValueOf(val)
+	return !v.IsValid() || reflect.DeepEqual(val, reflect.Zero(v.Type()).Interface())
+}
+
+// OnConflict sets the columns of the unique constraint whose conflicts
+// are handled by DoUpdate or DoNothing.
+func (ib *InsertBuilder) OnConflict(cols ...string) *InsertBuilder {
+	ib.conflict = cols
+	return ib
+}
+
+// DoUpdate updates the conflicting row with sets.
+func (ib *InsertBuilder) DoUpdate(sets ...Assignment) *InsertBuilder {
+	ib.sets = append(ib.sets, sets...)
+	ib.doNothing = false
+	return ib
+}
+
+// DoNothing leaves the conflicting row as it is.
+func (ib *InsertBuilder) DoNothing() *InsertBuilder {
+	ib.sets = nil
+	ib.doNothing = true
+	return ib
+}
+
+// Build generates the SQL of the statement along with its values.
+func (ib *InsertBuilder) Build() (string, []interface{}, error) {
+	if ib.err != nil {
+		return "", nil, ib.err
+	}
+	if len(ib.cols) <= 0 {
+		return "", nil, errors.New("goql: the insert has no columns")
+	}
+	d := ib.getDialect()
+	style := featuresOf(d).upsert
+	mysqlStyle := style == upsertOnDuplicateKey
+	handlesConflicts := len(ib.sets) > 0 || ib.doNothing
+	if handlesConflicts && style == upsertUnsupported {
+		return "", nil, fmt.Errorf("goql: the %s dialect doesn't support handling conflicts", d.Name())
+	}
+	if handlesConflicts && len(ib.conflict) <= 0 && !mysqlStyle {
+		return "", nil, errors.New("goql: OnConflict must be set to handle conflicts")
+	}
+
+	cols := make([]string, len(ib.cols))
+	positions := make([]string, len(ib.cols))
+	vals := []interface{}{}
+	for i, col := range ib.cols {
+		cols[i] = d.QuoteIdent(col)
+		sql, colVals := exprValue(d, ib.vals[i])
+		positions[i] = sql
+		vals = append(vals, colVals...)
+	}
+	sets := make([]string, len(ib.sets))
+	for i, set := range ib.sets {
+		sql, setVals := exprValue(d, set.val)
+		sets[i] = d.QuoteIdent(set.col) + " = " + sql
+		vals = append(vals, setVals...)
+	}
+	conflict := make([]string, len(ib.conflict))
+	for i, col := range ib.conflict {
+		conflict[i] = d.QuoteIdent(col)
+	}
+
+	qry := fmt.Sprintf(`INSERT INTO %s (%s) VALUES(%s)`, ib.table, strings.Join(cols, ","), strings.Join(positions, ","))
+	switch {
+	case mysqlStyle && ib.doNothing:
+		qry += " ON DUPLICATE KEY UPDATE " + cols[0] + " = " + cols[0]
+	case mysqlStyle && len(sets) > 0:
+		qry += " ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+	case ib.doNothing:
+		qry += fmt.Sprintf(" ON CONFLICT (%s) DO NOTHING", strings.Join(conflict, ","))
+	case len(sets) > 0:
+		qry += fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(conflict, ","), strings.Join(sets, ", "))
+	}
+	return numberPlaceholders(d, qry, 1), vals, nil
+}
+
+// Exec runs the statement, Db can be a *sql.DB, a *sql.Tx or any other
+// Queryer.
+func (ib *InsertBuilder) Exec(Db interface{}) (sql.Result, error) {
+	return ib.ExecContext(context.Background(), Db)
+}
+
+// ExecContext is the same as Exec but the statement is canceled when
+// ctx is done.
+func (ib *InsertBuilder) ExecContext(ctx context.Context, Db interface{}) (sql.Result, error) {
+	qry, vals, err := ib.Build()
+	if err != nil {
+		return nil, err
+	}
+	return execStatement(ctx, Db, KindInsert, ib.table, qry, vals)
+}
+
+// numberPlaceholders replaces the $? placeholders of qry with the ones
+// of the dialect d numbered starting at start.
+func numberPlaceholders(d Dialect, qry string, start int) string {
+	for i := start; strings.Contains(qry, "$?"); i++ {
+		qry = strings.Replace(qry, "$?", d.Placeholder(i), 1)
+	}
+	return qry
+}
+
+// Col is a column, optionally qualified with its table, quoted with the
+// dialect of the statement it's used in, for example Col("counters.count").
+func Col(name string) Expr {
+	return colExpr(name)
+}
+
+type colExpr string
+
+// SQL implements Expr.
+func (c colExpr) SQL() (string, []interface{}) {
+	return c.dialectSQL(activeDialect())
+}
+
+func (c colExpr) dialectSQL(d Dialect) (string, []interface{}) {
+	parts := strings.Split(string(c), ".")
+	for i, part := range parts {
+		parts[i] = d.QuoteIdent(part)
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// Excluded is the value of col in the row that could not be inserted
+// because of a conflict, EXCLUDED.col in Postgres and VALUES(col) in
+// MySQL.
+func Excluded(col string) Expr {
+	return excludedExpr(col)
+}
+
+type excludedExpr string
+
+// SQL implements Expr.
+func (e excludedExpr) SQL() (string, []interface{}) {
+	return e.dialectSQL(activeDialect())
+}
+
+func (e excludedExpr) dialectSQL(d Dialect) (string, []interface{}) {
+	if featuresOf(d).upsert == upsertOnDuplicateKey {
+		return "VALUES(" + d.QuoteIdent(string(e)) + ")", nil
+	}
+	return "EXCLUDED." + d.QuoteIdent(string(e)), nil
+}
+
+// Add is the sum of vals, which are bound as values unless they are an
+// Expr, for example Add(Col("count"), Excluded("count")).
+func Add(vals ...interface{}) Expr {
+	return opExpr{op: " + ", vals: vals}
+}
+
+// Sub subtracts the rest of vals from the first one, see Add.
+func Sub(vals ...interface{}) Expr {
+	return opExpr{op: " - ", vals: vals}
+}
+
+type opExpr struct {
+	op   string
+	vals []interface{}
+}
+
+// SQL implements Expr.
+func (o opExpr) SQL() (string, []interface{}) {
+	return o.dialectSQL(activeDialect())
+}
+
+func (o opExpr) dialectSQL(d Dialect) (string, []interface{}) {
+	parts := make([]string, len(o.vals))
+	vals := []interface{}{}
+	for i, val := range o.vals {
+		sql, exprVals := exprValue(d, val)
+		parts[i] = sql
+		vals = append(vals, exprVals...)
+	}
+	return strings.Join(parts, o.op), vals
+}
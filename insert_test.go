@@ -0,0 +1,66 @@
+package goql
+
+import (
+	"fmt"
+	"testing"
+)
+
+type counter struct {
+	ID    int64 `db:"id" pk:"true"`
+	Count int   `db:"count"`
+}
+
+func TestInsertOnConflictDoUpdate(t *testing.T) {
+	Testing = false
+	ib := InsertInto("counters").Values(counter{ID: 1, Count: 5}).
+		OnConflict("id").
+		DoUpdate(Assign("count", Add(Col("counters.count"), Excluded("count"), 1)))
+	expected := `INSERT INTO counters ("id","count") VALUES($1,$2) ON CONFLICT ("id") DO UPDATE SET "count" = "counters"."count" + EXCLUDED."count" + $3`
+	sql, args, err := ib.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if fmt.Sprint(args) != "[1 5 1]" {
+		t.Errorf("Unexpected args %v", args)
+	}
+
+	SetDialect(MySQL)
+	defer SetDialect(Postgres)
+	expected = "INSERT INTO counters (`id`,`count`) VALUES(?,?) ON DUPLICATE KEY UPDATE `count` = `counters`.`count` + VALUES(`count`) + ?"
+	if sql, _, _ := ib.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+
+	// UseDialect overrides SetDialect, for the expressions too
+	expected = `INSERT INTO counters ("id","count") VALUES($1,$2) ON CONFLICT ("id") DO UPDATE SET "count" = "counters"."count" + EXCLUDED."count" + $3`
+	if sql, _, _ := ib.UseDialect(Postgres).Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+}
+
+func TestInsertBuilderAccumulates(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`CREATE TABLE counters(id INTEGER PRIMARY KEY, count INTEGER)`)
+
+	for _, n := range []int{2, 3} {
+		_, err := InsertInto("counters").Set("id", 1).Set("count", n).
+			OnConflict("id").
+			DoUpdate(Assign("count", Add(Col("counters.count"), Excluded("count")))).
+			Exec(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	var count int
+	db.QueryRow("SELECT count FROM counters WHERE id = 1").Scan(&count)
+	if count != 5 {
+		t.Errorf("Expected 5, got %d", count)
+	}
+	if _, _, err := InsertInto("counters").Set("id", 1).DoNothing().Build(); err == nil {
+		t.Error("Expected an error without OnConflict")
+	}
+}
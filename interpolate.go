@@ -0,0 +1,153 @@
+package goql
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BuildInterpolated generates the SQL of the query builder with the values
+// inlined as literals instead of placeholders. It's meant for drivers or
+// proxies that don't support prepared statements and for tooling such as
+// EXPLAIN, the regular Build and GetValues should be preferred otherwise.
+// The values are quoted and escaped for the dialect of the query, for
+// example backslashes are escaped in MySQL strings and []byte values are
+// written as X'..' in MySQL and SQLite and as bytea in Postgres.
+// An error is returned when a value can't be safely represented as a
+// literal or when the number of values doesn't match the placeholders.
+func (qb *QueryBuilder) BuildInterpolated() (string, error) {
+	raw := qb.buildSQL()
+	if Testing {
+		// Same normalization as replaceWhereValues, conditions may use
+		// either marker when testing.
+		raw = strings.Replace(raw, "$?", "?", -1)
+		return interpolate(qb.getDialect(), raw, "?", qb.GetValues())
+	}
+	return interpolate(qb.getDialect(), raw, "$?", qb.GetValues())
+}
+
+// DebugString returns the SQL of the query with its values inlined,
+// quoted and escaped for its dialect, to be logged or pasted into a SQL
+// console. Unlike BuildInterpolated it never fails: values that can't be
+// represented as literals are inlined as quoted text and when the number
+// of values doesn't match the placeholders the values are listed in a
+// comment after the SQL. It doesn't change the query sent by Build, which
+// should always be used to run the query.
+func (qb *QueryBuilder) DebugString() string {
+	d := qb.getDialect()
+	vals := qb.GetValues()
+	lits := make([]interface{}, len(vals))
+	for i, v := range vals {
+		lit, err := sqlLiteral(d, v)
+		if err != nil {
+			lit = stringLiteral(d, fmt.Sprint(v))
+		}
+		lits[i] = debugLiteral(lit)
+	}
+	sql := qb.buildSQL()
+	if debug, err := interpolate(d, sql, "$?", lits); err == nil {
+		return debug
+	}
+	return fmt.Sprintf("%s /* values: %v */", sql, vals)
+}
+
+// debugLiteral is a value already converted into a literal.
+type debugLiteral string
+
+// interpolate replaces each placeholder in qry with the literal of the
+// corresponding value in the dialect d in a single pass so placeholders
+// that show up in the inlined values are left untouched.
+func interpolate(d Dialect, qry string, placeholder string, vals []interface{}) (string, error) {
+	buf := bytes.Buffer{}
+	for i, v := range vals {
+		pos := strings.Index(qry, placeholder)
+		if pos < 0 {
+			return "", fmt.Errorf("goql: got %d values but only %d placeholders", len(vals), i)
+		}
+		lit, err := sqlLiteral(d, v)
+		if err != nil {
+			return "", err
+		}
+		buf.WriteString(qry[:pos])
+		buf.WriteString(lit)
+		qry = qry[pos+len(placeholder):]
+	}
+	if strings.Contains(qry, placeholder) {
+		return "", fmt.Errorf("goql: got %d values but there are more placeholders", len(vals))
+	}
+	buf.WriteString(qry)
+	return buf.String(), nil
+}
+
+// sqlLiteral converts v into a SQL literal of the dialect d. Only types
+// that can be represented unambiguously are accepted.
+func sqlLiteral(d Dialect, v interface{}) (string, error) {
+	if valuer, ok := v.(driver.Valuer); ok {
+		val, err := valuer.Value()
+		if err != nil {
+			return "", err
+		}
+		if _, ok := val.(driver.Valuer); ok {
+			return "", fmt.Errorf("goql: %T returned another driver.Valuer", v)
+		}
+		return sqlLiteral(d, val)
+	}
+
+	switch val := v.(type) {
+	case debugLiteral:
+		return string(val), nil
+	case nil:
+		return "NULL", nil
+	case bool:
+		if val {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case string:
+		if strings.IndexByte(val, 0) >= 0 {
+			return "", fmt.Errorf("goql: string values can't contain NUL bytes")
+		}
+		return stringLiteral(d, val), nil
+	case []byte:
+		if d.Name() == MySQL.Name() || d.Name() == SQLite.Name() {
+			return "X'" + hex.EncodeToString(val) + "'", nil
+		}
+		return `'\x` + hex.EncodeToString(val) + `'::bytea`, nil
+	case time.Time:
+		return stringLiteral(d, val.Format("2006-01-02 15:04:05.999999999-07:00")), nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", fmt.Errorf("goql: %v can't be used as a literal", f)
+		}
+		return strconv.FormatFloat(f, 'g', -1, 64), nil
+	case reflect.String:
+		return sqlLiteral(d, rv.String())
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return "NULL", nil
+		}
+		return sqlLiteral(d, rv.Elem().Interface())
+	}
+	return "", fmt.Errorf("goql: unsupported value of type %T", v)
+}
+
+// stringLiteral quotes s as a string literal of the dialect d, MySQL
+// treating backslashes as escapes.
+func stringLiteral(d Dialect, s string) string {
+	return ddlString(d.Name(), s)
+}
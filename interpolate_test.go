@@ -0,0 +1,99 @@
+package goql
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildInterpolated(t *testing.T) {
+	Testing = false
+	expected := `SELECT id FROM users WHERE name = 'O''Brien $?' AND age > 30 AND active = TRUE AND deleted_at IS NOT DISTINCT FROM NULL AND created < '2020-01-02 03:04:05+00:00'`
+	qb := QueryBuilder{}
+	qb.Select("id").From("users").
+		Where("name = $?", "O'Brien $?").
+		Where("age > $?", 30).
+		Where("active = $?", true).
+		Where("deleted_at IS NOT DISTINCT FROM $?", nil).
+		Where("created < $?", time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+	sql, err := qb.BuildInterpolated()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+}
+
+func TestBuildInterpolatedDialects(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.UseDialect(MySQL).Select("id").From("users").
+		Where("name = $?", `\' OR 1=1 -- `).
+		Where("hash = $?", []byte{0xde, 0xad})
+	expected := `SELECT id FROM users WHERE name = '\\'' OR 1=1 -- ' AND hash = X'dead'`
+	if sql, err := qb.BuildInterpolated(); err != nil || sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s (%v)", expected, sql, err)
+	}
+	qb = QueryBuilder{}
+	qb.Select("id").From("users").Where("hash = $?", []byte{0xde, 0xad})
+	expected = `SELECT id FROM users WHERE hash = '\xdead'::bytea`
+	if sql, err := qb.BuildInterpolated(); err != nil || sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s (%v)", expected, sql, err)
+	}
+}
+
+func TestBuildInterpolatedTesting(t *testing.T) {
+	Testing = true
+	defer func() { Testing = false }()
+	qb := QueryBuilder{}
+	qb.Select("id").From("users").
+		Where("name = $?", "x").
+		Where("age > ?", 30).
+		Where("hash = $?", []byte{0xde, 0xad})
+	expected := `SELECT id FROM users WHERE name = 'x' AND age > 30 AND hash = X'dead'`
+	if sql, err := qb.BuildInterpolated(); err != nil || sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s (%v)", expected, sql, err)
+	}
+}
+
+func TestBuildInterpolatedRejectsUnsafeValues(t *testing.T) {
+	Testing = false
+	for _, val := range []interface{}{"nul\x00byte", struct{}{}, []int{1}} {
+		qb := QueryBuilder{}
+		qb.Select("id").From("users").Where("name = $?", val)
+		if _, err := qb.BuildInterpolated(); err == nil {
+			t.Errorf("Expected an error interpolating %#v", val)
+		}
+	}
+}
+
+func TestBuildInterpolatedChecksValueCount(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.Select("id").From("users").Where("id = $? OR id = $?", 1)
+	if _, err := qb.BuildInterpolated(); err == nil {
+		t.Error("Expected an error with missing values")
+	}
+}
+
+func TestDebugString(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.UseDialect(MySQL).Select("id").From("users").
+		Where("name = $?", `O'Brien \ $?`).
+		Where("tags = $?", []int{1, 2})
+	expected := `SELECT id FROM users WHERE name = 'O''Brien \\ $?' AND tags = '[1 2]'`
+	if sql := qb.DebugString(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if sql := qb.Build(); sql != `SELECT id FROM users WHERE name = ? AND tags = ?` {
+		t.Errorf("Unexpected SQL %s", sql)
+	}
+
+	qb = QueryBuilder{}
+	qb.Select("id").From("users").Where("id = $? OR id = $?", 1)
+	expected = `SELECT id FROM users WHERE id = $? OR id = $? /* values: [1] */`
+	if sql := qb.DebugString(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+}
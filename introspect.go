@@ -0,0 +1,100 @@
+package goql
+
+import (
+	"strings"
+)
+
+// Statement is implemented by the builders of the package, which lets
+// middleware such as metrics or tenancy checks reason about statements
+// without parsing their SQL, for example
+//
+//	func checkTenant(stmt goql.Statement) error {
+//		for _, table := range stmt.Tables() {
+//			...
+//		}
+//	}
+type Statement interface {
+	// Kind returns the kind of the statement.
+	Kind() StatementKind
+	// Tables returns the tables the statement reads or writes.
+	Tables() []string
+}
+
+var (
+	_ Statement = &QueryBuilder{}
+	_ Statement = &InsertBuilder{}
+	_ Statement = &UpdateBuilder{}
+)
+
+// Kind returns the kind of statement built by the query. QueryBuilder
+// only builds queries, including the ones locking rows with ForUpdate,
+// so it's always KindSelect, InsertBuilder and UpdateBuilder return
+// their own kinds.
+func (qb *QueryBuilder) Kind() StatementKind {
+	return KindSelect
+}
+
+// Tables returns the tables read by the query, including the ones
+// joined and the ones read by its subqueries, common table expressions
+// and combined queries, without duplicates. Common table expressions
+// themselves are not tables so they are not returned.
+func (qb *QueryBuilder) Tables() []string {
+	ctes := map[string]bool{}
+	for _, cte := range qb.ctes {
+		name := strings.SplitN(cte, " AS ", 2)[0]
+		ctes[strings.TrimSpace(strings.SplitN(name, "(", 2)[0])] = true
+	}
+	exprs := []string{qb.from}
+	exprs = append(exprs, qb.innerJoin...)
+	exprs = append(exprs, qb.leftJoin...)
+	for _, j := range qb.joins {
+		exprs = append(exprs, j.expr)
+	}
+
+	tables := []string{}
+	seen := map[string]bool{}
+	add := func(table string) {
+		if len(table) > 0 && !seen[table] && !ctes[table] {
+			seen[table] = true
+			tables = append(tables, table)
+		}
+	}
+	for _, expr := range exprs {
+		add(tableOf(expr))
+	}
+	for _, table := range qb.subTables {
+		add(table)
+	}
+	return tables
+}
+
+// Kind returns KindInsert.
+func (ib *InsertBuilder) Kind() StatementKind {
+	return KindInsert
+}
+
+// Tables returns the table the statement inserts into.
+func (ib *InsertBuilder) Tables() []string {
+	return []string{tableOf(ib.table)}
+}
+
+// Kind returns KindUpdate.
+func (ub *UpdateBuilder) Kind() StatementKind {
+	return KindUpdate
+}
+
+// Tables returns the table the statement updates.
+func (ub *UpdateBuilder) Tables() []string {
+	return []string{tableOf(ub.table)}
+}
+
+// tableOf returns the table of a FROM or JOIN expression such as
+// `"order" o ON o.id = u.order_id` or an empty string when the
+// expression is not a table, as for subqueries and functions.
+func tableOf(expr string) string {
+	fields := strings.Fields(expr)
+	if len(fields) <= 0 || strings.ContainsAny(fields[0], "()") {
+		return ""
+	}
+	return strings.Trim(fields[0], "\"`")
+}
@@ -0,0 +1,45 @@
+package goql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTablesAndKind(t *testing.T) {
+	recent := &QueryBuilder{}
+	recent.Select("id").From("orders").Where("created > $?", 1)
+	banned := &QueryBuilder{}
+	banned.Select("user_id").From("bans")
+	archived := &QueryBuilder{}
+	archived.Select("id").From("archived_users")
+
+	qb := QueryBuilder{}
+	qb.With("recent", recent).Select("u.id").From("users u").
+		InnerJoin(`"profiles" p ON p.user_id = u.id`).
+		LeftJoin("recent r ON r.id = u.id").
+		Where("u.id NOT IN $?", banned).
+		Union(archived).
+		CrossJoin("users")
+	if tables := strings.Join(qb.Tables(), ","); tables != "users,profiles,orders,bans,archived_users" {
+		t.Errorf("Unexpected tables %s", tables)
+	}
+	if qb.Kind() != KindSelect {
+		t.Errorf("Unexpected kind %s", qb.Kind())
+	}
+}
+
+func TestStatementKinds(t *testing.T) {
+	stmts := map[StatementKind]Statement{
+		KindSelect: (&QueryBuilder{}).Select("id").From("users"),
+		KindInsert: InsertInto(`"users"`).Set("name", "a"),
+		KindUpdate: UpdateTable("users").Set("name", "a").Where("id = $?", 1),
+	}
+	for kind, stmt := range stmts {
+		if stmt.Kind() != kind {
+			t.Errorf("Expected %s, got %s", kind, stmt.Kind())
+		}
+		if tables := strings.Join(stmt.Tables(), ","); tables != "users" {
+			t.Errorf("%s: unexpected tables %s", kind, tables)
+		}
+	}
+}
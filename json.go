@@ -0,0 +1,238 @@
+package goql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// jsonStyle is the way a database queries JSON documents.
+type jsonStyle int
+
+const (
+	// jsonPostgres uses the jsonb operators, such as @> and #>>
+	jsonPostgres jsonStyle = iota
+	// jsonMySQL uses JSON_EXTRACT and JSON_CONTAINS
+	jsonMySQL
+	// jsonSQLite uses json_extract, it can't tell whether a document
+	// contains another one
+	jsonSQLite
+	// jsonUnsupported is the style of the databases whose JSON functions
+	// are not supported
+	jsonUnsupported
+)
+
+// WhereJSONContains adds a condition matching the rows whose JSON column
+// contains the document val, which is marshaled to JSON, for example
+// queryBuilder.WhereJSONContains("meta", map[string]interface{}{"plan": "pro"})
+// generates WHERE meta @> $1::jsonb in Postgres and
+// WHERE JSON_CONTAINS(meta, $1) in MySQL. SQLite doesn't support it.
+func (qb *QueryBuilder) WhereJSONContains(column string, val interface{}) (ret *QueryBuilder) {
+	ret = qb
+	doc, err := json.Marshal(val)
+	if err != nil {
+		qb.addError(fmt.Errorf("WhereJSONContains %s: %s", column, err))
+		return
+	}
+	d := qb.getDialect()
+	switch featuresOf(d).json {
+	case jsonMySQL:
+		return qb.Where("JSON_CONTAINS("+column+", $?)", string(doc))
+	case jsonSQLite, jsonUnsupported:
+		qb.addError(fmt.Errorf("goql: the %s dialect doesn't support WhereJSONContains", d.Name()))
+		return
+	}
+	return qb.Where(column+" @> $?::jsonb", string(doc))
+}
+
+// WhereJSONPath adds a condition comparing the value at path in a JSON
+// column, as text, with val, path being the dot separated keys of the
+// value, for example
+// queryBuilder.WhereJSONPath("meta", "address.city", "=", "Paris")
+// generates WHERE meta #>> '{address,city}' = $1 in Postgres.
+func (qb *QueryBuilder) WhereJSONPath(column string, path string, op string, val interface{}) (ret *QueryBuilder) {
+	ret = qb
+	if !comparisonOperators[op] {
+		panic("Unsupported operator " + op)
+	}
+	field, err := jsonField(qb.getDialect(), column, path)
+	if err != nil {
+		qb.addError(err)
+		return
+	}
+	return qb.Where(fmt.Sprintf("%s %s $?", field, op), val)
+}
+
+// SelectJSONField selects the value at path in a JSON column as text
+// named alias, see WhereJSONPath, for example
+// queryBuilder.SelectJSONField("meta", "plan", "plan")
+// generates SELECT meta->>'plan' "plan" in Postgres and
+// SELECT JSON_UNQUOTE(JSON_EXTRACT(meta, '$."plan"')) `plan` in MySQL.
+func (qb *QueryBuilder) SelectJSONField(column string, path string, alias string) (ret *QueryBuilder) {
+	ret = qb
+	field, err := jsonField(qb.getDialect(), column, path)
+	if err != nil {
+		qb.addError(err)
+		return
+	}
+	qb.invalidate()
+	qb.selectExpr(Raw(field).As(alias))
+	return
+}
+
+// jsonField returns the expression of the value at path in the JSON
+// column, as text, in the dialect d.
+func jsonField(d Dialect, column string, path string) (string, error) {
+	keys := strings.Split(path, ".")
+	switch featuresOf(d).json {
+	case jsonMySQL:
+		return "JSON_UNQUOTE(JSON_EXTRACT(" + column + ", " + jsonPath(d, keys) + "))", nil
+	case jsonSQLite:
+		return "json_extract(" + column + ", " + jsonPath(d, keys) + ")", nil
+	case jsonUnsupported:
+		return "", fmt.Errorf("goql: the %s dialect doesn't support JSON paths", d.Name())
+	}
+	if len(keys) == 1 {
+		return column + "->>" + stringLiteral(d, keys[0]), nil
+	}
+	return column + " #>> " + stringLiteral(d, "{"+strings.Join(keys, ",")+"}"), nil
+}
+
+// jsonPath returns the $.key path of MySQL and SQLite quoted as a
+// string literal of the dialect d.
+func jsonPath(d Dialect, keys []string) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		key = strings.Replace(key, `\`, `\\`, -1)
+		parts[i] = `"` + strings.Replace(key, `"`, `\"`, -1) + `"`
+	}
+	return stringLiteral(d, "$."+strings.Join(parts, "."))
+}
+
+// UpdateJSONField applies the JSON merge patch patch, which is marshaled
+// to JSON and must be an object, to the JSON column of the row of table
+// with the primary key pk, so a document is partially updated without
+// reading and writing it whole, for example
+// goql.UpdateJSONField(db, "accounts", 1, "meta", map[string]interface{}{"plan": "pro", "trial": nil})
+// sets meta.plan and removes meta.trial. pk is either the value of the
+// id column or a struct whose fields tagged with pk identify the row.
+// The patch is applied with jsonb_set in Postgres, JSON_MERGE_PATCH in
+// MySQL and json_patch in SQLite.
+func UpdateJSONField(Db interface{}, table string, pk interface{}, column string, patch interface{}) (sql.Result, error) {
+	return UpdateJSONFieldContext(context.Background(), Db, table, pk, column, patch)
+}
+
+// UpdateJSONFieldContext is the same as UpdateJSONField but the statement
+// is canceled when ctx is done.
+func UpdateJSONFieldContext(ctx context.Context, Db interface{}, table string, pk interface{}, column string, patch interface{}) (sql.Result, error) {
+	doc, err := json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+	d := activeDialect()
+	col := d.QuoteIdent(column)
+	var set string
+	var vals []interface{}
+	switch featuresOf(d).json {
+	case jsonMySQL:
+		set, vals = "JSON_MERGE_PATCH(COALESCE("+col+", '{}'), $?)", []interface{}{string(doc)}
+	case jsonSQLite:
+		set, vals = "json_patch(COALESCE("+col+", '{}'), $?)", []interface{}{string(doc)}
+	case jsonUnsupported:
+		return nil, fmt.Errorf("goql: the %s dialect doesn't support UpdateJSONField", d.Name())
+	default:
+		if set, vals, err = jsonbPatch(col, doc); err != nil {
+			return nil, err
+		}
+	}
+
+	conds := []string{d.QuoteIdent("id") + " = $?"}
+	pkVals := []interface{}{pk}
+	if reflect.TypeOf(pk).Kind() == reflect.Struct {
+		queryInfo, err := creatQueryStructInfo(pk)
+		if err != nil {
+			return nil, err
+		}
+		if len(queryInfo.primaryKeyColumns) <= 0 {
+			return nil, errors.New("there is no primary key in the structure")
+		}
+		conds = make([]string, len(queryInfo.primaryKeyColumns))
+		for i, pkCol := range queryInfo.primaryKeyColumns {
+			conds[i] = d.QuoteIdent(pkCol) + " = $?"
+		}
+		pkVals = queryInfo.PrimaryKeyValues
+	}
+	qry := fmt.Sprintf(`UPDATE %s SET %s = %s WHERE (%s)`, table, col, set, strings.Join(conds, " AND "))
+	return execStatement(ctx, Db, KindUpdate, table, numberPlaceholders(d, qry, 1), append(vals, pkVals...))
+}
+
+// jsonbPatch returns the Postgres expression that applies the merge
+// patch doc to the jsonb column col, setting each one of its values
+// with jsonb_set and removing the null ones with #-.
+func jsonbPatch(col string, doc []byte) (string, []interface{}, error) {
+	patch := map[string]interface{}{}
+	if err := json.Unmarshal(doc, &patch); err != nil {
+		return "", nil, errors.New("goql: the JSON patch must be an object")
+	}
+	expr := "COALESCE(" + col + ", '{}'::jsonb)"
+	vals := []interface{}{}
+	var walk func(path []string, patch map[string]interface{})
+	walk = func(path []string, patch map[string]interface{}) {
+		keys := make([]string, 0, len(patch))
+		for key := range patch {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			keyPath := append(append([]string{}, path...), key)
+			literal := quoteString("{" + strings.Join(keyPath, ",") + "}")
+			switch val := patch[key].(type) {
+			case nil:
+				expr = "(" + expr + " #- " + literal + ")"
+			case map[string]interface{}:
+				// Nested objects are merged into the existing ones
+				expr = fmt.Sprintf("jsonb_set(%s, %s, COALESCE(%s #> %s, '{}'::jsonb))", expr, literal, col, literal)
+				walk(keyPath, val)
+			default:
+				b, _ := json.Marshal(val)
+				expr = fmt.Sprintf("jsonb_set(%s, %s, $?::jsonb)", expr, literal)
+				vals = append(vals, string(b))
+			}
+		}
+	}
+	walk(nil, patch)
+	return expr, vals, nil
+}
+
+// ErrDocumentChanged is returned by Update when a document guarded by a
+// field with the hashof tag was changed since it was read.
+var ErrDocumentChanged = errors.New("goql: the document was changed by another update")
+
+// documentGuard is the hash a document had when it was read.
+type documentGuard struct {
+	column string
+	hash   interface{}
+}
+
+// documentHash returns the expression of the hash of the document col,
+// which is compared with the one read into the fields tagged with
+// hashof, for example
+// DocumentHash string `db:"meta_hash" hashof:"meta"`
+// is selected as md5(meta::text) "meta_hash" in Postgres and makes
+// Update detect the concurrent edits of meta without a version column.
+// SQLite lacks a hash function so the document itself is compared, as
+// it is in the databases whose JSON functions are not supported.
+func documentHash(d Dialect, col string) string {
+	switch featuresOf(d).json {
+	case jsonMySQL:
+		return "MD5(CAST(" + col + " AS CHAR))"
+	case jsonSQLite, jsonUnsupported:
+		return col
+	}
+	return "md5(" + col + "::text)"
+}
@@ -0,0 +1,159 @@
+package goql
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestJSONHelpers(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.Select("id").SelectJSONField("meta", "plan", "plan").From("accounts").
+		WhereJSONContains("meta", map[string]interface{}{"plan": "pro"}).
+		WhereJSONPath("meta", "address.city", "=", "Paris")
+	expected := `SELECT id,meta->>'plan' "plan" FROM accounts WHERE meta @> $1::jsonb AND meta #>> '{address,city}' = $2`
+	sql, vals, err := qb.BuildWithArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if fmt.Sprint(vals) != `[{"plan":"pro"} Paris]` {
+		t.Errorf("Unexpected values %v", vals)
+	}
+
+	qb = QueryBuilder{}
+	qb.UseDialect(MySQL).SelectJSONField("meta", "plan", "plan").From("accounts").
+		WhereJSONContains("meta", []string{"a"}).
+		WhereJSONPath("meta", "address.city", "!=", "Paris")
+	expected = "SELECT JSON_UNQUOTE(JSON_EXTRACT(meta, '$.\"plan\"')) `plan` FROM accounts WHERE JSON_CONTAINS(meta, ?) AND JSON_UNQUOTE(JSON_EXTRACT(meta, '$.\"address\".\"city\"')) != ?"
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+
+	qb = QueryBuilder{}
+	qb.UseDialect(MySQL).Select("id").From("accounts").WhereJSONPath("meta", `a\'b`, "=", 1)
+	expected = `SELECT id FROM accounts WHERE JSON_UNQUOTE(JSON_EXTRACT(meta, '$."a\\\\''b"')) = ?`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+
+	qb = QueryBuilder{}
+	qb.UseDialect(SQLite).Select("id").From("accounts").WhereJSONContains("meta", 1)
+	if _, _, err := qb.BuildWithArgs(); err == nil {
+		t.Error("Expected an error for WhereJSONContains in SQLite")
+	}
+}
+
+func TestJSONPathInSQLite(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`CREATE TABLE account(id INTEGER PRIMARY KEY AUTOINCREMENT, meta TEXT)`)
+	db.Exec(`INSERT INTO account(meta) VALUES('{"plan": "pro", "address": {"city": "Paris"}}'), ('{"plan": "free"}')`)
+	qb := QueryBuilder{}
+	qb.SelectJSONField("meta", "plan", "plan").From("account").WhereJSONPath("meta", "address.city", "=", "Paris")
+	var plan string
+	if err := db.QueryRow(qb.Build(), qb.GetValues()...).Scan(&plan); err != nil {
+		t.Fatal(err)
+	}
+	if plan != "pro" {
+		t.Errorf("Expected pro, got %s", plan)
+	}
+}
+
+func TestUpdateJSONFieldStatements(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	defer resetStatementHooks()
+	Testing = false
+	defer SetDialect(Postgres)
+
+	var qry string
+	var args []interface{}
+	BeforeStatement(KindUpdate, "account", func(e *StatementEvent) error {
+		qry, args = e.Query, e.Args
+		return errors.New("aborted")
+	})
+	patch := map[string]interface{}{"plan": "pro", "trial": nil, "address": map[string]interface{}{"city": "Paris"}}
+	UpdateJSONField(db, "account", 7, "meta", patch)
+	expected := `UPDATE account SET "meta" = (jsonb_set(jsonb_set(jsonb_set(COALESCE("meta", '{}'::jsonb), '{address}', COALESCE("meta" #> '{address}', '{}'::jsonb)), '{address,city}', $1::jsonb), '{plan}', $2::jsonb) #- '{trial}') WHERE ("id" = $3)`
+	if qry != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, qry)
+	}
+	if fmt.Sprint(args) != `["Paris" "pro" 7]` {
+		t.Errorf("Unexpected args %v", args)
+	}
+
+	SetDialect(MySQL)
+	UpdateJSONField(db, "account", member{ID: 3}, "meta", patch)
+	expected = "UPDATE account SET `meta` = JSON_MERGE_PATCH(COALESCE(`meta`, '{}'), ?) WHERE (`id` = ?)"
+	if qry != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, qry)
+	}
+
+	SetDialect(Postgres)
+	if _, err := UpdateJSONField(db, "account", 7, "meta", []int{1}); err == nil {
+		t.Error("Expected an error for a patch that is not an object")
+	}
+}
+
+func TestUpdateJSONFieldInSQLite(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`CREATE TABLE account(id INTEGER PRIMARY KEY AUTOINCREMENT, meta TEXT)`)
+	db.Exec(`INSERT INTO account(meta) VALUES('{"plan": "free", "trial": true, "address": {"zip": "75001"}}')`)
+	patch := map[string]interface{}{"plan": "pro", "trial": nil, "address": map[string]interface{}{"city": "Paris"}}
+	if _, err := UpdateJSONField(db, "account", 1, "meta", patch); err != nil {
+		t.Fatal(err)
+	}
+	var meta string
+	db.QueryRow(`SELECT meta FROM account WHERE id = 1`).Scan(&meta)
+	if meta != `{"plan":"pro","address":{"zip":"75001","city":"Paris"}}` {
+		t.Errorf("Unexpected document %s", meta)
+	}
+}
+
+type guardedAccount struct {
+	ID       int64  `db:"id" pk:"true"`
+	Meta     string `db:"meta"`
+	MetaHash string `db:"meta_hash" hashof:"meta"`
+}
+
+func TestDocumentHashGuard(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.Select(guardedAccount{})
+	expected := `SELECT "id","meta",md5("meta"::text) "meta_hash" FROM guardedaccount`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`CREATE TABLE guardedaccount(id INTEGER PRIMARY KEY AUTOINCREMENT, meta TEXT)`)
+	if _, err := Insert(db, "guardedaccount", guardedAccount{Meta: `{"plan":"free"}`}); err != nil {
+		t.Fatal(err)
+	}
+	var first, second guardedAccount
+	for _, acc := range []*guardedAccount{&first, &second} {
+		qb = QueryBuilder{}
+		if err := qb.Select(guardedAccount{}).QueryAndScan(db, acc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first.Meta = `{"plan":"pro"}`
+	if _, err := Update(db, "guardedaccount", first); err != nil {
+		t.Fatal(err)
+	}
+	second.Meta = `{"plan":"team"}`
+	if _, err := Update(db, "guardedaccount", second); err != ErrDocumentChanged {
+		t.Errorf("Expected ErrDocumentChanged, got %v", err)
+	}
+	var meta string
+	db.QueryRow(`SELECT meta FROM guardedaccount WHERE id = 1`).Scan(&meta)
+	if meta != `{"plan":"pro"}` {
+		t.Errorf("Expected the first update to win, got %s", meta)
+	}
+}
@@ -0,0 +1,125 @@
+package goql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Loader batches the loads of rows by primary key, the ids requested by
+// concurrent calls to Load within a short window are loaded with a
+// single FindByPKs query, which avoids the N+1 queries of GraphQL
+// resolvers, for example
+//
+//	users := goql.NewLoader(db, User{}, 2*time.Millisecond)
+//	v, err := users.Load(ctx, order.UserID)
+//	user := v.(*User)
+type Loader struct {
+	// MaxBatch caps the number of ids loaded by a single query, a batch
+	// is loaded as soon as it's full. 0 means no limit.
+	MaxBatch int
+
+	db    Queryer
+	model reflect.Type
+	pk    reflect.StructField
+	wait  time.Duration
+
+	mu    sync.Mutex
+	batch *loaderBatch
+}
+
+// loaderBatch is a set of ids loaded by the same query.
+type loaderBatch struct {
+	ids     []interface{}
+	keys    map[string]bool
+	done    chan struct{}
+	results map[string]interface{}
+	err     error
+}
+
+// NewLoader returns a Loader of the rows of model, which must be a
+// struct with a field tagged with "pk", the table is the one Select
+// guesses from the struct. wait is how long a batch waits for more ids
+// before it's loaded.
+func NewLoader(db Queryer, model interface{}, wait time.Duration) *Loader {
+	t := reflect.TypeOf(model)
+	if t.Kind() != reflect.Struct {
+		panic("Unsupported interface passed")
+	}
+	pk, ok := pkField(t)
+	if !ok {
+		panic(fmt.Sprintf("%s has no field tagged with pk", t.Name()))
+	}
+	return &Loader{db: db, model: t, pk: pk, wait: wait}
+}
+
+// Load returns a pointer to the row with the given primary key, loaded
+// along with the ids requested by other calls in the same window.
+// sql.ErrNoRows is returned when there is no such row. The query isn't
+// canceled when ctx is done, as other calls may be waiting for it, but
+// Load returns ctx.Err() right away.
+func (l *Loader) Load(ctx context.Context, id interface{}) (interface{}, error) {
+	key := fmt.Sprint(id)
+	batch := l.add(id, key)
+	select {
+	case <-batch.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if batch.err != nil {
+		return nil, batch.err
+	}
+	if row, ok := batch.results[key]; ok {
+		return row, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+// add adds id to the current batch, starting a new one when there is
+// none, and returns the batch.
+func (l *Loader) add(id interface{}, key string) *loaderBatch {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.batch == nil {
+		batch := &loaderBatch{keys: map[string]bool{}, done: make(chan struct{})}
+		l.batch = batch
+		time.AfterFunc(l.wait, func() {
+			l.dispatch(batch)
+		})
+	}
+	batch := l.batch
+	if !batch.keys[key] {
+		batch.keys[key] = true
+		batch.ids = append(batch.ids, id)
+	}
+	if l.MaxBatch > 0 && len(batch.ids) >= l.MaxBatch {
+		go l.dispatch(batch)
+	}
+	return batch
+}
+
+// dispatch loads the rows of batch unless it has been loaded already,
+// which happens when it was full before its window ended.
+func (l *Loader) dispatch(batch *loaderBatch) {
+	l.mu.Lock()
+	if l.batch != batch {
+		l.mu.Unlock()
+		return
+	}
+	l.batch = nil
+	l.mu.Unlock()
+
+	defer close(batch.done)
+	rows := reflect.New(reflect.SliceOf(reflect.PtrTo(l.model)))
+	if batch.err = FindByPKs(l.db, rows.Interface(), batch.ids); batch.err != nil {
+		return
+	}
+	batch.results = map[string]interface{}{}
+	for i := 0; i <= rows.Elem().Len()-1; i++ {
+		row := rows.Elem().Index(i)
+		batch.results[fmt.Sprint(row.Elem().FieldByIndex(l.pk.Index).Interface())] = row.Interface()
+	}
+}
@@ -0,0 +1,50 @@
+package goql
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLoaderBatchesConcurrentLoads(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`CREATE TABLE member(id INTEGER PRIMARY KEY, username TEXT)`)
+	db.Exec(`INSERT INTO member(id, username) VALUES(1, 'a'), (2, 'b'), (3, 'c')`)
+
+	counter := &countingQueryer{Queryer: db}
+	loader := NewLoader(counter, member{}, 10*time.Millisecond)
+	names := make([]string, 4)
+	errs := make([]error, 4)
+	wg := sync.WaitGroup{}
+	for i, id := range []int64{3, 1, 3, 9} {
+		wg.Add(1)
+		go func(i int, id int64) {
+			defer wg.Done()
+			row, err := loader.Load(context.Background(), id)
+			errs[i] = err
+			if err == nil {
+				names[i] = row.(*member).Username
+			}
+		}(i, id)
+	}
+	wg.Wait()
+	if names[0] != "c" || names[1] != "a" || names[2] != "c" || errs[3] != sql.ErrNoRows {
+		t.Errorf("Unexpected results %v %v", names, errs)
+	}
+	if counter.queries != 1 {
+		t.Errorf("Expected 1 query, got %d", counter.queries)
+	}
+}
+
+type countingQueryer struct {
+	Queryer
+	queries int
+}
+
+func (q *countingQueryer) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	q.queries++
+	return q.Queryer.QueryContext(ctx, query, args...)
+}
@@ -0,0 +1,88 @@
+package goql
+
+import (
+	"strings"
+)
+
+// rowLock is the locking clause of a query.
+type rowLock struct {
+	mode string
+	of   []string
+	wait string
+}
+
+// LockOption changes how ForUpdate and ForShare lock the rows.
+type LockOption func(lock *rowLock)
+
+// SkipLocked skips the rows locked by other transactions instead of
+// waiting for them, which is useful to build queues.
+func SkipLocked() LockOption {
+	return func(lock *rowLock) {
+		lock.wait = "SKIP LOCKED"
+	}
+}
+
+// NoWait fails instead of waiting when a row is locked by another
+// transaction.
+func NoWait() LockOption {
+	return func(lock *rowLock) {
+		lock.wait = "NOWAIT"
+	}
+}
+
+// Of locks only the rows of the given tables, or aliases, of a join.
+func Of(tables ...string) LockOption {
+	return func(lock *rowLock) {
+		lock.of = tables
+	}
+}
+
+// ForUpdate locks the selected rows for update until the end of the
+// transaction, for example
+// queryBuilder.Select("id").From("jobs").ForUpdate(SkipLocked())
+// generates SELECT id FROM jobs FOR UPDATE SKIP LOCKED
+// No clause is rendered in SQLite, which locks the whole database, and
+// BuildWithArgs fails with the dialects that lack row locks, such as
+// MSSQL.
+func (qb *QueryBuilder) ForUpdate(opts ...LockOption) *QueryBuilder {
+	return qb.setLock("UPDATE", opts)
+}
+
+// ForShare locks the selected rows against writes from other
+// transactions until the end of the transaction, see ForUpdate.
+func (qb *QueryBuilder) ForShare(opts ...LockOption) *QueryBuilder {
+	return qb.setLock("SHARE", opts)
+}
+
+func (qb *QueryBuilder) setLock(mode string, opts []LockOption) (ret *QueryBuilder) {
+	ret = qb
+	qb.invalidate()
+	qb.lock = rowLock{mode: mode}
+	for _, opt := range opts {
+		opt(&qb.lock)
+	}
+	return
+}
+
+// lockable tells whether the rows read by a transaction can be kept
+// from other transactions in the dialect d, either with a locking
+// clause or because the database is locked whole.
+func lockable(d Dialect) bool {
+	f := featuresOf(d)
+	return f.locking || f.serialized
+}
+
+// build renders the locking clause, which is empty when there is no lock.
+func (lock rowLock) build() string {
+	if len(lock.mode) <= 0 {
+		return ""
+	}
+	sql := "FOR " + lock.mode
+	if len(lock.of) > 0 {
+		sql += " OF " + strings.Join(lock.of, ", ")
+	}
+	if len(lock.wait) > 0 {
+		sql += " " + lock.wait
+	}
+	return sql
+}
@@ -0,0 +1,25 @@
+package goql
+
+import (
+	"testing"
+)
+
+func TestLockingClauses(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.Select("j.id").From("jobs j").InnerJoin("queues q ON q.id = j.queue_id").ForUpdate(Of("j"), SkipLocked())
+	expected := `SELECT j.id FROM jobs j INNER JOIN queues q ON q.id = j.queue_id FOR UPDATE OF j SKIP LOCKED`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	qb.ForShare(NoWait())
+	expected = `SELECT j.id FROM jobs j INNER JOIN queues q ON q.id = j.queue_id FOR SHARE NOWAIT`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	qb.UseDialect(SQLite)
+	expected = `SELECT j.id FROM jobs j INNER JOIN queues q ON q.id = j.queue_id`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+}
@@ -0,0 +1,81 @@
+package goql
+
+import (
+	"reflect"
+	"strings"
+)
+
+// ResultColumn describes a column of the result of a query.
+type ResultColumn struct {
+	// Name is the name of the column in the result set.
+	Name string
+	// Field is the path of the struct field the column is scanned into,
+	// such as "User.Email" for a field of a join-tagged struct. It's
+	// empty for the columns that were not selected from a struct.
+	Field string
+	// Type is the type of the struct field, nil when Field is empty.
+	Type reflect.Type
+	// Computed tells whether the column is computed by the expression of
+	// an sql tag instead of read from the table.
+	Computed bool
+}
+
+// ResultColumns returns the columns of the result of the query in the
+// order they are selected, with the struct field each one is mapped to,
+// so that generic layers such as table renderers or CSV exporters can
+// label and format them without parsing the struct tags again.
+// The columns that were not selected from a struct are named after
+// their alias or, when they don't have one, as written in the query.
+// The columns of a struct are always selected in the order its fields
+// are declared, followed by the ones of its join-tagged structs, so the
+// order only changes when the struct does.
+func (qb *QueryBuilder) ResultColumns() []ResultColumn {
+	cols := make([]ResultColumn, len(qb.resultColumns))
+	copy(cols, qb.resultColumns)
+	return cols
+}
+
+// Columns returns the names of the columns of the result of the query in
+// the order they are selected, see ResultColumns, so the code scanning
+// rows by position can check in tests that it reads them in the right
+// order, for example
+// queryBuilder.Select(User{}).Columns() returns [id username password]
+func (qb *QueryBuilder) Columns() []string {
+	names := make([]string, len(qb.resultColumns))
+	for i, col := range qb.resultColumns {
+		names[i] = col.Name
+	}
+	return names
+}
+
+// outputName returns the name of the result column of the selected
+// expression col, for example "email" for `u.email` or `lower(email) AS email`.
+func outputName(col string) string {
+	fields := strings.Fields(col)
+	if len(fields) <= 0 {
+		return col
+	}
+	name := fields[len(fields)-1]
+	if len(fields) == 1 && !strings.ContainsAny(name, "()") {
+		name = name[strings.LastIndex(name, ".")+1:]
+	}
+	return strings.Trim(name, "\"`[]")
+}
+
+// selectorColumns returns the result columns of the columns selected by
+// a ColumnSelector, mapping them by name to the ones of the struct.
+func selectorColumns(selected []string, byStruct []ResultColumn) []ResultColumn {
+	byName := map[string]ResultColumn{}
+	for _, col := range byStruct {
+		byName[col.Name] = col
+	}
+	cols := make([]ResultColumn, len(selected))
+	for i, col := range selected {
+		name := outputName(col)
+		cols[i] = ResultColumn{Name: name}
+		if mapped, ok := byName[name]; ok {
+			cols[i] = mapped
+		}
+	}
+	return cols
+}
@@ -0,0 +1,36 @@
+package goql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResultColumns(t *testing.T) {
+	qb := QueryBuilder{}
+	qb.Select(User{}, "COUNT(*) AS n").Select(orderWithMember{})
+	expected := []ResultColumn{
+		{Name: "id", Field: "ID", Type: reflect.TypeOf(int64(0))},
+		{Name: "username", Field: "Username", Type: reflect.TypeOf("")},
+		{Name: "password", Field: "Password", Type: reflect.TypeOf("")},
+		{Name: "total", Field: "Total", Type: reflect.TypeOf(""), Computed: true},
+		{Name: "n"},
+		{Name: "id", Field: "ID", Type: reflect.TypeOf(int64(0))},
+		{Name: "member_id", Field: "Member.ID", Type: reflect.TypeOf(int64(0))},
+		{Name: "member_username", Field: "Member.Username", Type: reflect.TypeOf("")},
+	}
+	if cols := qb.ResultColumns(); !reflect.DeepEqual(cols, expected) {
+		t.Errorf("Expected:\n%+v\nGot:\n%+v", expected, cols)
+	}
+}
+
+func TestColumnsOrder(t *testing.T) {
+	qb := QueryBuilder{}
+	qb.Select(User{}, "COUNT(*) AS n").Select(orderWithMember{})
+	expected := []string{"id", "username", "password", "total", "n", "id", "member_id", "member_username"}
+	if cols := qb.Columns(); !reflect.DeepEqual(cols, expected) {
+		t.Errorf("Expected %v, got %v", expected, cols)
+	}
+	if cols := (&QueryBuilder{}).Select(User{}, Except("password")).Columns(); !reflect.DeepEqual(cols, []string{"id", "username", "total"}) {
+		t.Errorf("Unexpected columns %v", cols)
+	}
+}
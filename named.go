@@ -0,0 +1,54 @@
+package goql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// compileNamed rewrites the :name parameters of expr into $?
+// placeholders when vals is a single map[string]interface{}, returning
+// the values of the map in the order of the placeholders, so
+// Where("id = :id AND status = :status", map[string]interface{}{"id": 1, "status": "a"})
+// is the same as Where("id = $? AND status = $?", 1, "a").
+// String literals and Postgres casts such as x::text are left as they
+// are. Other values, and the map itself when expr has $? placeholders,
+// are returned untouched.
+func compileNamed(expr string, vals []interface{}) (string, []interface{}, error) {
+	if len(vals) != 1 || strings.Contains(expr, "$?") {
+		return expr, vals, nil
+	}
+	named, ok := vals[0].(map[string]interface{})
+	if !ok {
+		return expr, vals, nil
+	}
+	result := []byte{}
+	compiled := []interface{}{}
+	quoted := false
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		if c == '\'' {
+			quoted = !quoted
+		}
+		if quoted || c != ':' || (i > 0 && expr[i-1] == ':') || i+1 >= len(expr) || !isNameStart(expr[i+1]) {
+			result = append(result, c)
+			continue
+		}
+		end := i + 1
+		for end < len(expr) && (isNameStart(expr[end]) || (expr[end] >= '0' && expr[end] <= '9')) {
+			end++
+		}
+		name := expr[i+1 : end]
+		val, ok := named[name]
+		if !ok {
+			return expr, vals, fmt.Errorf("goql: no value for the parameter :%s", name)
+		}
+		result = append(result, "$?"...)
+		compiled = append(compiled, val)
+		i = end - 1
+	}
+	return string(result), compiled, nil
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,31 @@
+package goql
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestWhereNamedParameters(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.Select("id").From("users").
+		Where("id = :id AND status = :status AND name::text <> ':id' AND parent = :id", map[string]interface{}{"id": 1, "status": "a"}).
+		GroupBy("id").Having("COUNT(*) > :min", map[string]interface{}{"min": 2})
+	expected := `SELECT id FROM users WHERE id = $1 AND status = $2 AND name::text <> ':id' AND parent = $3 GROUP BY id HAVING COUNT(*) > $4`
+	sql, args, err := qb.BuildWithArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if fmt.Sprint(args) != "[1 a 1 2]" {
+		t.Errorf("Unexpected args %v", args)
+	}
+
+	qb = QueryBuilder{}
+	qb.Select("id").From("users").Where("id = :id", map[string]interface{}{"user": 1})
+	if _, _, err := qb.BuildWithArgs(); err == nil {
+		t.Error("Expected an error for the missing parameter")
+	}
+}
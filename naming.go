@@ -0,0 +1,115 @@
+package goql
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// NamingStrategy names the column of a struct field from the name of
+// the field.
+type NamingStrategy func(field string) string
+
+var (
+	// SnakeCase names CreatedAt as created_at and UserID as user_id.
+	SnakeCase NamingStrategy = snakeCase
+	// CamelCase names CreatedAt as createdAt and UserID as userID.
+	CamelCase NamingStrategy = lowerCamel
+	// ScreamingSnakeCase names CreatedAt as CREATED_AT.
+	ScreamingSnakeCase NamingStrategy = func(field string) string {
+		return strings.ToUpper(snakeCase(field))
+	}
+)
+
+// ColumnNamer can be implemented by models whose exported fields without
+// a db tag are mapped to the columns named by a NamingStrategy, which
+// suits lightly tagged structs of legacy schemas, for example
+// func (Invoice) ColumnNaming() goql.NamingStrategy { return goql.ScreamingSnakeCase }
+// The db tag still takes precedence and db:"-" leaves a field unmapped.
+type ColumnNamer interface {
+	ColumnNaming() NamingStrategy
+}
+
+var (
+	namingMu      sync.RWMutex
+	namings       = map[reflect.Type]NamingStrategy{}
+	defaultNaming NamingStrategy
+)
+
+// AutoMapFields maps the exported fields without a db tag of all the
+// models to the columns named by naming, unless the model implements
+// ColumnNamer, which saves tagging every field of wide structs, for
+// example goql.AutoMapFields(goql.SnakeCase) maps the Email field to
+// email. db:"-" leaves a field unmapped. A nil naming restores the
+// default, where untagged fields are ignored. It's meant to be called
+// once on start up.
+func AutoMapFields(naming NamingStrategy) {
+	namingMu.Lock()
+	defaultNaming = naming
+	namingMu.Unlock()
+}
+
+// namingOf returns the naming strategy of the model t, nil when its
+// untagged fields are not mapped.
+func namingOf(t reflect.Type) NamingStrategy {
+	namingMu.RLock()
+	naming, ok := namings[t]
+	fallback := defaultNaming
+	namingMu.RUnlock()
+	if ok {
+		if naming == nil {
+			return fallback
+		}
+		return naming
+	}
+	if namer, ok := reflect.New(t).Interface().(ColumnNamer); ok {
+		naming = namer.ColumnNaming()
+	}
+	namingMu.Lock()
+	namings[t] = naming
+	namingMu.Unlock()
+	if naming == nil {
+		return fallback
+	}
+	return naming
+}
+
+// columnName returns the column the field of the struct t is mapped to,
+// which is its db tag or the name given by the naming strategy of t to
+// the exported fields without one. It's empty when the field is not
+// mapped to a column.
+func columnName(t reflect.Type, field reflect.StructField) string {
+	tag, tagged := field.Tag.Lookup("db")
+	if tag == "-" {
+		return ""
+	}
+	if len(tag) > 0 || tagged {
+		return tag
+	}
+	if len(field.PkgPath) > 0 || field.Anonymous || len(field.Tag.Get("join")) > 0 {
+		return ""
+	}
+	if naming := namingOf(t); naming != nil {
+		return naming(field.Name)
+	}
+	return ""
+}
+
+// snakeCase converts name to snake case, keeping acronyms such as the
+// ID of UserID in a single word.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	out := []rune{}
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prevLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+				out = append(out, '_')
+			}
+		}
+		out = append(out, unicode.ToLower(r))
+	}
+	return string(out)
+}
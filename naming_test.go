@@ -0,0 +1,99 @@
+package goql
+
+import (
+	"testing"
+)
+
+func TestNamingStrategies(t *testing.T) {
+	cases := map[string][3]string{
+		"CreatedAt": {"created_at", "createdAt", "CREATED_AT"},
+		"UserID":    {"user_id", "userID", "USER_ID"},
+		"URLPath":   {"url_path", "urlPath", "URL_PATH"},
+		"Line2":     {"line2", "line2", "LINE2"},
+	}
+	for name, expected := range cases {
+		got := [3]string{SnakeCase(name), CamelCase(name), ScreamingSnakeCase(name)}
+		if got != expected {
+			t.Errorf("%s: expected %v, got %v", name, expected, got)
+		}
+	}
+}
+
+type legacyInvoice struct {
+	ID       int64 `db:"INVOICE_ID" pk:"true"`
+	Amount   float64
+	DueDate  string
+	Internal string `db:"-"`
+	secret   string
+}
+
+func (legacyInvoice) ColumnNaming() NamingStrategy {
+	return ScreamingSnakeCase
+}
+
+func TestColumnNaming(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.Select(legacyInvoice{}).Where(`"AMOUNT" > $?`, 10)
+	expected := `SELECT "INVOICE_ID","AMOUNT","DUE_DATE" FROM legacyinvoice WHERE "AMOUNT" > $1`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	info, err := creatQueryStructInfo(legacyInvoice{ID: 1, Amount: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Fields) != 2 || info.Fields[0] != "AMOUNT" || info.Fields[1] != "DUE_DATE" {
+		t.Errorf("Unexpected fields %v", info.Fields)
+	}
+	if len(GetFieldPointers(&legacyInvoice{})) != 3 {
+		t.Error("Expected 3 field pointers")
+	}
+}
+
+func TestAutoMapFields(t *testing.T) {
+	Testing = false
+	AutoMapFields(SnakeCase)
+	defer AutoMapFields(nil)
+
+	qb := QueryBuilder{}
+	qb.Select(User{}).Where("id = $?", 1)
+	expected := `SELECT "id","username","password","email",(COUNT(col)) "total" FROM user WHERE id = $1`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	// Models implementing ColumnNamer keep their own naming
+	qb = QueryBuilder{}
+	qb.Select(legacyInvoice{})
+	expected = `SELECT "INVOICE_ID","AMOUNT","DUE_DATE" FROM legacyinvoice`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+
+	AutoMapFields(nil)
+	qb = QueryBuilder{}
+	qb.Select(User{})
+	expected = `SELECT "id","username","password",(COUNT(col)) "total" FROM user`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+}
+
+func TestAutoMapFieldsInsert(t *testing.T) {
+	db := dbSetup()
+	AutoMapFields(SnakeCase)
+	defer AutoMapFields(nil)
+	if _, err := db.Exec("ALTER TABLE user ADD COLUMN email TEXT"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Insert(db, "user", User{Username: "mapped", Email: "a@b.com"}); err != nil {
+		t.Fatal(err)
+	}
+	var email string
+	if err := db.QueryRow("SELECT email FROM user WHERE username = 'mapped'").Scan(&email); err != nil {
+		t.Fatal(err)
+	}
+	if email != "a@b.com" {
+		t.Errorf("Expected the untagged Email to be inserted, got %q", email)
+	}
+}
@@ -0,0 +1,56 @@
+package goql
+
+import (
+	"context"
+	"fmt"
+)
+
+// Page is a page of results returned by Paginate.
+type Page struct {
+	// Items is the dest passed to Paginate
+	Items interface{}
+	// Total is the number of rows of the query without pagination
+	Total int64
+	// Page is the number of the page, starting at 1
+	Page    int
+	PerPage int
+}
+
+// Paginate runs the query for the given page, starting at 1, with
+// perPage rows per page and scans the rows into dest, which must be a
+// pointer to a slice of structs. The total number of rows is counted
+// with a second query without the ORDER BY and LIMIT clauses.
+func (qb *QueryBuilder) Paginate(db Queryer, page int, perPage int, dest interface{}) (*Page, error) {
+	return qb.PaginateContext(context.Background(), db, page, perPage, dest)
+}
+
+// PaginateContext is the same as Paginate but the queries are canceled
+// when ctx is done.
+func (qb *QueryBuilder) PaginateContext(ctx context.Context, db Queryer, page int, perPage int, dest interface{}) (*Page, error) {
+	if page < 1 || perPage < 1 {
+		return nil, fmt.Errorf("goql: invalid page %d with %d rows per page", page, perPage)
+	}
+	if _, _, err := qb.BuildWithArgs(); err != nil {
+		return nil, err
+	}
+	total, err := qb.CountContext(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	data := *qb
+	data.invalidate()
+	data.Limit(perPage).Offset((page - 1) * perPage)
+	sql, vals, err := data.BuildWithArgs()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(qb.tagged(ctx), sql, vals...)
+	if err != nil {
+		return nil, canceled(ctx, err)
+	}
+	if err := scanAll(rows, dest); err != nil {
+		return nil, canceled(ctx, err)
+	}
+	return &Page{Items: dest, Total: total, Page: page, PerPage: perPage}, nil
+}
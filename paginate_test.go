@@ -0,0 +1,32 @@
+package goql
+
+import (
+	"testing"
+)
+
+func TestPaginate(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`INSERT INTO user(username, password) VALUES('a', ''), ('b', ''), ('c', ''), ('d', ''), ('e', '')`)
+
+	users := []User{}
+	qb := QueryBuilder{}
+	qb.Select(User{}, IgnoreComputed(), Except("total")).Where("id > ?", 1).OrderBy("id DESC")
+	page, err := qb.Paginate(db, 2, 3, &users)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 4 || page.Page != 2 || page.PerPage != 3 {
+		t.Errorf("Unexpected page %+v", page)
+	}
+	if len(users) != 1 || users[0].Username != "b" {
+		t.Errorf("Unexpected users %+v", users)
+	}
+	if page.Items.(*[]User) != &users {
+		t.Error("Expected Items to be dest")
+	}
+
+	if _, err := qb.Paginate(db, 0, 3, &users); err == nil {
+		t.Error("Expected an error for page 0")
+	}
+}
@@ -0,0 +1,70 @@
+package goql
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// paginationStyle is the way a database expresses LIMIT and OFFSET.
+type paginationStyle int
+
+const (
+	// paginateLimit uses the Limit clause of the dialect, such as
+	// LIMIT n OFFSET m in Postgres
+	paginateLimit paginationStyle = iota
+	// paginateLimitRequired is the same as paginateLimit but an OFFSET
+	// needs a LIMIT, as in MySQL and SQLite
+	paginateLimitRequired
+	// paginateFetch uses the standard OFFSET m ROWS FETCH NEXT n ROWS ONLY
+	// supported by Oracle 12c and MSSQL 2012, as the Oracle dialect does
+	paginateFetch
+	// paginateTop uses SELECT TOP n when there is no offset and falls
+	// back to paginateFetch otherwise, as the MSSQL dialect does
+	paginateTop
+	// paginateRownum filters on the ROWNUM of a subquery, as older
+	// Oracle versions require, see Oracle11
+	paginateRownum
+)
+
+// maxLimit is the limit used when an OFFSET requires a LIMIT, it's the
+// largest value accepted by MySQL.
+const maxLimit = "18446744073709551615"
+
+// paginate adds the LIMIT and OFFSET of the query to sql, which is the
+// query without them, in the given style.
+func (qb *QueryBuilder) paginate(style paginationStyle, sql string) string {
+	limit, offset := qb.limit, qb.offset
+	if len(limit) <= 0 && len(offset) <= 0 {
+		return sql
+	}
+	switch style {
+	case paginateLimitRequired:
+		if len(limit) <= 0 {
+			limit = maxLimit
+		}
+	case paginateFetch, paginateTop:
+		if len(qb.orderBy) <= 0 && len(qb.defOrder) <= 0 {
+			// MSSQL requires an ORDER BY to use OFFSET
+			sql += " ORDER BY (SELECT NULL)"
+		}
+		return sql + " " + fetchLimit(limit, offset)
+	case paginateRownum:
+		return paginateRownumSQL(sql, limit, offset)
+	}
+	return sql + " " + qb.getDialect().Limit(limit, offset)
+}
+
+// paginateRownumSQL wraps sql in the subqueries needed to paginate it
+// with ROWNUM, which is assigned before ORDER BY is applied.
+func paginateRownumSQL(sql string, limit string, offset string) string {
+	from, _ := strconv.Atoi(offset)
+	wrapped := "SELECT goql_page.*, ROWNUM goql_rownum FROM (" + sql + ") goql_page"
+	if len(limit) > 0 {
+		if n, err := strconv.Atoi(limit); err == nil {
+			wrapped += fmt.Sprintf(" WHERE ROWNUM <= %d", from+n)
+		} else {
+			wrapped += fmt.Sprintf(" WHERE ROWNUM <= %d + %s", from, limit)
+		}
+	}
+	return fmt.Sprintf("SELECT * FROM (%s) WHERE goql_rownum > %d", wrapped, from)
+}
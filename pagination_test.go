@@ -0,0 +1,67 @@
+package goql
+
+import (
+	"testing"
+)
+
+func TestPaginationStyles(t *testing.T) {
+	qb := QueryBuilder{}
+	qb.Select("id").From("users").Limit(10).Offset(20)
+	cases := map[paginationStyle]string{
+		paginateLimit:         `SELECT id FROM users LIMIT 10 OFFSET 20`,
+		paginateLimitRequired: `SELECT id FROM users LIMIT 10 OFFSET 20`,
+		paginateFetch:         `SELECT id FROM users ORDER BY (SELECT NULL) OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY`,
+		paginateTop:           `SELECT id FROM users ORDER BY (SELECT NULL) OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY`,
+		paginateRownum:        `SELECT * FROM (SELECT goql_page.*, ROWNUM goql_rownum FROM (SELECT id FROM users) goql_page WHERE ROWNUM <= 30) WHERE goql_rownum > 20`,
+	}
+	for style, expected := range cases {
+		if sql := qb.buildPaginatedSQL(style); sql != expected {
+			t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+		}
+	}
+
+	qb = QueryBuilder{}
+	qb.Select("id").From("users").Distinct().OrderBy("id").Limit(5)
+	expected := `SELECT DISTINCT TOP 5 id FROM users ORDER BY id`
+	if sql := qb.buildPaginatedSQL(paginateTop); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+
+	qb = QueryBuilder{}
+	qb.Select("id").From("users").Offset(20)
+	expected = `SELECT id FROM users LIMIT 18446744073709551615 OFFSET 20`
+	if sql := qb.buildPaginatedSQL(paginateLimitRequired); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+}
+
+func TestPaginationDialects(t *testing.T) {
+	Testing = false
+	cases := []struct {
+		dialect  Dialect
+		offset   int
+		expected string
+	}{
+		{MSSQL, 0, `SELECT TOP 10 id FROM users WHERE name = @p1 ORDER BY id`},
+		{MSSQL, 20, `SELECT id FROM users WHERE name = @p1 ORDER BY id OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY`},
+		{Oracle, 20, `SELECT id FROM users WHERE name = :1 ORDER BY id OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY`},
+		{Oracle11, 20, `SELECT * FROM (SELECT goql_page.*, ROWNUM goql_rownum FROM (SELECT id FROM users WHERE name = :1 ORDER BY id) goql_page WHERE ROWNUM <= 30) WHERE goql_rownum > 20`},
+	}
+	for _, c := range cases {
+		qb := QueryBuilder{}
+		qb.UseDialect(c.dialect).Select("id").From("users").Where("name = $?", "a").OrderBy("id").Limit(10)
+		if c.offset > 0 {
+			qb.Offset(c.offset)
+		}
+		if sql := qb.Build(); sql != c.expected {
+			t.Errorf("%s: expected:\n%s\nGot:\n%s", c.dialect.Name(), c.expected, sql)
+		}
+	}
+
+	qb := QueryBuilder{}
+	qb.UseDialect(MSSQL).Select("id").From("users").Offset(5)
+	expected := `SELECT id FROM users ORDER BY (SELECT NULL) OFFSET 5 ROWS`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+}
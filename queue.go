@@ -0,0 +1,70 @@
+package goql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+)
+
+// ClaimRows implements the Postgres job queue pattern. It selects up to
+// limit rows of qb with FOR UPDATE SKIP LOCKED, so concurrent workers
+// never claim the same rows, and calls mark with them within the same
+// transaction so the claimed rows can be flagged, for example as
+// running, before the locks are released, for example
+//
+//	jobs := []Job{}
+//	err := goql.ClaimRows(db, pending, 10, &jobs, func(tx *sql.Tx, claimed interface{}) error {
+//		for _, job := range claimed.([]Job) {
+//			...
+//		}
+//		return nil
+//	})
+//
+// claimed is a slice of the type dest points to holding only the rows
+// claimed by the call. mark is not called when there are no rows to
+// claim. Once the transaction is committed the claimed rows are appended
+// to dest, which must be a pointer to a slice of structs mapped with the
+// "db" tag, and nothing is appended when it fails. qb is left untouched
+// and limit must be at least 1.
+func ClaimRows(db *sql.DB, qb *QueryBuilder, limit int, dest interface{}, mark func(tx *sql.Tx, claimed interface{}) error) error {
+	if limit < 1 {
+		return fmt.Errorf("ClaimRows: invalid limit %d", limit)
+	}
+	slice, _, err := destSlice(dest)
+	if err != nil {
+		return err
+	}
+	if d := qb.getDialect(); !lockable(d) {
+		// Without locks concurrent workers would claim the same rows
+		return fmt.Errorf("goql: the %s dialect can't claim rows as it doesn't support FOR UPDATE", d.Name())
+	}
+	claim := claimQuery(qb, limit)
+	claimed := reflect.New(slice.Type())
+	err = WithTxContext(context.Background(), db, func(tx *sql.Tx) error {
+		rows, err := claim.QueryContext(context.Background(), tx)
+		if err != nil {
+			return err
+		}
+		if err := scanAll(rows, claimed.Interface()); err != nil {
+			return err
+		}
+		if claimed.Elem().Len() <= 0 {
+			return nil
+		}
+		return mark(tx, claimed.Elem().Interface())
+	})
+	if err != nil {
+		return err
+	}
+	slice.Set(reflect.AppendSlice(slice, claimed.Elem()))
+	return nil
+}
+
+// claimQuery returns a copy of qb limited and locked for ClaimRows.
+func claimQuery(qb *QueryBuilder, limit int) *QueryBuilder {
+	claim := qb.clone()
+	claim.Limit(limit)
+	claim.ForUpdate(SkipLocked())
+	return claim
+}
@@ -0,0 +1,63 @@
+package goql
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestClaimQuery(t *testing.T) {
+	Testing = false
+	expected := `SELECT id FROM jobs WHERE status = $1 ORDER BY id LIMIT 10 FOR UPDATE SKIP LOCKED`
+	qb := QueryBuilder{}
+	qb.Select("id").From("jobs").Where("status = $?", "pending").OrderBy("id")
+	qb.Build()
+	if sql := claimQuery(&qb, 10).Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if sql := qb.Build(); sql != `SELECT id FROM jobs WHERE status = $1 ORDER BY id` {
+		t.Errorf("Expected the original query to be untouched, got %s", sql)
+	}
+}
+
+func TestClaimRows(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`INSERT INTO user (username, password) VALUES ('a', 'pending'), ('b', 'pending'), ('c', 'pending')`)
+	pending := &QueryBuilder{}
+	pending.Select(User{}, Except("total")).Where("password = $?", "pending").OrderBy("id")
+	mark := func(tx *sql.Tx, claimed interface{}) error {
+		for _, u := range claimed.([]User) {
+			if _, err := tx.Exec(`UPDATE user SET password = 'running' WHERE id = ?`, u.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	users := []User{{Username: "previous"}}
+	if err := ClaimRows(db, pending, 2, &users, mark); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 3 || users[1].Username != "a" || users[2].Username != "b" {
+		t.Errorf("Unexpected users %+v", users)
+	}
+	if err := ClaimRows(db, pending, 2, &users, mark); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 4 || users[3].Username != "c" {
+		t.Errorf("Unexpected users %+v", users)
+	}
+	// Nothing is left to claim, mark isn't called even though dest is not empty
+	err := ClaimRows(db, pending, 2, &users, func(tx *sql.Tx, claimed interface{}) error {
+		t.Errorf("Unexpected claimed rows %+v", claimed)
+		return nil
+	})
+	if err != nil || len(users) != 4 {
+		t.Errorf("Unexpected result %d %v", len(users), err)
+	}
+	for _, limit := range []int{0, -1} {
+		if err := ClaimRows(db, pending, limit, &users, mark); err == nil {
+			t.Errorf("Expected an error for the limit %d", limit)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package goql
+
+import (
+	"context"
+	"database/sql"
+	"log"
+)
+
+// KindRaw is the kind of the statements run with RawQuery.
+const KindRaw StatementKind = "raw"
+
+// RawStatement is a hand written statement, see RawQuery.
+type RawStatement struct {
+	db    Queryer
+	ctx   context.Context
+	query string
+	args  []interface{}
+}
+
+// RawQuery prepares a hand written statement for the cases the builder
+// can't express, it still gets scanned into structs mapped with the "db"
+// tag, logged and run through the hooks registered for KindRaw, for
+// example:
+// goql.RawQuery(db, "SELECT id, username FROM user WHERE id > $1", 10).ScanAll(&users)
+func RawQuery(db Queryer, qry string, args ...interface{}) *RawStatement {
+	return &RawStatement{db: db, ctx: context.Background(), query: qry, args: args}
+}
+
+// WithContext sets the context the statement is run with.
+func (r *RawStatement) WithContext(ctx context.Context) *RawStatement {
+	r.ctx = ctx
+	return r
+}
+
+// ScanAll runs the statement and scans every row into dest, which must
+// be a pointer to a slice of structs or of pointers to structs.
+func (r *RawStatement) ScanAll(dest interface{}) error {
+	return r.run(func(rows *sql.Rows) error {
+		return scanAll(rows, dest)
+	})
+}
+
+// ScanOne runs the statement and scans the first row into dest, which
+// must be a pointer to a struct. sql.ErrNoRows is returned when there
+// are no rows.
+func (r *RawStatement) ScanOne(dest interface{}) error {
+	return r.run(func(rows *sql.Rows) error {
+		return scanOne(rows, dest)
+	})
+}
+
+// Exec runs a statement that doesn't return rows.
+func (r *RawStatement) Exec() (sql.Result, error) {
+	e := &StatementEvent{Kind: KindRaw, Query: r.query, Args: r.args, Tag: QueryTagFrom(r.ctx)}
+	if err := runStatementHooks(false, e); err != nil {
+		return nil, err
+	}
+	result, err := r.db.ExecContext(r.ctx, r.query, r.args...)
+	return result, r.finish(e, err)
+}
+
+// run runs the statement and passes the rows to scan.
+func (r *RawStatement) run(scan func(rows *sql.Rows) error) error {
+	e := &StatementEvent{Kind: KindRaw, Query: r.query, Args: r.args, Tag: QueryTagFrom(r.ctx)}
+	if err := runStatementHooks(false, e); err != nil {
+		return err
+	}
+	rows, err := r.db.QueryContext(r.ctx, r.query, r.args...)
+	if err == nil {
+		err = scan(rows)
+	}
+	return r.finish(e, err)
+}
+
+// finish runs the after hooks and wraps the error of the statement.
+func (r *RawStatement) finish(e *StatementEvent, err error) error {
+	e.Err = err
+	if hookErr := runStatementHooks(true, e); hookErr != nil && err == nil {
+		return hookErr
+	}
+	if err = canceled(r.ctx, err); err == nil || err == sql.ErrNoRows || err == ErrCanceled {
+		return err
+	}
+	log.Println(err)
+	return &QueryError{Query: r.query, Err: err}
+}
+
+// QueryError is the error returned when a raw statement fails, it holds
+// the statement along with the error returned by the database.
+type QueryError struct {
+	Query string
+	Err   error
+}
+
+func (e *QueryError) Error() string {
+	return "goql: " + e.Err.Error() + " in query: " + e.Query
+}
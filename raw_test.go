@@ -0,0 +1,47 @@
+package goql
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestRawQueryScanning(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	defer resetStatementHooks()
+	queries := []string{}
+	AfterStatement(KindRaw, "", func(e *StatementEvent) error {
+		queries = append(queries, e.Query)
+		return nil
+	})
+
+	if _, err := RawQuery(db, "INSERT INTO user(username, password) VALUES(?, ?), (?, ?)", "a", "1", "b", "2").Exec(); err != nil {
+		t.Fatal(err)
+	}
+	users := []User{}
+	err := RawQuery(db, "SELECT id, username, password FROM user WHERE id >= ? ORDER BY id", 1).ScanAll(&users)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[1].Username != "b" {
+		t.Errorf("Unexpected users %+v", users)
+	}
+
+	user := User{}
+	if err := RawQuery(db, "SELECT id, username FROM user WHERE id = ?", 2).ScanOne(&user); err != nil {
+		t.Fatal(err)
+	}
+	if user.Username != "b" {
+		t.Errorf("Unexpected user %+v", user)
+	}
+	if err := RawQuery(db, "SELECT id FROM user WHERE id = ?", 3).ScanOne(&user); err != sql.ErrNoRows {
+		t.Errorf("Expected sql.ErrNoRows, got %v", err)
+	}
+	err = RawQuery(db, "SELECT nope FROM user").ScanAll(&users)
+	if qerr, ok := err.(*QueryError); !ok || qerr.Query != "SELECT nope FROM user" {
+		t.Errorf("Expected a QueryError, got %v", err)
+	}
+	if len(queries) != 5 {
+		t.Errorf("Expected the hooks to see 5 statements, got %d", len(queries))
+	}
+}
@@ -0,0 +1,62 @@
+package goql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ReadOnly makes BuildWithArgs, and so Query and QueryAndScan, fail when
+// the statement could write or lock rows, for code paths such as public
+// APIs or replicas that must never write. The check is made on the
+// generated SQL, WithReadOnlyTx can be used to also have the database
+// enforce it.
+func (qb *QueryBuilder) ReadOnly() (ret *QueryBuilder) {
+	ret = qb
+	qb.invalidate()
+	qb.readOnly = true
+	return
+}
+
+// quotedSQL matches the string literals and quoted identifiers of a
+// statement, which are ignored when looking for writes.
+var quotedSQL = regexp.MustCompile(`'(?:[^']|'')*'|"(?:[^"]|"")*"`)
+
+// writeKeywords matches the keywords of the statements that write.
+var writeKeywords = regexp.MustCompile(`(?i)\b(INSERT|UPDATE|DELETE|MERGE|UPSERT|TRUNCATE|DROP|ALTER|CREATE|GRANT|REVOKE|INTO|FOR\s+(NO\s+KEY\s+)?UPDATE|FOR\s+(KEY\s+)?SHARE)\b`)
+
+// leadingComments matches the comments that start a statement, such as
+// the hints of UseIndex.
+var leadingComments = regexp.MustCompile(`^(\s*/\*(?s:.*?)\*/)+`)
+
+// checkReadOnly returns an error when qry is not a plain SELECT.
+func checkReadOnly(qry string) error {
+	stripped := strings.TrimSpace(quotedSQL.ReplaceAllString(leadingComments.ReplaceAllString(qry, ""), "''"))
+	upper := strings.ToUpper(stripped)
+	if !strings.HasPrefix(upper, "SELECT") && !strings.HasPrefix(upper, "WITH") {
+		return fmt.Errorf("goql: read only query is not a SELECT")
+	}
+	if match := writeKeywords.FindString(stripped); len(match) > 0 {
+		return fmt.Errorf("goql: read only query contains %s", strings.ToUpper(match))
+	}
+	return nil
+}
+
+// WithReadOnlyTx runs fn inside a read only transaction, so the database
+// rejects any write made by fn. The transaction is always rolled back.
+func WithReadOnlyTx(Db *sql.DB, fn func(tx *sql.Tx) error) error {
+	return WithReadOnlyTxContext(context.Background(), Db, fn)
+}
+
+// WithReadOnlyTxContext is the same as WithReadOnlyTx but the
+// transaction is canceled when ctx is done.
+func WithReadOnlyTxContext(ctx context.Context, Db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := Db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	return fn(tx)
+}
@@ -0,0 +1,38 @@
+package goql
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestReadOnly(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.Select("id").From("users").Where("status = 'delete' AND \"update\" = $?", 1).ReadOnly()
+	if _, _, err := qb.BuildWithArgs(); err != nil {
+		t.Errorf("Unexpected error %s", err)
+	}
+
+	qb.ForUpdate()
+	if _, _, err := qb.BuildWithArgs(); err == nil {
+		t.Error("Expected an error for a locking query")
+	}
+
+	qb = QueryBuilder{}
+	qb.Select("id INTO backup").From("users").ReadOnly()
+	if _, _, err := qb.BuildWithArgs(); err == nil {
+		t.Error("Expected an error for SELECT INTO")
+	}
+}
+
+func TestWithReadOnlyTx(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	err := WithReadOnlyTx(db, func(tx *sql.Tx) error {
+		var count int
+		return tx.QueryRow("SELECT COUNT(*) FROM user").Scan(&count)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,145 @@
+package goql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// QueryResultSets executes a statement that returns multiple result sets,
+// such as a MSSQL stored procedure or MySQL multi statements, and scans
+// each result set into its own dest, see ScanResultSets.
+func QueryResultSets(db Queryer, qry string, args []interface{}, dests ...interface{}) error {
+	rows, err := db.QueryContext(context.Background(), qry, args...)
+	if err != nil {
+		return err
+	}
+	return ScanResultSets(rows, dests...)
+}
+
+// ScanResultSets scans the result sets of rows in order, one for each of
+// dests, which must be pointers to slices of structs mapped with the "db"
+// tag. An error is returned when there are fewer result sets than dests.
+// The rows are closed once all of them have been read.
+func ScanResultSets(rows *sql.Rows, dests ...interface{}) error {
+	defer rows.Close()
+	for i, dest := range dests {
+		if i > 0 && !rows.NextResultSet() {
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			return fmt.Errorf("goql: expected %d result sets, got %d", len(dests), i)
+		}
+		if err := scanRows(rows, dest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanAll scans every row into dest, which must be a pointer to a slice
+// of structs or of pointers to structs mapped with the "db" tag.
+// The rows are closed once all of them have been read.
+func scanAll(rows *sql.Rows, dest interface{}) error {
+	defer rows.Close()
+	return scanRows(rows, dest)
+}
+
+// scanRows scans the rows of the current result set into dest.
+func scanRows(rows *sql.Rows, dest interface{}) error {
+	slice, elemType, err := destSlice(dest)
+	if err != nil {
+		return err
+	}
+	isPtr := elemType.Kind() == reflect.Ptr
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+	checkFields(elemType)
+	for rows.Next() {
+		elem := reflect.New(elemType)
+		if err := scanStruct(rows, elem.Interface()); err != nil {
+			return err
+		}
+		if !isPtr {
+			elem = elem.Elem()
+		}
+		slice.Set(reflect.Append(slice, elem))
+	}
+	return rows.Err()
+}
+
+// scanOne scans the first row of rows into obj, which must be a pointer
+// to a struct, and closes them. sql.ErrNoRows is returned when there
+// are no rows, as sql.Row does.
+func scanOne(rows *sql.Rows, obj interface{}) error {
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	if t := reflect.TypeOf(obj); t.Kind() == reflect.Ptr {
+		checkFields(t.Elem())
+	}
+	if err := scanStruct(rows, obj); err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+// scanStruct scans the current row into the fields of obj and runs its
+// scan hooks. When the row has a different number of columns than the
+// struct, as it happens with SelectExcept, the columns are matched to
+// the fields by name.
+func scanStruct(rows *sql.Rows, obj interface{}) error {
+	pointers := GetFieldPointers(obj)
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	if len(cols) != len(pointers) {
+		if pointers, err = columnPointers(obj, cols); err != nil {
+			return err
+		}
+	}
+	if err := rows.Scan(pointers...); err != nil {
+		return err
+	}
+	return runScanHooks(obj)
+}
+
+// columnPointers returns the pointers to the fields of obj mapped to
+// each one of cols.
+func columnPointers(obj interface{}, cols []string) ([]interface{}, error) {
+	t := reflect.TypeOf(obj).Elem()
+	v := reflect.ValueOf(obj).Elem()
+	byName := map[string]interface{}{}
+	for i := 0; i <= t.NumField()-1; i++ {
+		if name := columnName(t, t.Field(i)); len(name) > 0 {
+			byName[name] = v.Field(i).Addr().Interface()
+		}
+	}
+	pointers := make([]interface{}, len(cols))
+	for i, col := range cols {
+		ptr, ok := byName[col]
+		if !ok {
+			return nil, fmt.Errorf("goql: no field of %s is mapped to column %q", t.Name(), col)
+		}
+		pointers[i] = ptr
+	}
+	return pointers, nil
+}
+
+// destSlice validates that dest is a pointer to a slice and returns the
+// slice value and the type of its elements.
+func destSlice(dest interface{}) (reflect.Value, reflect.Type, error) {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		return reflect.Value{}, nil, errors.New("dest must be a pointer to a slice")
+	}
+	return v.Elem(), v.Elem().Type().Elem(), nil
+}
@@ -0,0 +1,35 @@
+package goql
+
+import (
+	"testing"
+)
+
+type usernameRow struct {
+	Username string `db:"username"`
+}
+
+func TestScanResultSetsRequiresAllSets(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	Insert(db, "user", User{Username: "john"})
+
+	first := []usernameRow{}
+	second := []usernameRow{}
+	err := QueryResultSets(db, "SELECT username FROM user", nil, &first, &second)
+	if err == nil {
+		t.Error("Expected an error when there are fewer result sets than destinations")
+	}
+	if len(first) != 1 || first[0].Username != "john" {
+		t.Errorf("Expected the first result set to be scanned, got %v", first)
+	}
+}
+
+func TestScanResultSetsRejectsInvalidDest(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+
+	var dest usernameRow
+	if err := QueryResultSets(db, "SELECT username FROM user", nil, &dest); err == nil {
+		t.Error("Expected an error scanning into a non slice")
+	}
+}
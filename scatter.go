@@ -0,0 +1,75 @@
+package goql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// MergeOptions tells ExecuteAcross how the results of each database
+// must be combined.
+type MergeOptions struct {
+	// Less sorts the merged results when set, a and b are elements of dest.
+	Less func(a, b interface{}) bool
+	// Limit caps the number of merged results, 0 means no limit.
+	Limit int
+}
+
+// ExecuteAcross runs the query built by qb on every database in dbs
+// concurrently and scans the merged results into dest, which must be a
+// pointer to a slice of structs mapped with the "db" tag. Any previous
+// content of dest is replaced.
+// Results are appended in the same order as dbs unless opts.Less is set,
+// in which case they are sorted before applying opts.Limit. Note that the
+// LIMIT of qb, if any, is applied by each database individually.
+func ExecuteAcross(dbs []Queryer, qb *QueryBuilder, dest interface{}, opts *MergeOptions) error {
+	slice, _, err := destSlice(dest)
+	if err != nil {
+		return err
+	}
+	if opts == nil {
+		opts = &MergeOptions{}
+	}
+	qry, vals, err := qb.BuildWithArgs()
+	if err != nil {
+		return err
+	}
+
+	results := make([]reflect.Value, len(dbs))
+	errs := make([]error, len(dbs))
+	wg := sync.WaitGroup{}
+	for i, db := range dbs {
+		wg.Add(1)
+		go func(i int, db Queryer) {
+			defer wg.Done()
+			part := reflect.New(slice.Type())
+			rows, err := db.QueryContext(context.Background(), qry, vals...)
+			if err == nil {
+				err = scanAll(rows, part.Interface())
+			}
+			results[i] = part.Elem()
+			errs[i] = err
+		}(i, db)
+	}
+	wg.Wait()
+
+	merged := reflect.MakeSlice(slice.Type(), 0, 0)
+	for i, part := range results {
+		if errs[i] != nil {
+			return fmt.Errorf("goql: query %d of %d failed: %s", i+1, len(dbs), errs[i])
+		}
+		merged = reflect.AppendSlice(merged, part)
+	}
+	if opts.Less != nil {
+		sort.SliceStable(merged.Interface(), func(i, j int) bool {
+			return opts.Less(merged.Index(i).Interface(), merged.Index(j).Interface())
+		})
+	}
+	if opts.Limit > 0 && merged.Len() > opts.Limit {
+		merged = merged.Slice(0, opts.Limit)
+	}
+	slice.Set(merged)
+	return nil
+}
@@ -0,0 +1,56 @@
+package goql
+
+import (
+	"testing"
+)
+
+type shardUser struct {
+	ID       int64  `db:"id" pk:"true"`
+	Username string `db:"username"`
+}
+
+func TestExecuteAcross(t *testing.T) {
+	shards := []Queryer{}
+	for _, names := range [][]string{{"bob", "dave"}, {"alice", "carol"}} {
+		db := dbSetup()
+		defer db.Close()
+		for _, name := range names {
+			Insert(db, "user", User{Username: name})
+		}
+		shards = append(shards, db)
+	}
+
+	qb := QueryBuilder{}
+	qb.Select("id, username").From("user")
+	users := []shardUser{}
+	err := ExecuteAcross(shards, &qb, &users, &MergeOptions{
+		Less: func(a, b interface{}) bool {
+			return a.(shardUser).Username < b.(shardUser).Username
+		},
+		Limit: 3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"alice", "bob", "carol"}
+	if len(users) != len(expected) {
+		t.Fatalf("Expected %d users, got %v", len(expected), users)
+	}
+	for i, name := range expected {
+		if users[i].Username != name {
+			t.Errorf("Expected %s at position %d, got %s", name, i, users[i].Username)
+		}
+	}
+}
+
+func TestExecuteAcrossReturnsErrors(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+
+	qb := QueryBuilder{}
+	qb.Select("id, username").From("missing_table")
+	users := []shardUser{}
+	if err := ExecuteAcross([]Queryer{db}, &qb, &users, nil); err == nil {
+		t.Error("Expected an error querying a missing table")
+	}
+}
@@ -0,0 +1,57 @@
+package goql
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var (
+	schemasMu sync.RWMutex
+	schemas   = map[reflect.Type]string{}
+)
+
+// RegisterSchema registers the database or schema the table of model
+// lives in, so Select(model) reads from the fully qualified table and
+// the structs joined with the join tag are qualified too, which is what
+// a reporting instance consolidating several databases needs, for
+// example
+// goql.RegisterSchema(Invoice{}, "billing")
+// makes Select(Invoice{}) generate SELECT ... FROM billing.invoice
+// schema can hold several parts, such as "warehouse.billing". It's meant
+// to be called on start up.
+func RegisterSchema(model interface{}, schema string) {
+	schemasMu.Lock()
+	defer schemasMu.Unlock()
+	schemas[modelType(model)] = schema
+}
+
+// Table returns the table of model as Select guesses it, qualified with
+// the schema registered with RegisterSchema, for the joins and
+// subqueries written by hand, for example
+// queryBuilder.InnerJoin(goql.Table(Invoice{}) + " i ON i.user_id = u.id")
+func Table(model interface{}) string {
+	t := modelType(model)
+	return qualifyTable(t, strings.ToLower(t.Name()))
+}
+
+// qualifyTable prefixes table, the table of the model t, with the schema
+// registered for t unless it's already qualified.
+func qualifyTable(t reflect.Type, table string) string {
+	schemasMu.RLock()
+	schema, ok := schemas[t]
+	schemasMu.RUnlock()
+	if !ok || strings.Contains(table, ".") {
+		return table
+	}
+	return schema + "." + table
+}
+
+// modelType returns the struct type of model, which can be a pointer.
+func modelType(model interface{}) reflect.Type {
+	t := reflect.TypeOf(model)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,37 @@
+package goql
+
+import (
+	"reflect"
+	"testing"
+)
+
+type invoice struct {
+	ID     int64    `db:"id" pk:"true"`
+	Amount float64  `db:"amount"`
+	Client customer `join:"customer,customer_id"`
+}
+
+type customer struct {
+	ID   int64  `db:"id" pk:"true"`
+	Name string `db:"name"`
+}
+
+func TestRegisterSchema(t *testing.T) {
+	Testing = false
+	RegisterSchema(invoice{}, "billing")
+	RegisterSchema(&customer{}, "crm.public")
+	defer func() { schemas = map[reflect.Type]string{} }()
+
+	qb := QueryBuilder{}
+	qb.Select(invoice{}).Where(`"invoice"."amount" > $?`, 10)
+	expected := `SELECT "invoice"."id","invoice"."amount","client"."id" "client_id","client"."name" "client_name" FROM billing.invoice INNER JOIN crm.public.customer "client" ON "client"."id" = "invoice"."customer_id" WHERE "invoice"."amount" > $1`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if table := Table(&customer{}); table != "crm.public.customer" {
+		t.Errorf("Unexpected table %s", table)
+	}
+	if table := Table(User{}); table != "user" {
+		t.Errorf("Unexpected table %s", table)
+	}
+}
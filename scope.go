@@ -0,0 +1,86 @@
+package goql
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// scope is a condition registered with DefaultScope.
+type scope struct {
+	where string
+	vals  []interface{}
+}
+
+var (
+	scopesMu sync.RWMutex
+	scopes   = map[interface{}][]scope{}
+)
+
+// DefaultScope registers a condition that Select adds to the WHERE
+// clause of the queries selecting from the table of a struct, target
+// being either the struct or the name of the table, so rows such as the
+// soft deleted ones are left out without repeating the condition in
+// every query, for example
+// goql.DefaultScope(Invoice{}, "deleted_at IS NULL")
+// makes Select(Invoice{}) generate SELECT ... FROM invoice WHERE deleted_at IS NULL
+// The scopes are joined with AND to the conditions of the query and
+// Unscoped leaves them out. It's meant to be called on start up.
+func DefaultScope(target interface{}, where string, vals ...interface{}) {
+	key := scopeKey(target)
+	scopesMu.Lock()
+	defer scopesMu.Unlock()
+	scopes[key] = append(scopes[key], scope{where: where, vals: vals})
+}
+
+// Unscoped leaves out the conditions registered with DefaultScope, for
+// example to read the soft deleted rows too.
+func (qb *QueryBuilder) Unscoped() (ret *QueryBuilder) {
+	ret = qb
+	qb.invalidate()
+	qb.unscoped = true
+	qb.scopes = nil
+	qb.setValues("scope", nil)
+	return
+}
+
+// scopeKey returns the key the scopes of target are registered with.
+func scopeKey(target interface{}) interface{} {
+	if table, ok := target.(string); ok {
+		return table
+	}
+	t := modelType(target)
+	if t.Kind() != reflect.Struct {
+		panic("DefaultScope: target must be a struct or a table name")
+	}
+	return t
+}
+
+// applyScopes adds the scopes registered for the struct t and for table,
+// its table, to the query.
+func (qb *QueryBuilder) applyScopes(t reflect.Type, table string) {
+	if qb.unscoped {
+		return
+	}
+	scopesMu.RLock()
+	registered := append(append([]scope{}, scopes[t]...), scopes[table]...)
+	scopesMu.RUnlock()
+	for _, s := range registered {
+		conds := &QueryBuilder{}
+		conds.Where(s.where, s.vals...)
+		if conds.err != nil {
+			qb.addError(conds.err)
+		}
+		qb.scopes = append(qb.scopes, parenthesizeOr(conds.where[0].expr))
+		qb.addValues("scope", conds.values["where"])
+	}
+}
+
+// parenthesizeOr wraps expr in parentheses when it has an OR that would
+// otherwise take the conditions joined to it with AND.
+func parenthesizeOr(expr string) string {
+	if strings.Contains(strings.ToUpper(expr), " OR ") {
+		return "(" + expr + ")"
+	}
+	return expr
+}
@@ -0,0 +1,68 @@
+package goql
+
+import (
+	"fmt"
+	"testing"
+)
+
+type scopedPost struct {
+	ID    int64  `db:"id" pk:"true"`
+	Title string `db:"title"`
+}
+
+func TestDefaultScope(t *testing.T) {
+	Testing = false
+	DefaultScope(scopedPost{}, "deleted_at IS NULL")
+	DefaultScope("scopedpost", "tenant_id = $? OR public", 7)
+	defer func() { scopes = map[interface{}][]scope{} }()
+
+	qb := QueryBuilder{}
+	qb.Select(scopedPost{}).Where("title = $?", "go").OrWhere("id = $?", 1)
+	expected := `SELECT "id","title" FROM scopedpost WHERE deleted_at IS NULL AND (tenant_id = $1 OR public) AND (title = $2 OR id = $3)`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if vals := qb.GetValues(); fmt.Sprint(vals) != "[7 go 1]" {
+		t.Errorf("Unexpected values %v", vals)
+	}
+
+	qb = QueryBuilder{}
+	qb.Select(scopedPost{}).Where("title = $?", "go").Unscoped()
+	expected = `SELECT "id","title" FROM scopedpost WHERE title = $1`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if vals := qb.GetValues(); fmt.Sprint(vals) != "[go]" {
+		t.Errorf("Unexpected values %v", vals)
+	}
+
+	qb = QueryBuilder{}
+	qb.Unscoped().Select(scopedPost{})
+	if sql := qb.Build(); sql != `SELECT "id","title" FROM scopedpost` {
+		t.Errorf("Unexpected query %s", sql)
+	}
+
+	qb = QueryBuilder{}
+	qb.Select("id").From("scopedpost")
+	if sql := qb.Build(); sql != `SELECT id FROM scopedpost` {
+		t.Errorf("Scopes must only be applied by Select(struct), got %s", sql)
+	}
+}
+
+func TestDefaultScopeCount(t *testing.T) {
+	db := dbSetup()
+	db.Exec(`INSERT INTO user (username, password) VALUES ('john', 'x'), ('jane', 'y'), ('jim', 'x')`)
+	DefaultScope(User{}, "password = $?", "x")
+	defer func() { scopes = map[interface{}][]scope{} }()
+
+	qb := QueryBuilder{}
+	n, err := qb.SelectExcept(User{}, "total").Count(db)
+	if err != nil || n != 2 {
+		t.Errorf("Expected 2 rows, got %d %v", n, err)
+	}
+	qb = QueryBuilder{}
+	n, err = qb.SelectExcept(User{}, "total").Unscoped().Count(db)
+	if err != nil || n != 3 {
+		t.Errorf("Expected 3 rows, got %d %v", n, err)
+	}
+}
@@ -0,0 +1,168 @@
+package goql
+
+import (
+	"context"
+	"database/sql"
+	"log"
+)
+
+// SessionSetup runs the statements that set up the session of a
+// connection, see Session.
+type SessionSetup func(ctx context.Context, conn Queryer) error
+
+// SessionDB is a *sql.DB whose connections are set up by a SessionSetup
+// each time goql checks one out, see Session.
+type SessionDB struct {
+	db    *sql.DB
+	setup SessionSetup
+}
+
+// Session returns a Queryer running each statement, and each
+// transaction started by WithTx, on a connection of db set up by setup
+// right before, so session settings such as the time zone, the
+// search_path or the role hold whichever pooled connection is used and
+// whatever the previous user of the connection changed, for example
+//
+//	db := goql.Session(pool, goql.SessionStatements(
+//		"SET TIME ZONE 'UTC'",
+//		"SET search_path TO billing, public",
+//	))
+//	err := queryBuilder.QueryAndScanContext(ctx, db, &invoice)
+//
+// The statements of setup run inside the transactions started by
+// WithTx, so SET LOCAL can be used there.
+func Session(db *sql.DB, setup SessionSetup) *SessionDB {
+	return &SessionDB{db: db, setup: setup}
+}
+
+// SessionStatements returns a SessionSetup running stmts in order.
+func SessionStatements(stmts ...string) SessionSetup {
+	return func(ctx context.Context, conn Queryer) error {
+		for _, stmt := range stmts {
+			if _, err := conn.ExecContext(ctx, stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// DB returns the pool of s.
+func (s *SessionDB) DB() *sql.DB {
+	return s.db
+}
+
+// ExecContext implements Queryer.
+func (s *SessionDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	conn, err := s.checkout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return conn.ExecContext(ctx, query, args...)
+}
+
+// QueryContext implements Queryer, the connection is released once the
+// rows are closed.
+func (s *SessionDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	conn, err := s.checkout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// Closing the connection waits for the rows to be closed
+	go conn.Close()
+	return rows, nil
+}
+
+// QueryRowContext implements Queryer, the connection is released once
+// the row is scanned. As a sql.Row can't be built with an error, when
+// the session can't be set up the error is logged and the row fails
+// with context.Canceled without running the statement. The methods of
+// goql, such as Count or Exists, return the error of the setup instead.
+func (s *SessionDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	conn, err := s.checkout(ctx)
+	if err != nil {
+		log.Println(err)
+		canceledCtx, cancel := context.WithCancel(ctx)
+		cancel()
+		return s.db.QueryRowContext(canceledCtx, query, args...)
+	}
+	row := conn.QueryRowContext(ctx, query, args...)
+	go conn.Close()
+	return row
+}
+
+// queryRow implements rowQueryer, returning the error of the setup.
+func (s *SessionDB) queryRow(ctx context.Context, query string, args ...interface{}) rowScanner {
+	conn, err := s.checkout(ctx)
+	if err != nil {
+		return errRow{err: err}
+	}
+	row := conn.QueryRowContext(ctx, query, args...)
+	go conn.Close()
+	return row
+}
+
+// BeginTx starts a transaction on a connection of the pool and sets it
+// up.
+func (s *SessionDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	tx, err := s.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.setup(ctx, tx); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return tx, nil
+}
+
+// checkout checks out a connection of the pool and sets it up.
+func (s *SessionDB) checkout(ctx context.Context) (*sql.Conn, error) {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, canceled(ctx, err)
+	}
+	if err := s.setup(ctx, conn); err != nil {
+		conn.Close()
+		return nil, canceled(ctx, err)
+	}
+	return conn, nil
+}
+
+// rowScanner is the Scan method of *sql.Row.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// rowQueryer is implemented by the Queryers that can fail before they
+// run a statement returning a row, so the error can be returned by Scan
+// as *sql.Row can't be built with one.
+type rowQueryer interface {
+	queryRow(ctx context.Context, query string, args ...interface{}) rowScanner
+}
+
+// errRow is a row whose Scan fails with err.
+type errRow struct {
+	err error
+}
+
+// Scan implements rowScanner.
+func (r errRow) Scan(dest ...interface{}) error {
+	return r.err
+}
+
+// queryRow runs query on db, which is expected to return a row. goql
+// uses it instead of QueryRowContext so the errors of a rowQueryer are
+// not lost.
+func queryRow(ctx context.Context, db Queryer, query string, args ...interface{}) rowScanner {
+	if q, ok := db.(rowQueryer); ok {
+		return q.queryRow(ctx, query, args...)
+	}
+	return db.QueryRowContext(ctx, query, args...)
+}
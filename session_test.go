@@ -0,0 +1,120 @@
+package goql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+func TestSession(t *testing.T) {
+	pool := dbSetup()
+	defer pool.Close()
+	pool.SetMaxOpenConns(1)
+	setups := 0
+	db := Session(pool, func(ctx context.Context, conn Queryer) error {
+		setups++
+		return SessionStatements("PRAGMA case_sensitive_like = ON")(ctx, conn)
+	})
+	caseSensitive := func(q Queryer) bool {
+		var matches bool
+		if err := q.QueryRowContext(context.Background(), "SELECT 'a' LIKE 'A'").Scan(&matches); err != nil {
+			t.Fatal(err)
+		}
+		return !matches
+	}
+
+	if !caseSensitive(db) {
+		t.Error("Expected the session to be set up")
+	}
+	// Another user of the pool changes the setting of the connection
+	pool.Exec("PRAGMA case_sensitive_like = OFF")
+	if caseSensitive(pool) {
+		t.Error("Expected the setting to be changed")
+	}
+	qb := QueryBuilder{}
+	qb.Select("id").From("user").Where("username LIKE $?", "JOHN")
+	pool.Exec(`INSERT INTO user (username, password) VALUES ('john', 'x')`)
+	if n, err := qb.CountContext(context.Background(), db); err != nil || n != 0 {
+		t.Errorf("Expected no rows with a case sensitive LIKE, got %d %v", n, err)
+	}
+	rows, err := qb.QueryContext(context.Background(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows.Next() {
+		t.Error("Expected no rows")
+	}
+	rows.Close()
+
+	pool.Exec("PRAGMA case_sensitive_like = OFF")
+	err = WithTx(db, func(tx *sql.Tx) error {
+		if !caseSensitive(tx) {
+			t.Error("Expected the transaction to be set up")
+		}
+		_, err := InsertContext(context.Background(), tx, "user", User{Username: "jane"})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if setups != 4 {
+		t.Errorf("Expected 4 setups, got %d", setups)
+	}
+
+	failing := Session(pool, func(ctx context.Context, conn Queryer) error {
+		return errors.New("no role")
+	})
+	if _, err := failing.ExecContext(context.Background(), "DELETE FROM user"); err == nil || err.Error() != "no role" {
+		t.Errorf("Expected the error of the setup, got %v", err)
+	}
+	if err := WithTx(failing, func(tx *sql.Tx) error { return nil }); err == nil {
+		t.Error("Expected the error of the setup")
+	}
+	var n int
+	if err := failing.QueryRowContext(context.Background(), "SELECT COUNT(*) FROM user").Scan(&n); err == nil {
+		t.Error("Expected the row to fail")
+	}
+	if n, _ := (&QueryBuilder{}).Select("id").From("user").Count(pool); n != 2 {
+		t.Errorf("Expected 2 rows, got %d", n)
+	}
+}
+
+func TestSessionSetupErrors(t *testing.T) {
+	pool := dbSetup()
+	defer pool.Close()
+	pool.SetMaxOpenConns(1)
+	pool.Exec(`INSERT INTO user (username, password) VALUES ('john', 'x')`)
+	errSetup := errors.New("no role")
+	db := Session(pool, func(ctx context.Context, conn Queryer) error {
+		return errSetup
+	})
+
+	qb := QueryBuilder{}
+	qb.Select("id").From("user")
+	if _, err := qb.Count(db); err != errSetup {
+		t.Errorf("Count: expected the error of the setup, got %v", err)
+	}
+	if _, err := qb.Exists(db); err != errSetup {
+		t.Errorf("Exists: expected the error of the setup, got %v", err)
+	}
+	if _, err := qb.SumInt(db, "id"); err != errSetup {
+		t.Errorf("SumInt: expected the error of the setup, got %v", err)
+	}
+
+	called := false
+	err := WithTx(db, func(tx *sql.Tx) error {
+		called = true
+		return nil
+	})
+	if err != errSetup {
+		t.Errorf("WithTx: expected the error of the setup, got %v", err)
+	}
+	if called {
+		t.Error("The transaction must not run when the setup fails")
+	}
+	// The transaction was rolled back and its connection released
+	if n, err := qb.Count(pool); err != nil || n != 1 {
+		t.Errorf("Expected 1 row, got %d %v", n, err)
+	}
+}
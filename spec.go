@@ -0,0 +1,251 @@
+package goql
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// identifierPattern matches plain, optionally qualified, identifiers.
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// specTypes holds the param types allowed in a FilterSpec.
+var specTypes = map[string]bool{
+	"": true, "string": true, "int": true, "float": true, "bool": true, "time": true,
+}
+
+// specOperators holds the operators allowed in a FilterSpec.
+var specOperators = map[string]bool{
+	"=": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true, "LIKE": true,
+}
+
+// QuerySpec is a declarative query definition that can be loaded from
+// configuration with LoadQueries and compiled into a QueryBuilder with
+// Builder. The fields carry yaml tags too so specs can be decoded from
+// YAML with LoadQueriesWith and any YAML package.
+type QuerySpec struct {
+	Name    string       `json:"name" yaml:"name"`
+	Table   string       `json:"table" yaml:"table"`
+	Columns []string     `json:"columns" yaml:"columns"`
+	Filters []FilterSpec `json:"filters" yaml:"filters"`
+	// Sort holds the columns to order by optionally followed by asc or desc
+	Sort  []string `json:"sort" yaml:"sort"`
+	Limit int      `json:"limit" yaml:"limit"`
+}
+
+// FilterSpec is a condition of a QuerySpec whose value is taken from the
+// parameter Param when the query is built. Type can be "string", "int",
+// "float", "bool" or "time" (RFC 3339) and defaults to "string". Filters
+// whose parameter is not given are skipped unless they are Required.
+type FilterSpec struct {
+	Column   string `json:"column" yaml:"column"`
+	Op       string `json:"op" yaml:"op"`
+	Param    string `json:"param" yaml:"param"`
+	Type     string `json:"type" yaml:"type"`
+	Required bool   `json:"required" yaml:"required"`
+}
+
+// LoadQueries reads a JSON list of query specs from r and validates them
+// so that invalid definitions are caught at startup. The specs are
+// returned by name.
+func LoadQueries(r io.Reader) (map[string]*QuerySpec, error) {
+	specs := []*QuerySpec{}
+	if err := json.NewDecoder(r).Decode(&specs); err != nil {
+		return nil, err
+	}
+	return indexQueries(specs)
+}
+
+// LoadQueriesWith is the same as LoadQueries but the list of specs is
+// decoded by unmarshal, so they can be written in YAML without goql
+// depending on a YAML package, for example
+// specs, err := goql.LoadQueriesWith(file, yaml.Unmarshal)
+func LoadQueriesWith(r io.Reader, unmarshal func(data []byte, v interface{}) error) (map[string]*QuerySpec, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	specs := []*QuerySpec{}
+	if err := unmarshal(data, &specs); err != nil {
+		return nil, err
+	}
+	return indexQueries(specs)
+}
+
+// indexQueries validates specs and returns them by name.
+func indexQueries(specs []*QuerySpec) (map[string]*QuerySpec, error) {
+	result := map[string]*QuerySpec{}
+	for _, spec := range specs {
+		if err := spec.Validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := result[spec.Name]; ok {
+			return nil, fmt.Errorf("goql: duplicated query %q", spec.Name)
+		}
+		result[spec.Name] = spec
+	}
+	return result, nil
+}
+
+// Validate checks that the spec only references valid identifiers,
+// operators and parameter types.
+func (s *QuerySpec) Validate() error {
+	if len(s.Name) <= 0 {
+		return fmt.Errorf("goql: query without name")
+	}
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("goql: query %q: %s", s.Name, fmt.Sprintf(format, args...))
+	}
+	if !identifierPattern.MatchString(s.Table) {
+		return invalid("invalid table %q", s.Table)
+	}
+	for _, col := range s.Columns {
+		if !identifierPattern.MatchString(col) {
+			return invalid("invalid column %q", col)
+		}
+	}
+	params := map[string]bool{}
+	for _, f := range s.Filters {
+		if !identifierPattern.MatchString(f.Column) {
+			return invalid("invalid filter column %q", f.Column)
+		}
+		if !specOperators[strings.ToUpper(f.Op)] {
+			return invalid("invalid operator %q", f.Op)
+		}
+		if len(f.Param) <= 0 || params[f.Param] {
+			return invalid("missing or duplicated param %q", f.Param)
+		}
+		params[f.Param] = true
+		if !specTypes[f.Type] {
+			return invalid("invalid type %q for param %q", f.Type, f.Param)
+		}
+	}
+	for _, sort := range s.Sort {
+		if _, err := parseSpecSort(sort); err != nil {
+			return invalid("%s", err)
+		}
+	}
+	if s.Limit < 0 {
+		return invalid("negative limit")
+	}
+	return nil
+}
+
+// Builder compiles the spec into a QueryBuilder binding params to the
+// filters. Unknown params, missing required params and values that can't
+// be converted to the filter type are rejected.
+func (s *QuerySpec) Builder(params map[string]interface{}) (*QueryBuilder, error) {
+	for name := range params {
+		known := false
+		for _, f := range s.Filters {
+			known = known || f.Param == name
+		}
+		if !known {
+			return nil, fmt.Errorf("goql: query %q: unknown param %q", s.Name, name)
+		}
+	}
+
+	qb := &QueryBuilder{}
+	if len(s.Columns) > 0 {
+		qb.Select(strings.Join(s.Columns, ", "))
+	}
+	qb.From(s.Table)
+	for _, f := range s.Filters {
+		raw, ok := params[f.Param]
+		if !ok {
+			if f.Required {
+				return nil, fmt.Errorf("goql: query %q: missing param %q", s.Name, f.Param)
+			}
+			continue
+		}
+		val, err := convertParam(f.Type, raw)
+		if err != nil {
+			return nil, fmt.Errorf("goql: query %q: param %q: %s", s.Name, f.Param, err)
+		}
+		qb.Where(fmt.Sprintf("%s %s $?", f.Column, strings.ToUpper(f.Op)), val)
+	}
+	for _, sort := range s.Sort {
+		order, _ := parseSpecSort(sort)
+		qb.OrderBy(order)
+	}
+	if s.Limit > 0 {
+		qb.Limit(s.Limit)
+	}
+	return qb, nil
+}
+
+// parseSpecSort validates a sort entry such as "created_at desc".
+func parseSpecSort(sort string) (string, error) {
+	parts := strings.Fields(sort)
+	if len(parts) < 1 || len(parts) > 2 || !identifierPattern.MatchString(parts[0]) {
+		return "", fmt.Errorf("invalid sort %q", sort)
+	}
+	if len(parts) == 2 {
+		dir := strings.ToUpper(parts[1])
+		if dir != "ASC" && dir != "DESC" {
+			return "", fmt.Errorf("invalid sort direction %q", parts[1])
+		}
+		return parts[0] + " " + dir, nil
+	}
+	return parts[0], nil
+}
+
+// convertParam converts a param value, either a go value of the right
+// kind or a string such as the ones found in a query string, to typ.
+func convertParam(typ string, val interface{}) (interface{}, error) {
+	str, isString := val.(string)
+	switch typ {
+	case "", "string":
+		if !isString {
+			return nil, fmt.Errorf("expected a string, got %T", val)
+		}
+		return str, nil
+	case "int":
+		switch v := val.(type) {
+		case int:
+			return int64(v), nil
+		case int64:
+			return v, nil
+		case float64:
+			if v != float64(int64(v)) {
+				return nil, fmt.Errorf("expected an integer, got %v", v)
+			}
+			return int64(v), nil
+		case string:
+			return strconv.ParseInt(v, 10, 64)
+		}
+	case "float":
+		switch v := val.(type) {
+		case float64:
+			return v, nil
+		case int:
+			return float64(v), nil
+		case int64:
+			return float64(v), nil
+		case string:
+			return strconv.ParseFloat(v, 64)
+		}
+	case "bool":
+		switch v := val.(type) {
+		case bool:
+			return v, nil
+		case string:
+			return strconv.ParseBool(v)
+		}
+	case "time":
+		switch v := val.(type) {
+		case time.Time:
+			return v, nil
+		case string:
+			return time.Parse(time.RFC3339, v)
+		}
+	default:
+		return nil, fmt.Errorf("unknown param type %q", typ)
+	}
+	return nil, fmt.Errorf("expected a %s, got %T", typ, val)
+}
@@ -0,0 +1,102 @@
+package goql
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const specsJSON = `[{
+	"name": "recent_orders",
+	"table": "orders",
+	"columns": ["id", "total"],
+	"filters": [
+		{"column": "status", "op": "=", "param": "status", "required": true},
+		{"column": "total", "op": ">=", "param": "min_total", "type": "float"}
+	],
+	"sort": ["created_at desc"],
+	"limit": 50
+}]`
+
+func TestLoadQueriesAndBuild(t *testing.T) {
+	Testing = false
+	specs, err := LoadQueries(strings.NewReader(specsJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	qb, err := specs["recent_orders"].Builder(map[string]interface{}{"status": "paid", "min_total": "10.5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `SELECT id, total FROM orders WHERE status = $1 AND total >= $2 ORDER BY created_at DESC LIMIT 50`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if vals := qb.GetValues(); len(vals) != 2 || vals[1] != 10.5 {
+		t.Errorf("Unexpected values %v", vals)
+	}
+}
+
+func TestQuerySpecRejectsInvalidParams(t *testing.T) {
+	specs, err := LoadQueries(strings.NewReader(specsJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := specs["recent_orders"]
+	invalid := []map[string]interface{}{
+		{},
+		{"status": "paid", "unknown": 1},
+		{"status": "paid", "min_total": "abc"},
+		{"status": 10},
+	}
+	for _, params := range invalid {
+		if _, err := spec.Builder(params); err == nil {
+			t.Errorf("Expected an error for params %v", params)
+		}
+	}
+}
+
+func TestLoadQueriesValidatesSpecs(t *testing.T) {
+	invalid := []string{
+		`[{"name": "q", "table": "users; DROP TABLE users"}]`,
+		`[{"name": "q", "table": "users", "filters": [{"column": "id", "op": "OR 1=1", "param": "id"}]}]`,
+		`[{"name": "q", "table": "users", "filters": [{"column": "id", "op": "=", "param": "id", "type": "uuid"}]}]`,
+		`[{"name": "q", "table": "users", "sort": ["id sideways"]}]`,
+		`[{"name": "q", "table": "users"}, {"name": "q", "table": "users"}]`,
+	}
+	for _, spec := range invalid {
+		if _, err := LoadQueries(strings.NewReader(spec)); err == nil {
+			t.Errorf("Expected an error loading %s", spec)
+		}
+	}
+}
+
+func TestLoadQueriesWith(t *testing.T) {
+	const specsYAML = "- name: users\n  table: users\n  columns: [id]\n"
+	decoded := []*QuerySpec{{Name: "users", Table: "users", Columns: []string{"id"}}}
+	unmarshal := func(data []byte, v interface{}) error {
+		if string(data) != specsYAML {
+			t.Errorf("Unexpected data %q", data)
+		}
+		*v.(*[]*QuerySpec) = decoded
+		return nil
+	}
+	specs, err := LoadQueriesWith(strings.NewReader(specsYAML), unmarshal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if specs["users"] != decoded[0] {
+		t.Errorf("Unexpected specs %v", specs)
+	}
+
+	decoded = append(decoded, &QuerySpec{Name: "users", Table: "accounts"})
+	if _, err := LoadQueriesWith(strings.NewReader(specsYAML), unmarshal); err == nil {
+		t.Error("Expected an error for duplicated queries")
+	}
+	failing := func(data []byte, v interface{}) error {
+		return errors.New("invalid yaml")
+	}
+	if _, err := LoadQueriesWith(strings.NewReader(specsYAML), failing); err == nil || err.Error() != "invalid yaml" {
+		t.Errorf("Expected the error of unmarshal, got %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package goql
+
+import (
+	"context"
+	"strings"
+)
+
+// TagComments appends the tag set with Tag to the SQL of the queries as
+// a comment, such as SELECT ... /* tag='billing-report' */, which shows
+// up in the database logs and statistics, for example pg_stat_activity.
+var TagComments = false
+
+type tagKey struct{}
+
+// Tag names the application workload the query belongs to, such as
+// "billing-report", so the load of the database can be attributed to it
+// instead of to the raw SQL. The queries run by the query builder pass
+// the tag in their context, where it can be read with QueryTagFrom by
+// instrumented drivers, and it's added to their SQL when TagComments is
+// set.
+func (qb *QueryBuilder) Tag(name string) (ret *QueryBuilder) {
+	ret = qb
+	qb.invalidate()
+	qb.tag = name
+	return
+}
+
+// QueryTag returns the name set with Tag.
+func (qb *QueryBuilder) QueryTag() string {
+	return qb.tag
+}
+
+// WithQueryTag returns a copy of ctx carrying tag, the statements run
+// with it by Insert, Update, Delete, RawQuery and the rest of the
+// helpers report it in the StatementEvent of their hooks, for example
+// goql.InsertContext(goql.WithQueryTag(ctx, "signup"), db, "users", user)
+func WithQueryTag(ctx context.Context, tag string) context.Context {
+	return context.WithValue(ctx, tagKey{}, tag)
+}
+
+// QueryTagFrom returns the tag carried by ctx, empty when there is none.
+func QueryTagFrom(ctx context.Context) string {
+	tag, _ := ctx.Value(tagKey{}).(string)
+	return tag
+}
+
+// tagged returns ctx carrying the tag of the query, if any.
+func (qb *QueryBuilder) tagged(ctx context.Context) context.Context {
+	if len(qb.tag) <= 0 {
+		return ctx
+	}
+	return WithQueryTag(ctx, qb.tag)
+}
+
+// Comment adds text, such as "trace_id=abc", to a comment appended to
+// the SQL of the query, in the style of sqlcommenter, so the slow
+// queries found in the database logs can be matched with the traces of
+// the application, for example
+// queryBuilder.Comment("trace_id=abc").Comment("route=/users")
+// generates SELECT ... /* trace_id=abc,route=/users */
+// The comments are joined with commas and the tag is added to them when
+// TagComments is set. */ is removed from text, until none is left, so
+// it can't end the comment.
+func (qb *QueryBuilder) Comment(text string) (ret *QueryBuilder) {
+	ret = qb
+	qb.invalidate()
+	qb.comments = append(qb.comments, commentText(text))
+	return
+}
+
+// tagComment returns the comment appended to the SQL of the query with
+// the texts added with Comment and the tag when TagComments is set.
+func (qb *QueryBuilder) tagComment() string {
+	parts := append([]string{}, qb.comments...)
+	if TagComments && len(qb.tag) > 0 {
+		tag := strings.Replace(commentText(qb.tag), "'", "''", -1)
+		parts = append(parts, "tag='"+tag+"'")
+	}
+	if len(parts) <= 0 {
+		return ""
+	}
+	return " /* " + strings.Join(parts, ",") + " */"
+}
+
+// commentText removes */ from text until none is left, since removing it
+// once lets "**//" rebuild it.
+func commentText(text string) string {
+	for strings.Contains(text, "*/") {
+		text = strings.Replace(text, "*/", "", -1)
+	}
+	return text
+}
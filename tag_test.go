@@ -0,0 +1,102 @@
+package goql
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestTag(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.Select("id").From("invoices").Where("paid = $?", false).Tag("billing-report")
+	expected := `SELECT id FROM invoices WHERE paid = $1`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	TagComments = true
+	defer func() { TagComments = false }()
+	expected = `SELECT id FROM invoices WHERE paid = $1 /* tag='billing-report' */`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	qb.Tag("it's */")
+	if sql := qb.Build(); sql != `SELECT id FROM invoices WHERE paid = $1 /* tag='it''s ' */` {
+		t.Errorf("Unexpected comment in %s", sql)
+	}
+}
+
+// taggedQueryer records the tags of the queries it runs.
+type taggedQueryer struct {
+	Queryer
+	tags []string
+}
+
+func (q *taggedQueryer) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	q.tags = append(q.tags, QueryTagFrom(ctx))
+	return q.Queryer.QueryContext(ctx, query, args...)
+}
+
+func TestQueryTagPropagation(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	beforeHooks, afterHooks = nil, nil
+	defer func() { beforeHooks, afterHooks = nil, nil }()
+
+	q := &taggedQueryer{Queryer: db}
+	qb := QueryBuilder{}
+	qb.Select("id").From("user").Tag("signup")
+	rows, err := qb.QueryContext(context.Background(), q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if len(q.tags) != 1 || q.tags[0] != "signup" {
+		t.Errorf("Expected the query to carry its tag, got %v", q.tags)
+	}
+
+	var tag string
+	AfterStatement(KindInsert, "user", func(e *StatementEvent) error {
+		tag = e.Tag
+		return nil
+	})
+	if _, err := InsertContext(WithQueryTag(context.Background(), "signup"), db, "user", User{Username: "john"}); err != nil {
+		t.Fatal(err)
+	}
+	if tag != "signup" {
+		t.Errorf("Expected the statement event to carry the tag, got %q", tag)
+	}
+}
+
+func TestComment(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.Select("id").From("invoices").Where("paid = $?", false).Comment("trace_id=abc")
+	expected := `SELECT id FROM invoices WHERE paid = $1 /* trace_id=abc */`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	qb.Comment("route=/x*/;DROP").Tag("billing**//")
+	TagComments = true
+	defer func() { TagComments = false }()
+	expected = `SELECT id FROM invoices WHERE paid = $1 /* trace_id=abc,route=/x;DROP,tag='billing' */`
+	if sql, _, err := qb.BuildWithArgs(); err != nil || sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s %v", expected, sql, err)
+	}
+	expected = `SELECT COUNT(*) FROM invoices WHERE paid = $1 /* trace_id=abc,route=/x;DROP,tag='billing' */`
+	if sql := qb.BuildCount(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+
+	comment := (&QueryBuilder{}).Select("id").From("t").Comment("x **//; DROP TABLE users; --").Build()
+	expected = `SELECT id FROM t /* x ; DROP TABLE users; -- */`
+	if comment != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, comment)
+	}
+
+	db := dbSetup()
+	defer db.Close()
+	if _, err := (&QueryBuilder{}).Select("id").From("user").Comment("trace_id=abc").Count(db); err != nil {
+		t.Error(err)
+	}
+}
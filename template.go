@@ -0,0 +1,174 @@
+package goql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Param is a parameter of a query template, see Prepare.
+type Param string
+
+// queryTemplate is a query registered with Prepare.
+type queryTemplate struct {
+	sql string
+	// args holds the values of the query, the ones of type Param are
+	// replaced by the parameters passed to Execute
+	args   []interface{}
+	params map[string]bool
+}
+
+type stmtKey struct {
+	db   *sql.DB
+	name string
+}
+
+var (
+	templatesMu sync.RWMutex
+	templates   = map[string]*queryTemplate{}
+	stmts       = map[stmtKey]*sql.Stmt{}
+)
+
+// Prepare registers the query built by factory as the template name, it
+// is meant to be called at init time. The values of the query that are
+// a Param are replaced by the parameters passed to Execute, for example
+//
+//	goql.Prepare("userByEmail", func() *goql.QueryBuilder {
+//		qb := &goql.QueryBuilder{}
+//		return qb.Select(User{}).Where("email = $?", goql.Param("email"))
+//	})
+//
+// The query is built and validated once, Prepare panics when it's
+// invalid or when name is already registered.
+func Prepare(name string, factory func() *QueryBuilder) {
+	qry, args, err := factory().BuildWithArgs()
+	if err != nil {
+		panic(fmt.Sprintf("goql: invalid template %s: %s", name, err))
+	}
+	tmpl := &queryTemplate{sql: qry, args: args, params: map[string]bool{}}
+	for _, arg := range args {
+		if param, ok := arg.(Param); ok {
+			tmpl.params[string(param)] = true
+		}
+	}
+	templatesMu.Lock()
+	defer templatesMu.Unlock()
+	if _, ok := templates[name]; ok {
+		panic(fmt.Sprintf("goql: template %s is already registered", name))
+	}
+	templates[name] = tmpl
+}
+
+// Execute runs the template name registered with Prepare with the
+// given parameters, which must match the ones of the template. When db
+// is a *sql.DB the statement is prepared once and reused. The prepared
+// statements, and db, are kept referenced until ClosePrepared is called
+// with db, which should be done before closing a *sql.DB that isn't
+// used for the whole life of the program.
+func Execute(db Queryer, name string, params map[string]interface{}) (*sql.Rows, error) {
+	return ExecuteContext(context.Background(), db, name, params)
+}
+
+// ExecuteContext is the same as Execute but the query is canceled when
+// ctx is done.
+func ExecuteContext(ctx context.Context, db Queryer, name string, params map[string]interface{}) (*sql.Rows, error) {
+	templatesMu.RLock()
+	tmpl, ok := templates[name]
+	templatesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("goql: unknown template %s", name)
+	}
+	args, err := tmpl.bind(params)
+	if err != nil {
+		return nil, fmt.Errorf("goql: template %s: %s", name, err)
+	}
+	conn, ok := db.(*sql.DB)
+	if !ok {
+		return db.QueryContext(ctx, tmpl.sql, args...)
+	}
+	stmt, err := preparedStmt(ctx, conn, name, tmpl.sql)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.QueryContext(ctx, args...)
+}
+
+// bind returns the values of the template with its parameters replaced.
+func (tmpl *queryTemplate) bind(params map[string]interface{}) ([]interface{}, error) {
+	unknown := []string{}
+	for name := range params {
+		if !tmpl.params[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown parameters %s", strings.Join(unknown, ", "))
+	}
+	args := make([]interface{}, len(tmpl.args))
+	for i, arg := range tmpl.args {
+		param, ok := arg.(Param)
+		if !ok {
+			args[i] = arg
+			continue
+		}
+		val, ok := params[string(param)]
+		if !ok {
+			return nil, fmt.Errorf("missing parameter %s", param)
+		}
+		args[i] = val
+	}
+	return args, nil
+}
+
+// preparedStmt returns the statement of the template name prepared on db.
+func preparedStmt(ctx context.Context, db *sql.DB, name string, qry string) (*sql.Stmt, error) {
+	key := stmtKey{db, name}
+	templatesMu.RLock()
+	stmt, ok := stmts[key]
+	templatesMu.RUnlock()
+	if ok {
+		return stmt, nil
+	}
+	templatesMu.Lock()
+	defer templatesMu.Unlock()
+	if stmt, ok := stmts[key]; ok {
+		return stmt, nil
+	}
+	stmt, err := db.PrepareContext(ctx, qry)
+	if err != nil {
+		return nil, err
+	}
+	stmts[key] = stmt
+	return stmt, nil
+}
+
+// ClosePrepared closes the statements prepared by Execute on db and
+// forgets them, so the pool can be closed and released, for example
+//
+//	defer db.Close()
+//	defer goql.ClosePrepared(db)
+//
+// The statements are prepared again if db is used by Execute later. The
+// first error closing a statement is returned.
+func ClosePrepared(db *sql.DB) error {
+	templatesMu.Lock()
+	closing := []*sql.Stmt{}
+	for key, stmt := range stmts {
+		if key.db == db {
+			closing = append(closing, stmt)
+			delete(stmts, key)
+		}
+	}
+	templatesMu.Unlock()
+	var err error
+	for _, stmt := range closing {
+		if closeErr := stmt.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,94 @@
+package goql
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestPrepareAndExecute(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`INSERT INTO user(username, password) VALUES('a', 'x'), ('b', 'y')`)
+
+	Prepare("testUserByName", func() *QueryBuilder {
+		qb := &QueryBuilder{}
+		return qb.Select("id").From("user").Where("username = ? AND id > ?", Param("name"), 0)
+	})
+	for i := 0; i < 2; i++ {
+		rows, err := Execute(db, "testUserByName", map[string]interface{}{"name": "b"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var id int64
+		if !rows.Next() {
+			t.Fatal("Expected a row")
+		}
+		rows.Scan(&id)
+		rows.Close()
+		if id != 2 {
+			t.Errorf("Expected id 2, got %d", id)
+		}
+	}
+
+	if _, err := Execute(db, "testUserByName", map[string]interface{}{"name": "b", "other": 1}); err == nil {
+		t.Error("Expected an error for an unknown parameter")
+	}
+	if _, err := Execute(db, "testUserByName", nil); err == nil {
+		t.Error("Expected an error for a missing parameter")
+	}
+	if _, err := Execute(db, "nope", nil); err == nil {
+		t.Error("Expected an error for an unknown template")
+	}
+
+	defer func() {
+		if rec := recover(); rec == nil {
+			t.Error("Expected to panic for a duplicated template")
+		}
+	}()
+	Prepare("testUserByName", func() *QueryBuilder {
+		qb := &QueryBuilder{}
+		return qb.Select("id").From("user")
+	})
+}
+
+func TestClosePrepared(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	other := dbSetup()
+	defer other.Close()
+	Prepare("testUserCount", func() *QueryBuilder {
+		qb := &QueryBuilder{}
+		return qb.Select("COUNT(*)").From("user").Where("id > ?", Param("min"))
+	})
+	prepared := func(db *sql.DB) bool {
+		templatesMu.RLock()
+		defer templatesMu.RUnlock()
+		_, ok := stmts[stmtKey{db, "testUserCount"}]
+		return ok
+	}
+	for _, pool := range []*sql.DB{db, other} {
+		rows, err := Execute(pool, "testUserCount", map[string]interface{}{"min": 0})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+	}
+
+	if err := ClosePrepared(db); err != nil {
+		t.Fatal(err)
+	}
+	if prepared(db) || !prepared(other) {
+		t.Error("Expected only the statements of db to be released")
+	}
+	// The statement is prepared again when db is used later
+	rows, err := Execute(db, "testUserCount", map[string]interface{}{"min": 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if !prepared(db) {
+		t.Error("Expected the statement to be prepared again")
+	}
+	ClosePrepared(db)
+	ClosePrepared(other)
+}
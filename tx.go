@@ -0,0 +1,236 @@
+package goql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// savepointCounter is used to generate unique savepoint names.
+var savepointCounter uint64
+
+// WithTx runs fn inside a transaction. The transaction is committed
+// when fn returns nil and rolled back when it returns an error or panics.
+// Db must be either a *sql.DB, a *SessionDB or a *sql.Tx, when a *sql.Tx
+// is passed the call is nested in the existing transaction using a
+// savepoint, so only the work done by fn is rolled back on error.
+func WithTx(Db interface{}, fn func(tx *sql.Tx) error) error {
+	return WithTxContext(context.Background(), Db, fn)
+}
+
+// WithTxContext is the same as WithTx but the transaction is rolled back
+// when ctx is done before it's committed.
+func WithTxContext(ctx context.Context, Db interface{}, fn func(tx *sql.Tx) error) (err error) {
+	if getDbType(Db) == dbTypeTx {
+		return withSavepoint(ctx, Db.(*sql.Tx), fn)
+	}
+	var tx *sql.Tx
+	if session, ok := Db.(*SessionDB); ok {
+		tx, err = session.BeginTx(ctx, nil)
+	} else {
+		tx, err = Db.(*sql.DB).BeginTx(ctx, nil)
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rec := recover(); rec != nil {
+			tx.Rollback()
+			panic(rec)
+		}
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+	return fn(tx)
+}
+
+// RetryStatement runs fn, which usually runs a single statement, in a
+// savepoint of tx so when it fails only its work is rolled back and tx
+// can still be used. In Postgres a failed statement aborts the whole
+// transaction otherwise, which makes it impossible to handle errors
+// such as unique violations, for example
+//
+//	err := goql.RetryStatement(tx, func(tx *sql.Tx) error {
+//		_, err := goql.Insert(tx, "user", user)
+//		return err
+//	})
+//	if isUniqueViolation(err) {
+//		_, err = goql.Update(tx, "user", user)
+//	}
+//
+// The error returned by fn is returned as it is.
+func RetryStatement(tx *sql.Tx, fn func(tx *sql.Tx) error) error {
+	return RetryStatementContext(context.Background(), tx, fn)
+}
+
+// RetryStatementContext is the same as RetryStatement but the savepoint
+// statements are canceled when ctx is done.
+func RetryStatementContext(ctx context.Context, tx *sql.Tx, fn func(tx *sql.Tx) error) error {
+	return withSavepoint(ctx, tx, fn)
+}
+
+func withSavepoint(ctx context.Context, tx *sql.Tx, fn func(tx *sql.Tx) error) (err error) {
+	name := fmt.Sprintf("goql_sp_%d", atomic.AddUint64(&savepointCounter, 1))
+	if _, err = tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+	defer func() {
+		if rec := recover(); rec != nil {
+			tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+			panic(rec)
+		}
+		if err != nil {
+			tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+			return
+		}
+		_, err = tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	}()
+	return fn(tx)
+}
+
+// WithSchema runs fn with the Postgres search_path set to schema, which
+// is useful for schema per tenant deployments.
+// Db must be either a *sql.DB, in which case a new transaction is started
+// with WithTx, or a *sql.Tx. The search_path is set with SET LOCAL so it
+// never outlives the transaction and can't leak to other users of the
+// pooled connection. When running on an existing *sql.Tx the previous
+// search_path is restored once fn returns, even when it fails.
+func WithSchema(Db interface{}, schema string, fn func(tx *sql.Tx) error) error {
+	return WithSchemaContext(context.Background(), Db, schema, fn)
+}
+
+// WithSchemaContext is the same as WithSchema but the statements are
+// canceled when ctx is done.
+func WithSchemaContext(ctx context.Context, Db interface{}, schema string, fn func(tx *sql.Tx) error) error {
+	if getDbType(Db) == dbTypeDb {
+		return WithTxContext(ctx, Db, func(tx *sql.Tx) error {
+			return WithSchemaContext(ctx, tx, schema, fn)
+		})
+	}
+
+	tx := Db.(*sql.Tx)
+	return withSchema(ctx, tx, schema, func() error {
+		return fn(tx)
+	})
+}
+
+// withSchema sets the search_path of the transaction q to schema while
+// fn runs and restores the previous one once fn returns, whatever its
+// error, since q may be the transaction of a caller that carries on
+// after fn fails. The error of the restore is only returned when fn
+// succeeds, after a failed statement Postgres rejects it until the
+// transaction or a savepoint is rolled back, which undoes SET LOCAL too.
+func withSchema(ctx context.Context, q Queryer, schema string, fn func() error) (err error) {
+	var previous string
+	if err = queryRow(ctx, q, `SELECT current_setting('search_path')`).Scan(&previous); err != nil {
+		return err
+	}
+	if _, err = q.ExecContext(ctx, `SET LOCAL search_path TO `+quoteIdent(schema)); err != nil {
+		return err
+	}
+	defer func() {
+		_, restoreErr := q.ExecContext(ctx, `SELECT set_config('search_path', $1, true)`, previous)
+		if err == nil {
+			err = restoreErr
+		}
+	}()
+	return fn()
+}
+
+// quoteIdent quotes an identifier such as a table or schema name.
+func quoteIdent(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// SetConstraintsDeferred defers the checks of the constraints names,
+// all the deferrable ones when none is given, to the commit of tx, so
+// rows referencing each other, such as the nodes of a graph with
+// circular foreign keys, can be inserted in any order, for example
+//
+//	err := goql.WithTx(db, func(tx *sql.Tx) error {
+//		if err := goql.SetConstraintsDeferred(tx, "employee_manager_fkey"); err != nil {
+//			return err
+//		}
+//		...
+//	})
+//
+// Postgres only defers the constraints declared DEFERRABLE. SQLite
+// defers all the foreign keys with PRAGMA defer_foreign_keys, whatever
+// names are given, and MySQL doesn't support it.
+func SetConstraintsDeferred(tx *sql.Tx, names ...string) error {
+	return SetConstraintsDeferredContext(context.Background(), tx, names...)
+}
+
+// SetConstraintsDeferredContext is the same as SetConstraintsDeferred
+// but the statement is canceled when ctx is done.
+func SetConstraintsDeferredContext(ctx context.Context, tx *sql.Tx, names ...string) error {
+	return setConstraints(ctx, tx, "DEFERRED", names)
+}
+
+// SetConstraintsImmediate checks the constraints names, all of them when
+// none is given, at the end of each statement again, as they are by
+// default, see SetConstraintsDeferred. The rows changed while they were
+// deferred are checked right away, in SQLite with PRAGMA foreign_key_check.
+func SetConstraintsImmediate(tx *sql.Tx, names ...string) error {
+	return SetConstraintsImmediateContext(context.Background(), tx, names...)
+}
+
+// SetConstraintsImmediateContext is the same as SetConstraintsImmediate
+// but the statement is canceled when ctx is done.
+func SetConstraintsImmediateContext(ctx context.Context, tx *sql.Tx, names ...string) error {
+	return setConstraints(ctx, tx, "IMMEDIATE", names)
+}
+
+func setConstraints(ctx context.Context, tx *sql.Tx, mode string, names []string) error {
+	d := activeDialect()
+	qry, err := constraintsSQL(d, mode, names)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, qry); err != nil || mode != "IMMEDIATE" || d.Name() != SQLite.Name() {
+		return err
+	}
+	// SQLite doesn't check the rows changed while the keys were deferred
+	var table, parent string
+	var rowid, fkid sql.NullInt64
+	err = tx.QueryRowContext(ctx, "PRAGMA foreign_key_check").Scan(&table, &rowid, &parent, &fkid)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("goql: row %d of %s violates its foreign key to %s", rowid.Int64, table, parent)
+}
+
+// constraintsSQL returns the statement setting the constraints names to
+// mode, DEFERRED or IMMEDIATE, in the dialect d.
+func constraintsSQL(d Dialect, mode string, names []string) (string, error) {
+	switch d.Name() {
+	case SQLite.Name():
+		if mode == "DEFERRED" {
+			return "PRAGMA defer_foreign_keys = ON", nil
+		}
+		return "PRAGMA defer_foreign_keys = OFF", nil
+	case MySQL.Name():
+		return "", fmt.Errorf("goql: the %s dialect doesn't support deferred constraints", d.Name())
+	}
+	constraints := "ALL"
+	if len(names) > 0 {
+		quoted := make([]string, len(names))
+		for i, name := range names {
+			parts := strings.Split(name, ".")
+			for j, part := range parts {
+				parts[j] = quoteIdent(part)
+			}
+			quoted[i] = strings.Join(parts, ".")
+		}
+		constraints = strings.Join(quoted, ", ")
+	}
+	return "SET CONSTRAINTS " + constraints + " " + mode, nil
+}
@@ -0,0 +1,222 @@
+package goql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWithTxCommits(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+
+	err := WithTx(db, func(tx *sql.Tx) error {
+		_, err := Insert(tx, "user", User{Username: "john", Password: "doe"})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM user").Scan(&count)
+	if count != 1 {
+		t.Error("Expected 1 row, got", count)
+	}
+}
+
+func TestWithTxRollsBackOnError(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+
+	expected := errors.New("failed")
+	err := WithTx(db, func(tx *sql.Tx) error {
+		if _, err := Insert(tx, "user", User{Username: "john", Password: "doe"}); err != nil {
+			return err
+		}
+		return expected
+	})
+	if err != expected {
+		t.Errorf("Expected %v, got %v", expected, err)
+	}
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM user").Scan(&count)
+	if count != 0 {
+		t.Error("Expected the insert to be rolled back, got", count)
+	}
+}
+
+func TestQuoteIdent(t *testing.T) {
+	if q := quoteIdent(`tenant"42`); q != `"tenant""42"` {
+		t.Errorf("Unexpected quoted identifier %s", q)
+	}
+}
+
+func TestRetryStatementKeepsTheTransaction(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+
+	err := WithTx(db, func(tx *sql.Tx) error {
+		if _, err := Upsert(tx, "user", User{ID: 1, Username: "john"}); err != nil {
+			return err
+		}
+		err := RetryStatement(tx, func(tx *sql.Tx) error {
+			_, err := tx.Exec(`INSERT INTO user(id, username) VALUES(1, 'jane')`)
+			return err
+		})
+		if err == nil {
+			t.Error("Expected the duplicated insert to fail")
+		}
+		_, err = Update(tx, "user", User{ID: 1, Username: "jane"})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var username string
+	db.QueryRow("SELECT username FROM user WHERE id = 1").Scan(&username)
+	if username != "jane" {
+		t.Errorf("Expected jane, got %s", username)
+	}
+}
+
+func TestConstraintsSQL(t *testing.T) {
+	tests := []struct {
+		d        Dialect
+		mode     string
+		names    []string
+		expected string
+	}{
+		{Postgres, "DEFERRED", nil, `SET CONSTRAINTS ALL DEFERRED`},
+		{Postgres, "DEFERRED", []string{"employee_manager_fkey", "hr.team_lead_fkey"}, `SET CONSTRAINTS "employee_manager_fkey", "hr"."team_lead_fkey" DEFERRED`},
+		{Postgres, "IMMEDIATE", []string{"employee_manager_fkey"}, `SET CONSTRAINTS "employee_manager_fkey" IMMEDIATE`},
+		{SQLite, "DEFERRED", []string{"employee_manager_fkey"}, `PRAGMA defer_foreign_keys = ON`},
+		{SQLite, "IMMEDIATE", nil, `PRAGMA defer_foreign_keys = OFF`},
+	}
+	for _, test := range tests {
+		if qry, err := constraintsSQL(test.d, test.mode, test.names); err != nil || qry != test.expected {
+			t.Errorf("Expected %s, got %s %v", test.expected, qry, err)
+		}
+	}
+	if _, err := constraintsSQL(MySQL, "DEFERRED", nil); err == nil {
+		t.Error("Expected an error for MySQL")
+	}
+}
+
+func TestSetConstraintsDeferred(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	db.Exec(`PRAGMA foreign_keys = ON`)
+	db.Exec(`CREATE TABLE employee(id INTEGER PRIMARY KEY, manager_id INTEGER REFERENCES employee(id))`)
+
+	insertPair := func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`INSERT INTO employee (id, manager_id) VALUES (1, 2)`); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`INSERT INTO employee (id, manager_id) VALUES (2, 1)`)
+		return err
+	}
+	if err := WithTx(db, insertPair); err == nil {
+		t.Fatal("Expected the foreign key to be checked right away")
+	}
+	err := WithTx(db, func(tx *sql.Tx) error {
+		if err := SetConstraintsDeferred(tx); err != nil {
+			return err
+		}
+		return insertPair(tx)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = WithTx(db, func(tx *sql.Tx) error {
+		if err := SetConstraintsDeferred(tx); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO employee (id, manager_id) VALUES (3, 4)`); err != nil {
+			return err
+		}
+		return SetConstraintsImmediate(tx)
+	})
+	if err == nil {
+		t.Error("Expected the foreign key to fail once immediate")
+	}
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM employee").Scan(&count)
+	if count != 2 {
+		t.Errorf("Expected 2 rows, got %d", count)
+	}
+}
+
+// recordingQueryer records the statements run on it, the rows it
+// returns hold row.
+type recordingQueryer struct {
+	stmts []string
+	args  [][]interface{}
+	row   string
+}
+
+func (q *recordingQueryer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	q.stmts = append(q.stmts, query)
+	q.args = append(q.args, args)
+	return driver.RowsAffected(0), nil
+}
+
+func (q *recordingQueryer) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func (q *recordingQueryer) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+func (q *recordingQueryer) queryRow(ctx context.Context, query string, args ...interface{}) rowScanner {
+	q.stmts = append(q.stmts, query)
+	q.args = append(q.args, args)
+	return stringRow(q.row)
+}
+
+type stringRow string
+
+func (r stringRow) Scan(dest ...interface{}) error {
+	*dest[0].(*string) = string(r)
+	return nil
+}
+
+func TestWithSchemaStatements(t *testing.T) {
+	q := &recordingQueryer{row: `"$user", public`}
+	var during int
+	err := withSchema(context.Background(), q, `tenant "a"`, func() error {
+		during = len(q.stmts)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`SELECT current_setting('search_path')`,
+		`SET LOCAL search_path TO "tenant ""a"""`,
+		`SELECT set_config('search_path', $1, true)`,
+	}
+	if strings.Join(q.stmts, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected:\n%s\nGot:\n%s", strings.Join(expected, "\n"), strings.Join(q.stmts, "\n"))
+	}
+	if during != 2 {
+		t.Errorf("Expected fn to run after SET LOCAL, it ran after %d statements", during)
+	}
+	if len(q.args[2]) != 1 || q.args[2][0] != `"$user", public` {
+		t.Errorf("Expected the previous search_path to be restored, got %v", q.args[2])
+	}
+
+	// The search_path is restored when fn fails as the transaction may
+	// belong to a caller that carries on
+	q = &recordingQueryer{row: "public"}
+	if err := withSchema(context.Background(), q, "tenant", func() error { return errors.New("failed") }); err == nil || err.Error() != "failed" {
+		t.Errorf("Expected the error of fn, got %v", err)
+	}
+	if len(q.stmts) != 3 || q.stmts[2] != expected[2] || q.args[2][0] != "public" {
+		t.Errorf("Expected the previous search_path to be restored, got %v %v", q.stmts, q.args)
+	}
+}
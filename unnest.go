@@ -0,0 +1,63 @@
+package goql
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+)
+
+// FromUnnest selects from the Postgres array built from values, which
+// must be a slice, with the position of each element, for example
+// queryBuilder.Select("v.value").FromUnnest([]int64{3, 1, 2}, "v").OrderBy("v.ordinality")
+// generates SELECT v.value FROM unnest($1::bigint[]) WITH ORDINALITY v(value, ordinality)
+// The whole slice is bound as a single value no matter its length.
+func (qb *QueryBuilder) FromUnnest(values interface{}, alias string) (ret *QueryBuilder) {
+	ret = qb
+	expr, arr, err := unnest(values, alias)
+	if err != nil {
+		qb.addError(err)
+		return
+	}
+	qb.From(expr)
+	qb.setValues("from", []interface{}{arr})
+	return
+}
+
+// InnerJoinUnnest joins the query with the Postgres array built from
+// values on the on condition, which is a cleaner alternative to large
+// IN lists that also gives the position of each element, for example
+// queryBuilder.Select("u.*").From("users u").InnerJoinUnnest(ids, "i", "i.value = u.id").OrderBy("i.ordinality")
+// returns the users in the order of ids.
+func (qb *QueryBuilder) InnerJoinUnnest(values interface{}, alias string, on string) (ret *QueryBuilder) {
+	ret = qb
+	expr, arr, err := unnest(values, alias)
+	if err != nil {
+		qb.addError(err)
+		return
+	}
+	return qb.InnerJoin(expr+" ON "+on, arr)
+}
+
+// unnest returns the unnest expression for values and the array that
+// must be bound to it.
+func unnest(values interface{}, alias string) (string, driver.Valuer, error) {
+	v := reflect.ValueOf(values)
+	if v.Kind() != reflect.Slice {
+		return "", nil, fmt.Errorf("unnest: values must be a slice, got %T", values)
+	}
+	elemType, ok := pgArrayTypes[v.Type().Elem().Kind()]
+	if !ok {
+		return "", nil, fmt.Errorf("unnest: unsupported element type %s", v.Type().Elem())
+	}
+	expr := fmt.Sprintf("unnest($?::%s[]) WITH ORDINALITY %s(value, ordinality)", elemType, alias)
+	return expr, Array(values), nil
+}
+
+// pgArrayTypes maps the kinds of the elements of the slices supported
+// by unnest to their Postgres type.
+var pgArrayTypes = map[reflect.Kind]string{
+	reflect.Int: "bigint", reflect.Int8: "bigint", reflect.Int16: "bigint", reflect.Int32: "bigint", reflect.Int64: "bigint",
+	reflect.Uint8: "bigint", reflect.Uint16: "bigint", reflect.Uint32: "bigint",
+	reflect.Float32: "double precision", reflect.Float64: "double precision",
+	reflect.Bool: "boolean", reflect.String: "text",
+}
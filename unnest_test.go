@@ -0,0 +1,54 @@
+package goql
+
+import (
+	"testing"
+)
+
+func TestFromUnnest(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.Select("v.value").FromUnnest([]int64{3, 1, 2}, "v").Where("v.value > $?", 1).OrderBy("v.ordinality")
+	expected := `SELECT v.value FROM unnest($1::bigint[]) WITH ORDINALITY v(value, ordinality) WHERE v.value > $2 ORDER BY v.ordinality`
+	sql, args, err := qb.BuildWithArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if arr, _ := args[0].(PGArray).Value(); arr != "{3,1,2}" || args[1] != 1 {
+		t.Errorf("Unexpected args %v", args)
+	}
+}
+
+func TestInnerJoinUnnest(t *testing.T) {
+	Testing = false
+	qb := QueryBuilder{}
+	qb.Select("u.id").From("users u").InnerJoinUnnest([]string{`a"b`, `c\d`}, "n", "n.value = u.name")
+	expected := `SELECT u.id FROM users u INNER JOIN unnest($1::text[]) WITH ORDINALITY n(value, ordinality) ON n.value = u.name`
+	sql, args, err := qb.BuildWithArgs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if arr, _ := args[0].(PGArray).Value(); arr != `{"a\"b","c\\d"}` {
+		t.Errorf("Unexpected array %v", arr)
+	}
+
+	// The array is encoded as Array does
+	qb = QueryBuilder{}
+	qb.Select("v.value").FromUnnest([]bool{true, false}, "v")
+	if _, args, err := qb.BuildWithArgs(); err != nil {
+		t.Error(err)
+	} else if arr, _ := args[0].(PGArray).Value(); arr != "{t,f}" {
+		t.Errorf("Unexpected array %v", arr)
+	}
+
+	qb = QueryBuilder{}
+	qb.Select("id").FromUnnest([]struct{}{}, "v")
+	if _, _, err := qb.BuildWithArgs(); err == nil {
+		t.Error("Expected an error for an unsupported slice")
+	}
+}
@@ -0,0 +1,142 @@
+package goql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// UpdateBuilder builds an UPDATE statement of the columns set one by one
+// or from a map, for example the fields of a PATCH request body:
+//
+//	goql.UpdateTable("users").
+//		SetMap(map[string]interface{}{"name": "John", "age": 30}).
+//		Set("updated_at", goql.Raw("now()")).
+//		Where("id = $?", 1)
+//
+// generates UPDATE users SET "age" = $1, "name" = $2, "updated_at" = now()
+// WHERE id = $3
+// The statement is generated for the dialect set with SetDialect, or
+// with UseDialect.
+type UpdateBuilder struct {
+	dialect Dialect
+	table   string
+	cols    []string
+	vals    []interface{}
+	where   []string
+	args    []interface{}
+	err     error
+}
+
+// UpdateTable starts an UPDATE statement of table.
+func UpdateTable(table string) *UpdateBuilder {
+	return &UpdateBuilder{table: table}
+}
+
+// UseDialect sets the dialect of the statement, overriding the one set
+// with SetDialect, see QueryBuilder.UseDialect.
+func (ub *UpdateBuilder) UseDialect(d Dialect) *UpdateBuilder {
+	ub.dialect = d
+	return ub
+}
+
+// getDialect returns the dialect the statement is generated for.
+func (ub *UpdateBuilder) getDialect() Dialect {
+	if ub.dialect != nil {
+		return ub.dialect
+	}
+	return activeDialect()
+}
+
+// Set sets col to val, which is bound as a value unless it's an Expr,
+// such as Raw("now()") or Case. Setting the same column again replaces
+// its value.
+func (ub *UpdateBuilder) Set(col string, val interface{}) *UpdateBuilder {
+	if !identifierPattern.MatchString(col) {
+		ub.err = fmt.Errorf("goql: invalid column %q", col)
+		return ub
+	}
+	for i, c := range ub.cols {
+		if c == col {
+			ub.vals[i] = val
+			return ub
+		}
+	}
+	ub.cols = append(ub.cols, col)
+	ub.vals = append(ub.vals, val)
+	return ub
+}
+
+// SetMap sets each column of vals to its value, see Set. The columns are
+// set in alphabetical order so the statement is the same for the same
+// columns. As the keys are usually read from requests, the ones that are
+// not plain identifiers are rejected by Build, the ones that are not
+// columns of the table are rejected by the database.
+func (ub *UpdateBuilder) SetMap(vals map[string]interface{}) *UpdateBuilder {
+	cols := make([]string, 0, len(vals))
+	for col := range vals {
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+	for _, col := range cols {
+		ub.Set(col, vals[col])
+	}
+	return ub
+}
+
+// Where adds a condition to the WHERE clause, joined with AND to the
+// previous ones, vals being bound to its $? placeholders, see
+// QueryBuilder.Where.
+func (ub *UpdateBuilder) Where(where string, vals ...interface{}) *UpdateBuilder {
+	where, vals, err := compileNamed(where, vals)
+	if err != nil {
+		ub.err = err
+	}
+	ub.where = append(ub.where, parenthesizeOr(where))
+	ub.args = append(ub.args, vals...)
+	return ub
+}
+
+// Build generates the SQL of the statement along with its values. An
+// update without conditions is refused, Where("1 = 1") updates all the
+// rows on purpose.
+func (ub *UpdateBuilder) Build() (string, []interface{}, error) {
+	if ub.err != nil {
+		return "", nil, ub.err
+	}
+	if len(ub.cols) <= 0 {
+		return "", nil, errors.New("goql: the update has no columns")
+	}
+	if len(ub.where) <= 0 {
+		return "", nil, errors.New("goql: the update has no conditions")
+	}
+	d := ub.getDialect()
+	sets := make([]string, len(ub.cols))
+	vals := []interface{}{}
+	for i, col := range ub.cols {
+		sql, colVals := exprValue(d, ub.vals[i])
+		sets[i] = d.QuoteIdent(col) + " = " + sql
+		vals = append(vals, colVals...)
+	}
+	qry := fmt.Sprintf(`UPDATE %s SET %s WHERE %s`, ub.table, strings.Join(sets, ", "), strings.Join(ub.where, " AND "))
+	return numberPlaceholders(d, qry, 1), append(vals, ub.args...), nil
+}
+
+// Exec runs the statement, Db can be a *sql.DB, a *sql.Tx or any other
+// Queryer.
+func (ub *UpdateBuilder) Exec(Db interface{}) (sql.Result, error) {
+	return ub.ExecContext(context.Background(), Db)
+}
+
+// ExecContext is the same as Exec but the statement is canceled when
+// ctx is done.
+func (ub *UpdateBuilder) ExecContext(ctx context.Context, Db interface{}) (sql.Result, error) {
+	qry, vals, err := ub.Build()
+	if err != nil {
+		return nil, err
+	}
+	return execStatement(ctx, Db, KindUpdate, ub.table, qry, vals)
+}
@@ -0,0 +1,64 @@
+package goql
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestUpdateBuilderSetMap(t *testing.T) {
+	Testing = false
+	ub := UpdateTable("users").
+		SetMap(map[string]interface{}{"name": "John", "age": 30}).
+		Set("level", Case().When("age > 60", "senior").Else("regular")).
+		Set("updated_at", Raw("now()")).
+		Where("id = $?", 1).
+		Where("active OR admin")
+	expected := `UPDATE users SET "age" = $1, "name" = $2, "level" = CASE WHEN age > 60 THEN $3 ELSE $4 END, "updated_at" = now() WHERE id = $5 AND (active OR admin)`
+	sql, args, err := ub.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+	if fmt.Sprint(args) != "[30 John senior regular 1]" {
+		t.Errorf("Unexpected args %v", args)
+	}
+
+	// UseDialect overrides SetDialect
+	ub = UpdateTable("users").UseDialect(MySQL).Set("level", Case().When("age > 60", Col("users.age")).Else(0)).Where("id = $?", 1)
+	expected = "UPDATE users SET `level` = CASE WHEN age > 60 THEN `users`.`age` ELSE ? END WHERE id = ?"
+	if sql, _, _ := ub.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+
+	if _, _, err := UpdateTable("users").SetMap(map[string]interface{}{`name" = 'x', "admin`: true}).Where("id = 1").Build(); err == nil {
+		t.Error("Expected an error for an invalid column")
+	}
+	if _, _, err := UpdateTable("users").Set("name", "x").Build(); err == nil {
+		t.Error("Expected an error without conditions")
+	}
+	if _, _, err := UpdateTable("users").SetMap(nil).Where("id = 1").Build(); err == nil {
+		t.Error("Expected an error without columns")
+	}
+}
+
+func TestUpdateBuilderExec(t *testing.T) {
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`INSERT INTO user (username, password) VALUES ('john', 'x'), ('jane', 'y')`)
+
+	patch := map[string]interface{}{"username": "johnny", "password": "z"}
+	result, err := UpdateTable("user").SetMap(patch).Set("username", Raw("upper($?)", "johnny")).Where("id = $?", 1).Exec(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := result.RowsAffected(); n != 1 {
+		t.Errorf("Expected 1 row updated, got %d", n)
+	}
+	var username, password string
+	db.QueryRow("SELECT username, password FROM user WHERE id = 1").Scan(&username, &password)
+	if username != "JOHNNY" || password != "z" {
+		t.Errorf("Unexpected row %s %s", username, password)
+	}
+}
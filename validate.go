@@ -0,0 +1,159 @@
+package goql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidateSQL makes BuildWithArgs, and so the methods that run queries,
+// check the generated SQL for unbalanced parentheses and quotes,
+// dangling keywords and misplaced commas before returning it. The check
+// is a lightweight scan of the statement, not a full parser, meant to be
+// turned on in tests to catch the mistakes made composing queries before
+// they reach the database.
+var ValidateSQL = false
+
+// sqlToken is a token of a statement, words are upper cased.
+type sqlToken struct {
+	text string
+	pos  int
+}
+
+// danglingKeywords can't be the last token of a clause.
+var danglingKeywords = map[string]bool{
+	"WHERE": true, "AND": true, "OR": true, "ON": true, "HAVING": true, "SET": true, "NOT": true,
+	"BY": true, "SELECT": true, "FROM": true, "JOIN": true, "IN": true, "LIMIT": true, "OFFSET": true,
+}
+
+// clauseKeywords start a new clause or close the current one.
+var clauseKeywords = map[string]bool{
+	"FROM": true, "WHERE": true, "GROUP": true, "HAVING": true, "ORDER": true, "LIMIT": true,
+	"OFFSET": true, "UNION": true, "AND": true, "OR": true, "JOIN": true, "INNER": true, "LEFT": true,
+	"RIGHT": true, "FULL": true, "CROSS": true, ")": true, ",": true,
+}
+
+// checkSQL returns the first syntax error found in qry, a statement of
+// the dialect d.
+func checkSQL(d Dialect, qry string) error {
+	tokens, err := tokenizeSQL(d, qry)
+	if err != nil {
+		return err
+	}
+	depth := 0
+	for i, token := range tokens {
+		next := ""
+		if i+1 < len(tokens) {
+			next = tokens[i+1].text
+		}
+		switch {
+		case token.text == "(":
+			depth++
+			if next == "," {
+				return syntaxError(qry, tokens[i+1], "misplaced comma")
+			}
+			if next == ")" && i > 0 && tokens[i-1].text == "IN" {
+				return syntaxError(qry, token, "empty IN list")
+			}
+		case token.text == ")":
+			depth--
+			if depth < 0 {
+				return syntaxError(qry, token, "unbalanced )")
+			}
+		case token.text == ",":
+			if endsClause(tokens, i+1) {
+				return syntaxError(qry, token, "misplaced comma")
+			}
+		case danglingKeywords[token.text]:
+			if endsClause(tokens, i+1) {
+				return syntaxError(qry, token, "dangling "+token.text)
+			}
+		case strings.HasPrefix(token.text, "$") && len(token.text) > 1 && token.text != "$?" && !strings.HasPrefix(d.Placeholder(1), "$"):
+			return syntaxError(qry, token, fmt.Sprintf("placeholder %s is not a placeholder of %s", token.text, d.Name()))
+		}
+	}
+	if depth > 0 {
+		return fmt.Errorf("goql: invalid SQL, %d unclosed ( in %s", depth, qry)
+	}
+	return nil
+}
+
+// tokenizeSQL splits qry into words, numbers, placeholders and
+// punctuation, skipping the string literals, quoted identifiers and
+// comments, which are the only places where the rest can appear freely.
+func tokenizeSQL(d Dialect, qry string) ([]sqlToken, error) {
+	identQuote := d.QuoteIdent("x")[0]
+	tokens := []sqlToken{}
+	for i := 0; i < len(qry); {
+		c := qry[i]
+		switch {
+		case c == '\'' || c == '"' || c == identQuote:
+			end := closingQuote(qry, i)
+			if end < 0 {
+				return nil, fmt.Errorf("goql: invalid SQL, unterminated %c at %d in %s", c, i, qry)
+			}
+			tokens = append(tokens, sqlToken{text: "''", pos: i})
+			i = end + 1
+		case strings.HasPrefix(qry[i:], "--"):
+			end := strings.IndexByte(qry[i:], '\n')
+			if end < 0 {
+				end = len(qry) - i
+			}
+			i += end
+		case strings.HasPrefix(qry[i:], "/*"):
+			end := strings.Index(qry[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("goql: invalid SQL, unterminated comment at %d in %s", i, qry)
+			}
+			i += end + 4
+		case isNameStart(c) || c == '$' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(qry) && (isNameStart(qry[end]) || (qry[end] >= '0' && qry[end] <= '9') || qry[end] == '?' || qry[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, sqlToken{text: strings.ToUpper(qry[i:end]), pos: i})
+			i = end
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		default:
+			tokens = append(tokens, sqlToken{text: string(c), pos: i})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+// closingQuote returns the position of the quote that closes the one at
+// start, doubled quotes being escaped ones, or -1 when it's not closed.
+func closingQuote(qry string, start int) int {
+	quote := qry[start]
+	for i := start + 1; i < len(qry); i++ {
+		if qry[i] != quote {
+			continue
+		}
+		if i+1 < len(qry) && qry[i+1] == quote {
+			i++
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+// endsClause tells whether the i-th token ends the current clause, the
+// end of the statement included. LEFT and RIGHT followed by ( are calls
+// to the string functions.
+func endsClause(tokens []sqlToken, i int) bool {
+	if i >= len(tokens) {
+		return true
+	}
+	text := tokens[i].text
+	if (text == "LEFT" || text == "RIGHT") && i+1 < len(tokens) && tokens[i+1].text == "(" {
+		return false
+	}
+	return clauseKeywords[text]
+}
+
+// syntaxError reports the error found at token.
+func syntaxError(qry string, token sqlToken, msg string) error {
+	return fmt.Errorf("goql: invalid SQL, %s at %d in %s", msg, token.pos, qry)
+}
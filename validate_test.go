@@ -0,0 +1,60 @@
+package goql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckSQL(t *testing.T) {
+	valid := []string{
+		`SELECT id,LEFT(name, 3) FROM users u WHERE (a = $? OR b = $?) AND c IN ($?,$?) ORDER BY id DESC LIMIT 10`,
+		`SELECT "from", 'a, (b' FROM "order" WHERE name = 'it''s' -- trailing, comment`,
+		`SELECT COUNT(*) FROM (SELECT id FROM users GROUP BY id) counted`,
+		`INSERT INTO users ("id") VALUES($?) ON CONFLICT ("id") DO UPDATE SET "id" = EXCLUDED."id"`,
+	}
+	for _, qry := range valid {
+		if err := checkSQL(Postgres, qry); err != nil {
+			t.Errorf("Unexpected error %s", err)
+		}
+	}
+	invalid := map[string]string{
+		`SELECT id FROM users WHERE (a = $?`:               "unclosed (",
+		`SELECT id FROM users WHERE a = $?)`:               "unbalanced )",
+		`SELECT id FROM users WHERE name = 'x`:             "unterminated '",
+		`SELECT id FROM users WHERE AND a = $?`:            "dangling WHERE",
+		`SELECT id FROM users WHERE a = $? AND`:            "dangling AND",
+		`SELECT id FROM users WHERE a = $? OR ORDER BY id`: "dangling OR",
+		`SELECT id, FROM users`:                            "misplaced comma",
+		`SELECT id FROM users WHERE id IN ()`:              "empty IN list",
+		`SELECT FROM users`:                                "dangling SELECT",
+		`SELECT id FROM users /* unclosed`:                 "unterminated comment",
+	}
+	for qry, expected := range invalid {
+		err := checkSQL(Postgres, qry)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: expected %q, got %v", qry, expected, err)
+		}
+	}
+	if err := checkSQL(MySQL, "SELECT id FROM users WHERE id = $1"); err == nil {
+		t.Error("Expected an error for a Postgres placeholder in MySQL")
+	}
+	if err := checkSQL(MySQL, "SELECT `a\"b` FROM users WHERE id = ?"); err != nil {
+		t.Errorf("Unexpected error %s", err)
+	}
+}
+
+func TestValidateSQL(t *testing.T) {
+	Testing = false
+	ValidateSQL = true
+	defer func() { ValidateSQL = false }()
+	qb := QueryBuilder{}
+	qb.Select("id").From("users").Where("(status = $?", "a")
+	if _, _, err := qb.BuildWithArgs(); err == nil || !strings.Contains(err.Error(), "unclosed (") {
+		t.Errorf("Expected an unclosed ( error, got %v", err)
+	}
+	qb = QueryBuilder{}
+	qb.Select("id").From("users").Where("status = $?", "a")
+	if _, _, err := qb.BuildWithArgs(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,107 @@
+package goql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WindowSpec is the definition of a window used by window functions,
+// built with Partition.
+type WindowSpec struct {
+	partition []string
+	order     []string
+	frame     string
+}
+
+// Partition starts a window definition partitioned by cols, no cols
+// means that the whole result is a single partition, for example
+// Partition("user_id").OrderBy("created DESC")
+// generates (PARTITION BY user_id ORDER BY created DESC)
+func Partition(cols ...string) *WindowSpec {
+	return &WindowSpec{partition: cols}
+}
+
+// OrderBy orders the rows of each partition.
+func (w *WindowSpec) OrderBy(terms ...string) *WindowSpec {
+	w.order = append(w.order, terms...)
+	return w
+}
+
+// Frame sets the frame of the window, such as
+// "ROWS BETWEEN 6 PRECEDING AND CURRENT ROW".
+func (w *WindowSpec) Frame(frame string) *WindowSpec {
+	w.frame = frame
+	return w
+}
+
+func (w *WindowSpec) String() string {
+	parts := []string{}
+	if len(w.partition) > 0 {
+		parts = append(parts, "PARTITION BY "+strings.Join(w.partition, ", "))
+	}
+	if len(w.order) > 0 {
+		parts = append(parts, "ORDER BY "+strings.Join(w.order, ", "))
+	}
+	if len(w.frame) > 0 {
+		parts = append(parts, w.frame)
+	}
+	return "(" + strings.Join(parts, " ") + ")"
+}
+
+// OverExpr is a window function call built with Over.
+type OverExpr struct {
+	fn     string
+	window string
+	alias  string
+}
+
+// Over calls the window function fn over window, which is either a
+// *WindowSpec or the name of a window declared with Window, for example
+// queryBuilder.Select("id", Over("ROW_NUMBER()", Partition("user_id").OrderBy("created")).As("rn"))
+// generates SELECT id,ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY created) "rn"
+func Over(fn string, window interface{}) *OverExpr {
+	expr := &OverExpr{fn: fn}
+	switch window := window.(type) {
+	case *WindowSpec:
+		expr.window = window.String()
+	case string:
+		expr.window = window
+	default:
+		panic(fmt.Sprintf("Unsupported window %T", window))
+	}
+	return expr
+}
+
+// As names the column when the expression is selected.
+func (o *OverExpr) As(alias string) *OverExpr {
+	o.alias = alias
+	return o
+}
+
+// Alias returns the name set with As.
+func (o *OverExpr) Alias() string {
+	return o.alias
+}
+
+// SQL implements Expr.
+func (o *OverExpr) SQL() (string, []interface{}) {
+	return o.fn + " OVER " + o.window, nil
+}
+
+// Window declares the named window name so it can be shared by several
+// window functions of the query with Over, for example
+// queryBuilder.Window("w", Partition("user_id")).Select(Over("SUM(total)", "w").As("user_total"))
+// adds WINDOW w AS (PARTITION BY user_id) after the HAVING clause.
+func (qb *QueryBuilder) Window(name string, def *WindowSpec) (ret *QueryBuilder) {
+	ret = qb
+	qb.invalidate()
+	qb.windows = append(qb.windows, name+" AS "+def.String())
+	return
+}
+
+func (qb *QueryBuilder) buildWindow() string {
+	if len(qb.windows) > 0 {
+		return "WINDOW " + strings.Join(qb.windows, ", ")
+	}
+	return ""
+}
@@ -0,0 +1,30 @@
+package goql
+
+import (
+	"testing"
+)
+
+func TestWindowFunctions(t *testing.T) {
+	qb := QueryBuilder{}
+	qb.Select("id",
+		Over("ROW_NUMBER()", Partition("user_id").OrderBy("created DESC")).As("rn"),
+		Over("SUM(total)", "w").As("running"),
+	).From("orders").Window("w", Partition().OrderBy("created").Frame("ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW"))
+	expected := `SELECT id,ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY created DESC) "rn",SUM(total) OVER w "running" FROM orders WINDOW w AS (ORDER BY created ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW)`
+	if sql := qb.Build(); sql != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, sql)
+	}
+
+	db := dbSetup()
+	defer db.Close()
+	db.Exec(`INSERT INTO user(username) VALUES('a'), ('b'), ('c')`)
+	qb = QueryBuilder{}
+	qb.Select(Over("SUM(id)", "w")).From("user").Window("w", Partition().OrderBy("id")).OrderBy("id DESC").Limit(1)
+	var sum int
+	if err := db.QueryRow(qb.Build()).Scan(&sum); err != nil {
+		t.Fatal(err)
+	}
+	if sum != 6 {
+		t.Errorf("Expected 6, got %d", sum)
+	}
+}